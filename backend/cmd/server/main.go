@@ -1,12 +1,19 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"linktree-mohamedfadel-backend/docs"
 	"linktree-mohamedfadel-backend/internal/api"
 	"linktree-mohamedfadel-backend/internal/database"
 	"linktree-mohamedfadel-backend/internal/services"
+	"linktree-mohamedfadel-backend/internal/utils"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -35,11 +42,96 @@ func main() {
 	userService := services.NewUserService(database.DB)
 	linkService := services.NewLinkService(database.DB)
 	analyticsService := services.NewAnalyticsService(database.DB)
+	sectionService := services.NewSectionService(database.DB)
+	blockService := services.NewBlockService(database.DB)
 
-	router := api.NewRouter(userService, linkService, analyticsService)
+	// Admin rights go with accounts rather than names, so a listed name
+	// nobody has registered yet cannot be claimed at signup.
+	missingAdmins, err := userService.SetAdmins(utils.LoadAdminUsernames())
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, username := range missingAdmins {
+		log.Printf("admin %q has no account and was not granted admin rights", username)
+	}
+
+	metadataConfig, err := services.LoadMetadataFetcherConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	linkService.SetMetadataFetcher(services.NewMetadataFetcher(metadataConfig))
+
+	urlPolicy, err := services.LoadURLPolicy()
+	if err != nil {
+		log.Fatal(err)
+	}
+	linkService.SetURLPolicy(urlPolicy)
+	blockService.SetURLPolicy(urlPolicy)
+
+	clickParser, err := services.LoadClickParser()
+	if err != nil {
+		log.Fatal(err)
+	}
+	analyticsService.SetClickParser(clickParser)
+	linkService.SetClickParser(clickParser)
+
+	geoLocator, err := services.LoadGeoLocator()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if geoLocator != nil {
+		defer geoLocator.Close()
+		analyticsService.SetGeoLocator(geoLocator)
+	}
+
+	botConfig, err := services.LoadBotClassifierConfig(clickParser)
+	if err != nil {
+		log.Fatal(err)
+	}
+	analyticsService.SetBotClassifier(services.NewBotClassifier(botConfig))
+
+	healthConfig, err := services.LoadHealthCheckerConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go services.NewHealthChecker(database.DB, healthConfig).Start(ctx)
+
+	// Clicks stored before the rules changed are classified again with the
+	// new ones, without holding up startup.
+	go func() {
+		if _, err := services.ReclassifyClicks(database.DB, clickParser); err != nil {
+			log.Printf("click reclassification failed: %v", err)
+		}
+	}()
+
+	ingesterConfig, err := services.LoadClickIngesterConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	clickIngester := services.NewClickIngester(database.DB, ingesterConfig)
+	clickIngester.Start()
+	analyticsService.SetClickIngester(clickIngester)
+
+	rollupJob, err := services.NewClickRollupJob(database.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go rollupJob.Start(ctx)
+
+	router := api.NewRouter(userService, linkService, analyticsService, sectionService, blockService, urlPolicy)
 
 	engine := gin.Default()
 
+	// Client addresses key the unlock limits, the bot rate check, unique
+	// visitors and geolocation, so forwarding headers are only believed
+	// from the configured proxies.
+	if err := engine.SetTrustedProxies(utils.LoadTrustedProxies()); err != nil {
+		log.Fatal(err)
+	}
+
 	engine.Use(cors.New(cors.Config{
 		AllowAllOrigins:  true,
 		AllowMethods:     []string{"*"},
@@ -51,5 +143,22 @@ func main() {
 
 	router.SetupRoutes(engine)
 
-	engine.Run(":8188")
+	server := &http.Server{Addr: ":8188", Handler: engine}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+
+	// Stop taking requests before draining the clicks they queued.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
+	}
+	if err := clickIngester.Close(shutdownCtx); err != nil {
+		log.Printf("click ingester shutdown: %v", err)
+	}
 }
@@ -15,100 +15,74 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/analytics/{id}/click": {
-            "post": {
+        "/admin/analytics/ingestion": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Records a click event for a specific link. If the request includes authentication, the click will be associated with the authenticated user.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Report the depth of the click ingestion queue, how many clicks overflowed it or were dropped, how many of those were lost to failed batch writes, and how long batch writes take. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "analytics"
-                ],
-                "summary": "Track a link click",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "example": 1,
-                        "description": "Link ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Get click ingestion stats",
                 "responses": {
                     "200": {
-                        "description": "message: Click tracked successfully"
-                    },
-                    "400": {
-                        "description": "error: Invalid link ID"
+                        "description": "Ingestion stats",
+                        "schema": {
+                            "$ref": "#/definitions/services.ClickIngesterStats"
+                        }
                     },
-                    "404": {
-                        "description": "error: Link not found"
+                    "401": {
+                        "description": "error: Unauthorized"
                     },
-                    "500": {
-                        "description": "error: Internal server error"
+                    "403": {
+                        "description": "error: Admin access required"
                     }
                 }
             }
         },
-        "/links": {
-            "post": {
+        "/admin/blocklist": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new link for the authenticated user's profile",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "List the domains that links may not point to. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "links"
+                    "admin"
                 ],
-                "summary": "Create a new link",
-                "parameters": [
-                    {
-                        "description": "Link details",
-                        "name": "link",
-                        "in": "body",
-                        "required": true,
+                "summary": "Get the domain blocklist",
+                "responses": {
+                    "200": {
+                        "description": "Blocked domains",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateLinkRequest"
+                            "$ref": "#/definitions/handlers.BlocklistResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "message: Link created successfully"
-                    },
-                    "400": {
-                        "description": "error: Link already exists"
                     },
                     "401": {
                         "description": "error: Unauthorized"
+                    },
+                    "403": {
+                        "description": "error: Admin access required"
                     }
                 }
-            }
-        },
-        "/links/{id}": {
-            "put": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing link for the authenticated user",
+                "description": "Add domains, including all their subdomains, to the blocklist. New and updated links pointing to them are rejected. Admin only.",
                 "consumes": [
                     "application/json"
                 ],
@@ -116,31 +90,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "links"
+                    "admin"
                 ],
-                "summary": "Update a link",
+                "summary": "Block domains",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "example": 1,
-                        "description": "Link ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Updated link details",
-                        "name": "link",
+                        "description": "Domains to block",
+                        "name": "domains",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateLinkRequest"
+                            "$ref": "#/definitions/handlers.BlockDomainsRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Link updated successfully"
+                        "description": "Blocked domains",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BlocklistResponse"
+                        }
                     },
                     "400": {
                         "description": "error: Invalid input"
@@ -148,89 +117,103 @@ const docTemplate = `{
                     "401": {
                         "description": "error: Unauthorized"
                     },
-                    "404": {
-                        "description": "error: Link not found"
+                    "403": {
+                        "description": "error: Admin access required"
                     }
                 }
-            },
+            }
+        },
+        "/admin/blocklist/{domain}": {
             "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete an existing link from the authenticated user's profile",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Remove a domain from the blocklist. Admin only.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "links"
+                    "admin"
                 ],
-                "summary": "Delete a link",
+                "summary": "Unblock a domain",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "example": 1,
-                        "description": "Link ID",
-                        "name": "id",
+                        "type": "string",
+                        "example": "phishing.example",
+                        "description": "Domain",
+                        "name": "domain",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: Link deleted successfully"
-                    },
-                    "400": {
-                        "description": "error: Invalid link ID"
+                        "description": "Blocked domains",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BlocklistResponse"
+                        }
                     },
                     "401": {
                         "description": "error: Unauthorized"
                     },
+                    "403": {
+                        "description": "error: Admin access required"
+                    },
                     "404": {
-                        "description": "error: Link not found"
+                        "description": "error: Domain is not blocked"
                     }
                 }
             }
         },
-        "/users": {
-            "put": {
+        "/analytics/ctr": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update the authenticated user's profile information",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Return how often the authenticated user's profile was viewed in a period and, for each of their links, the clicks and the click-through rate (clicks per profile view). Views by the owner and by bots are not counted. The period covers whole days from the start date to the end date in the given timezone, by default the last 30 days in UTC.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "analytics"
                 ],
-                "summary": "Update user profile",
+                "summary": "Get link click-through rates",
                 "parameters": [
                     {
-                        "description": "Updated user information",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-31",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Europe/Berlin",
+                        "description": "IANA timezone, UTC by default",
+                        "name": "tz",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: User updated successfully"
+                        "description": "Click-through rates",
+                        "schema": {
+                            "$ref": "#/definitions/services.CTRReport"
+                        }
                     },
                     "400": {
-                        "description": "error: Invalid input"
+                        "description": "error: Invalid date range"
                     },
                     "401": {
                         "description": "error: Unauthorized"
@@ -239,328 +222,3730 @@ const docTemplate = `{
                         "description": "error: User not found"
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/analytics/links/{id}": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Permanently delete the authenticated user's account and all associated data",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Return the clicks on one of the authenticated user's links bucketed by hour, day or week. The range covers whole days from the start date to the end date in the given timezone, by default the last 30 days in UTC. Weeks start on Monday. Clicks are aggregated per UTC hour, so in timezones offset by a fraction of an hour each hour is counted in the bucket its start falls in.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "analytics"
+                ],
+                "summary": "Get a link's clicks over time",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-31",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "day",
+                        "description": "hour, day (default) or week",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Europe/Berlin",
+                        "description": "IANA timezone, UTC by default",
+                        "name": "tz",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Delete user account",
                 "responses": {
                     "200": {
-                        "description": "message: User deleted successfully"
+                        "description": "Click series",
+                        "schema": {
+                            "$ref": "#/definitions/services.ClickSeries"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid date range"
                     },
                     "401": {
-                        "description": "error:Unauthorized"
+                        "description": "error: Unauthorized"
                     },
                     "404": {
-                        "description": "error:User not found"
-                    },
-                    "500": {
-                        "description": "error:Internal server error"
+                        "description": "error: Link not found"
                     }
                 }
             }
         },
-        "/users/login": {
-            "post": {
-                "description": "Authenticate user credentials and return JWT token",
-                "consumes": [
-                    "application/json"
+        "/analytics/links/{id}/breakdown": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Return the most frequent source domains, device classes (desktop, mobile, tablet, tv, bot, unknown), browsers, operating systems, countries, regions, cities and destinations of the clicks on one of the authenticated user's links. Clicks without a referrer come from \"direct\"; cities are listed with their country, e.g. \"Berlin, DE\". Destinations are the URLs the link pointed at when the clicks happened, following its revision history. The period covers whole days from the start date to the end date in the given timezone, by default the last 30 days in UTC.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "analytics"
                 ],
-                "summary": "Login user",
+                "summary": "Get where a link's clicks came from",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "credentials",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
-                        }
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-31",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Europe/Berlin",
+                        "description": "IANA timezone, UTC by default",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 10,
+                        "description": "Values per dimension, 10 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "token: JWT_TOKEN_STRING"
+                        "description": "Click breakdown",
+                        "schema": {
+                            "$ref": "#/definitions/services.ClickBreakdown"
+                        }
                     },
                     "400": {
-                        "description": "error: Invalid input"
+                        "description": "error: Invalid date range"
                     },
                     "401": {
-                        "description": "error: Invalid username or password"
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
                     }
                 }
             }
         },
-        "/users/signup": {
-            "post": {
-                "description": "Create a new user account with the provided information",
-                "consumes": [
-                    "application/json"
+        "/analytics/profile": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Return the clicks on all of the authenticated user's links bucketed by hour, day or week, with the same range and timezone rules as the per-link series.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "analytics"
                 ],
-                "summary": "Register a new user",
+                "summary": "Get a profile's clicks over time",
                 "parameters": [
                     {
-                        "description": "User registration details",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.SignUpRequest"
-                        }
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-31",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "day",
+                        "description": "hour, day (default) or week",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Europe/Berlin",
+                        "description": "IANA timezone, UTC by default",
+                        "name": "tz",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "message: User created successfully"
-                    },
-                    "400": {
+                    "200": {
+                        "description": "Click series",
+                        "schema": {
+                            "$ref": "#/definitions/services.ClickSeries"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid date range"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/analytics/profile/breakdown": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Return the most frequent source domains, device classes, browsers, operating systems, countries, regions and cities of the clicks on all of the authenticated user's links, with the same range and timezone rules as the per-link breakdown.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get where a profile's clicks came from",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "First day, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-01-31",
+                        "description": "Last day, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Europe/Berlin",
+                        "description": "IANA timezone, UTC by default",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "example": 10,
+                        "description": "Values per dimension, 10 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Click breakdown",
+                        "schema": {
+                            "$ref": "#/definitions/services.ClickBreakdown"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid date range"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/analytics/{id}/click": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Records a click event for a specific link. The referrer, user agent, the location from CDN headers or the geolocation database and a hash of the IP address and user agent keyed with a salt that changes every day are stored with the click. Neither the IP address nor who clicked is stored. Pass the variant_id from the profile as variant to attribute the click to an A/B test variant.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Track a link click",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "ID of the A/B test variant that was clicked",
+                        "name": "variant",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Click tracked successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid link ID"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
+                    },
+                    "500": {
+                        "description": "error: Internal server error"
+                    }
+                }
+            }
+        },
+        "/blocks": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Append a typed content block to the authenticated user's profile. The payload is validated against the block type, and image and video URLs must pass the URL policy; link blocks are created through the links API.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocks"
+                ],
+                "summary": "Create a new block",
+                "parameters": [
+                    {
+                        "description": "Block type and payload",
+                        "name": "block",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateBlockRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created block",
+                        "schema": {
+                            "$ref": "#/definitions/models.Block"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/blocks/order": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the display order of the authenticated user's blocks. The list must contain every block ID exactly once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocks"
+                ],
+                "summary": "Reorder blocks",
+                "parameters": [
+                    {
+                        "description": "Block IDs in display order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReorderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Blocks reordered successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/blocks/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the payload of one of the authenticated user's blocks. The block type cannot change.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocks"
+                ],
+                "summary": "Update a block",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Block ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New payload",
+                        "name": "block",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateBlockRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Block updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete one of the authenticated user's blocks. Link blocks are removed by deleting the link.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocks"
+                ],
+                "summary": "Delete a block",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Block ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Block deleted successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid block ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Block not found"
+                    }
+                }
+            }
+        },
+        "/links": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the authenticated user's links with their tags, a page at a time. Repeat tag to only get links carrying all of the given tags.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "List links",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tags the links must carry",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Links per page, at most 100",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of links",
+                        "schema": {
+                            "$ref": "#/definitions/services.LinkPage"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid page"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new link for the authenticated user's profile. A random short slug is generated when none is given. When the title is left out, or fetch_metadata is set, the page is fetched to fill in the title, description, preview image and favicon.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Create a new link",
+                "parameters": [
+                    {
+                        "description": "Link details",
+                        "name": "link",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "message: Link created successfully"
+                    },
+                    "400": {
+                        "description": "error: url rejected: scheme \\\"javascript\\\" is not allowed"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the authenticated user's links in profile order, with section, slug, preview description and image, tags and creation time, as JSON or CSV; CSV lists tags comma separated in one cell. CSV cells starting with =, +, - or @ are prefixed with a single quote so spreadsheets treat them as text.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Export links",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Export format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Exported links",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.LinkRecord"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Unsupported format"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/links/health": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the authenticated user's links with the result of their latest reachability check: status code, latency, final URL after redirects and failure streak",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Get link health",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Only return links whose last check failed",
+                        "name": "broken",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Links with health",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Link"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/links/import": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Import links from a JSON array, a CSV file with a header row, or a browser bookmarks HTML file sent as the request body. With dry_run the per-row report is returned without saving anything. Preview images must be http or https URLs the URL policy accepts. Duplicates are skipped; any invalid row rejects the whole import. Imported links are added after the links their section already has, in position order, with rows of the same position kept in file order.",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "text/html"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Import links",
+                "parameters": [
+                    {
+                        "enum": [
+                            "json",
+                            "csv",
+                            "html"
+                        ],
+                        "type": "string",
+                        "description": "Import format",
+                        "name": "format",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate only",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Import report",
+                        "schema": {
+                            "$ref": "#/definitions/services.ImportReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid rows",
+                        "schema": {
+                            "$ref": "#/definitions/services.ImportReport"
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/order": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the display order of the links that are not in a section. The list must contain every such link ID exactly once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Reorder ungrouped links",
+                "parameters": [
+                    {
+                        "description": "Link IDs in display order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReorderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Links reordered successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Search the authenticated user's links by title, URL, description and tags, a page at a time. On Postgres results are ranked by full-text relevance; every word must otherwise appear in the link.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Search links",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "podcast",
+                        "description": "Search text",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tags the links must carry",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Links per page, at most 100",
+                        "name": "per_page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of matching links",
+                        "schema": {
+                            "$ref": "#/definitions/services.LinkPage"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Search text is required"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing link for the authenticated user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Update a link",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated link details",
+                        "name": "link",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Link updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete an existing link from the authenticated user's profile",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Delete a link",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Link deleted successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid link ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
+                    }
+                }
+            }
+        },
+        "/links/{id}/limits": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Make a link stop working after max_clicks visitors or at expires_at. Clicks taken for bots and link previews do not count towards max_clicks. Once expired, the link is hidden from the profile (hide), shows a sold out page (sold_out, the default) or redirects to fallback_url (fallback). Omit both limits to remove them; reset_clicks restarts the count.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Set link limits",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Limits",
+                        "name": "limits",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkLimitsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Link limits updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/metadata": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Fetch the linked page again and update the link's description, preview image and favicon from its OpenGraph, Twitter card and HTML metadata",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Refresh link preview",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated link",
+                        "schema": {
+                            "$ref": "#/definitions/models.Link"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid link ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
+                    },
+                    "502": {
+                        "description": "error: Failed to fetch metadata"
+                    }
+                }
+            }
+        },
+        "/links/{id}/protection": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Gate a link behind a password and/or a sensitive content warning. An empty password removes the password. Protected links have their URL hidden on the public profile.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Set link protection",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Protection settings",
+                        "name": "protection",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkProtectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Link protection updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Link not found"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/revisions": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the previous titles and URLs of a link with when each was live and who replaced it, most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "List link revisions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Previous versions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.LinkRevision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid link ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
+                    }
+                }
+            }
+        },
+        "/links/{id}/revisions/{revisionId}/rollback": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Restore the title and URL of one of a link's revisions. The replaced version is kept as a new revision.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Roll back a link",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Revision ID",
+                        "name": "revisionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Restored link",
+                        "schema": {
+                            "$ref": "#/definitions/models.Link"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/rules": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the targeting rules of a link. The redirect endpoint sends visitors to the target of the first rule matching their platform, language, referrer and time of day, and to the link's URL when none match. An empty list removes all rules.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Set link rules",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ordered rules",
+                        "name": "rules",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkRulesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Link rules updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/rules/test": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Evaluate a link's rules against a simulated visit and return where it would be redirected. Pass rules to try them without saving; otherwise the saved rules are used. The time defaults to now.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Test link rules",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Simulated visit",
+                        "name": "visit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TestLinkRulesRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Redirect target",
+                        "schema": {
+                            "$ref": "#/definitions/services.RuleResult"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/tags": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the free-form tags of a link. Tags are lowercased, duplicates dropped, and each is at most 32 characters.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Set link tags",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tags",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkTagsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Saved tags",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkTagsRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/utm": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Override fields of the profile's UTM template for one link. Empty fields fall back to the profile template, and an empty template removes the override.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Set link UTM template",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "UTM template",
+                        "name": "utm",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UTMTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Link UTM template updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/variants": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Report impressions, clicks and click-through rate of each variant of a link. Each variant is compared with the first one using a two-proportion z-test; significant means the difference holds at 95% confidence with at least 30 impressions on both sides.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Get A/B test report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Variant statistics",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/services.VariantStats"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid link ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Link not found"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add an alternative title and/or URL to A/B test on a link. Profile visitors are split across a link's variants in proportion to their weights and keep seeing the same variant.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Add a link variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Variant",
+                        "name": "variant",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LinkVariantRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created variant",
+                        "schema": {
+                            "$ref": "#/definitions/models.LinkVariant"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/links/{id}/variants/{variantId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove a variant and its statistics from a link's A/B test",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Delete a link variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Variant ID",
+                        "name": "variantId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Variant deleted successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid variant ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Variant not found"
+                    }
+                }
+            }
+        },
+        "/links/{id}/variants/{variantId}/promote": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "End a link's A/B test by copying the winning variant's title and URL onto the link. All of the link's variants are removed.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "links"
+                ],
+                "summary": "Promote a link variant",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Link ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Variant ID",
+                        "name": "variantId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Updated link",
+                        "schema": {
+                            "$ref": "#/definitions/models.Link"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/sections": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the authenticated user's sections in display order with their links nested",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sections"
+                ],
+                "summary": "List sections",
+                "responses": {
+                    "200": {
+                        "description": "Ordered sections",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Section"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a section header to group links on the authenticated user's profile. New sections are appended at the end.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sections"
+                ],
+                "summary": "Create a new section",
+                "parameters": [
+                    {
+                        "description": "Section details",
+                        "name": "section",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateSectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created section",
+                        "schema": {
+                            "$ref": "#/definitions/models.Section"
+                        }
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/sections/order": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the display order of the authenticated user's sections. The list must contain every section ID exactly once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sections"
+                ],
+                "summary": "Reorder sections",
+                "parameters": [
+                    {
+                        "description": "Section IDs in display order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReorderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Sections reordered successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/sections/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Rename a section or change whether it is collapsed by default",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sections"
+                ],
+                "summary": "Update a section",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated section details",
+                        "name": "section",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateSectionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Section updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Section not found"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a section. The links query parameter decides whether its links are moved out to the ungrouped list or deleted with it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sections"
+                ],
+                "summary": "Delete a section",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "move",
+                            "delete"
+                        ],
+                        "type": "string",
+                        "description": "What to do with the section's links",
+                        "name": "links",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Section deleted successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid section ID"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: Section not found"
+                    }
+                }
+            }
+        },
+        "/sections/{id}/links/order": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the display order of the links inside a section. The list must contain every link ID in the section exactly once.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "sections"
+                ],
+                "summary": "Reorder links in a section",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "example": 1,
+                        "description": "Section ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Link IDs in display order",
+                        "name": "order",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ReorderRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Links reordered successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/users": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update the authenticated user's profile information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user profile",
+                "parameters": [
+                    {
+                        "description": "Updated user information",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: User updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Permanently delete the authenticated user's account and all associated data: links and their analytics, click history, tags, revisions, slug aliases and A/B variants, sections, blocks and profile views",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user account",
+                "responses": {
+                    "200": {
+                        "description": "message: User deleted successfully"
+                    },
+                    "401": {
+                        "description": "error:Unauthorized"
+                    },
+                    "404": {
+                        "description": "error:User not found"
+                    },
+                    "500": {
+                        "description": "error:Internal server error"
+                    }
+                }
+            }
+        },
+        "/users/bot-traffic": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Choose whether the authenticated user's click charts, breakdowns and click-through rates include clicks taken for crawlers, link previews and other automated traffic. Bot clicks are always stored and counted apart from people's clicks; this only changes what the reports show.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Show or hide bot traffic",
+                "parameters": [
+                    {
+                        "description": "Bot traffic setting",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.BotTrafficRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Bot traffic setting updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/users/login": {
+            "post": {
+                "description": "Authenticate user credentials and return JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Login user",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token: JWT_TOKEN_STRING"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Invalid username or password"
+                    }
+                }
+            }
+        },
+        "/users/privacy": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Choose whether the authenticated user's public profile shows the click counts of their links.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set analytics privacy",
+                "parameters": [
+                    {
+                        "description": "Privacy settings",
+                        "name": "privacy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AnalyticsPrivacyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Privacy settings updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/users/signup": {
+            "post": {
+                "description": "Create a new user account with the provided information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register a new user",
+                "parameters": [
+                    {
+                        "description": "User registration details",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SignUpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "message: User created successfully"
+                    },
+                    "400": {
                         "description": "error: Username already exists"
                     }
                 }
             }
         },
-        "/users/{username}": {
-            "get": {
-                "description": "Retrieve user profile information and their associated links",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "users"
-                ],
-                "summary": "Get user profile",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Username",
-                        "name": "username",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "User profile with associated links",
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
-                    },
-                    "404": {
-                        "description": "error: User not found"
-                    }
+        "/users/utm": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set the UTM parameters added to all of the authenticated user's outgoing links when visitors are redirected. Values may use {username}, {link_id} and {slug}. Parameters a destination already has are kept. An empty template turns tagging off.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Set profile UTM template",
+                "parameters": [
+                    {
+                        "description": "UTM template",
+                        "name": "utm",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UTMTemplate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: UTM template updated successfully"
+                    },
+                    "400": {
+                        "description": "error: Invalid input"
+                    },
+                    "401": {
+                        "description": "error: Unauthorized"
+                    }
+                }
+            }
+        },
+        "/users/{username}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Retrieve user profile information and their associated links. Links under A/B test are shown as the variant the visitor is assigned to, kept stable by a visitor_id cookie, with variant_id set and the tracked URL attributing clicks to that variant. Link analytics are only included in full for the signed-in owner; other visitors see click counts if the owner made them public and nothing otherwise. The visit counts as a profile view unless it comes from the profile's owner or a bot; bots see links under A/B test as saved and are not counted as variant impressions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user profile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "User profile with associated links",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        },
+        "/users/{username}/blocks": {
+            "get": {
+                "description": "Retrieve the ordered list of typed blocks making up a user's public profile. Links under A/B test are shown as the variant the visitor is assigned to, kept stable by a visitor_id cookie, with variant_id set and the tracked URL attributing clicks to that variant, and count as impressions of it. Bots see links under A/B test as saved.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocks"
+                ],
+                "summary": "List a user's blocks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ordered profile blocks",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Block"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error: User not found"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.AnalyticsPrivacyRequest": {
+            "type": "object",
+            "properties": {
+                "public_click_counts": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.BlockDomainsRequest": {
+            "type": "object",
+            "required": [
+                "domains"
+            ],
+            "properties": {
+                "domains": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "phishing.example"
+                    ]
+                }
+            }
+        },
+        "handlers.BlocklistResponse": {
+            "type": "object",
+            "properties": {
+                "domains": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "phishing.example"
+                    ]
+                }
+            }
+        },
+        "handlers.BotTrafficRequest": {
+            "type": "object",
+            "properties": {
+                "show_bot_traffic": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "handlers.CreateBlockRequest": {
+            "type": "object",
+            "required": [
+                "type"
+            ],
+            "properties": {
+                "payload": {
+                    "type": "object"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "text",
+                        "header",
+                        "divider",
+                        "image",
+                        "video",
+                        "email_signup",
+                        "contact_form"
+                    ],
+                    "example": "text"
+                }
+            }
+        },
+        "handlers.CreateLinkRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "fetch_metadata": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "section_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "sensitive": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "slug": {
+                    "type": "string",
+                    "example": "johndoe-github"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "My GitHub"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://github.com/johndoe"
+                }
+            }
+        },
+        "handlers.CreateSectionRequest": {
+            "type": "object",
+            "required": [
+                "title"
+            ],
+            "properties": {
+                "collapsed": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Podcasts"
+                }
+            }
+        },
+        "handlers.LinkLimitsRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string",
+                    "example": "2024-12-31T23:59:59Z"
+                },
+                "expiry_action": {
+                    "type": "string",
+                    "enum": [
+                        "hide",
+                        "sold_out",
+                        "fallback"
+                    ],
+                    "example": "fallback"
+                },
+                "fallback_url": {
+                    "type": "string",
+                    "example": "https://shop.example.com/waitlist"
+                },
+                "max_clicks": {
+                    "type": "integer",
+                    "example": 100
+                },
+                "reset_clicks": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "handlers.LinkProtectionRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "open-sesame"
+                },
+                "sensitive": {
+                    "type": "boolean",
+                    "example": false
+                }
+            }
+        },
+        "handlers.LinkRulesRequest": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LinkRule"
+                    }
+                }
+            }
+        },
+        "handlers.LinkTagsRequest": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "podcast",
+                        "audio"
+                    ]
+                }
+            }
+        },
+        "handlers.LinkVariantRequest": {
+            "type": "object",
+            "properties": {
+                "title": {
+                    "type": "string",
+                    "example": "Listen to my podcast"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://podcast.example.com/latest"
+                },
+                "weight": {
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "securepassword123"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "johndoe"
+                }
+            }
+        },
+        "handlers.ReorderRequest": {
+            "type": "object",
+            "required": [
+                "ids"
+            ],
+            "properties": {
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        3,
+                        1,
+                        2
+                    ]
+                }
+            }
+        },
+        "handlers.SignUpRequest": {
+            "type": "object",
+            "required": [
+                "full_name",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "bio": {
+                    "type": "string",
+                    "example": "Software Developer | Tech Enthusiast"
+                },
+                "full_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "securepassword123"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "johndoe"
+                }
+            }
+        },
+        "handlers.TestLinkRulesRequest": {
+            "type": "object",
+            "properties": {
+                "accept_language": {
+                    "type": "string",
+                    "example": "de-DE,de;q=0.9"
+                },
+                "referrer": {
+                    "type": "string",
+                    "example": "https://www.instagram.com/"
+                },
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LinkRule"
+                    }
+                },
+                "time": {
+                    "type": "string",
+                    "example": "2024-01-01T12:00:00Z"
+                },
+                "user_agent": {
+                    "type": "string",
+                    "example": "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"
+                }
+            }
+        },
+        "handlers.UpdateBlockRequest": {
+            "type": "object",
+            "required": [
+                "payload"
+            ],
+            "properties": {
+                "payload": {
+                    "type": "object"
+                }
+            }
+        },
+        "handlers.UpdateLinkRequest": {
+            "type": "object",
+            "properties": {
+                "section_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "sensitive": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "slug": {
+                    "type": "string",
+                    "example": "johndoe-github"
+                },
+                "title": {
+                    "type": "string",
+                    "example": "My GitHub"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://github.com/johndoe"
+                }
+            }
+        },
+        "handlers.UpdateSectionRequest": {
+            "type": "object",
+            "properties": {
+                "collapsed": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Podcasts"
+                }
+            }
+        },
+        "models.Analytics": {
+            "description": "Analytics data for tracking link usage",
+            "type": "object",
+            "properties": {
+                "bot_click_count": {
+                    "description": "BotClickCount tracks clicks taken for crawlers, link previews and\nother automated traffic",
+                    "type": "integer",
+                    "example": 5
+                },
+                "click_count": {
+                    "description": "ClickCount tracks number of clicks by people",
+                    "type": "integer",
+                    "example": 42
+                },
+                "created_at": {
+                    "description": "CreatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "link_id": {
+                    "description": "LinkID is the foreign key to the associated link",
+                    "type": "integer",
+                    "example": 1
+                },
+                "unlock_count": {
+                    "description": "UnlockCount tracks visitors who entered a link's password or\nacknowledged its sensitive content warning",
+                    "type": "integer",
+                    "example": 7
+                },
+                "updated_at": {
+                    "description": "UpdatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                }
+            }
+        },
+        "models.Block": {
+            "description": "A typed content block on a profile, rendered in position order",
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "CreatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "link": {
+                    "description": "Link rendered by a link block",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Link"
+                        }
+                    ]
+                },
+                "link_id": {
+                    "description": "LinkID points at the link rendered by a link block",
+                    "type": "integer",
+                    "example": 1
+                },
+                "payload": {
+                    "description": "Payload holds the type-specific settings\nswagger:strfmt json",
+                    "type": "object"
+                },
+                "position": {
+                    "description": "Position of the block on the profile, lowest first",
+                    "type": "integer",
+                    "example": 0
+                },
+                "type": {
+                    "description": "Type selects how the block is rendered and which payload it carries",
+                    "type": "string",
+                    "enum": [
+                        "link",
+                        "text",
+                        "header",
+                        "divider",
+                        "image",
+                        "video",
+                        "email_signup",
+                        "contact_form"
+                    ],
+                    "example": "text"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "user_id": {
+                    "description": "UserID is the foreign key to the owner",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.Link": {
+            "description": "A link entry with associated analytics",
+            "type": "object",
+            "properties": {
+                "analytics": {
+                    "description": "Analytics data for this link, shown in full to the owner only",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.Analytics"
+                        }
+                    ]
+                },
+                "clicks_used": {
+                    "description": "ClicksUsed counts the visitors let through towards MaxClicks",
+                    "type": "integer",
+                    "example": 42
+                },
+                "created_at": {
+                    "description": "CreatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "description": {
+                    "description": "Description is the page summary shown in the link preview",
+                    "type": "string",
+                    "example": "Open source projects by John Doe"
+                },
+                "expired": {
+                    "description": "Expired is true once the click cap is reached or the expiry date passed",
+                    "type": "boolean",
+                    "example": false
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the link stops letting visitors through",
+                    "type": "string",
+                    "example": "2024-12-31T23:59:59Z"
+                },
+                "expiry_action": {
+                    "description": "ExpiryAction is what visitors get once the link expired",
+                    "type": "string",
+                    "enum": [
+                        "hide",
+                        "sold_out",
+                        "fallback"
+                    ],
+                    "example": "sold_out"
+                },
+                "fallback_url": {
+                    "description": "FallbackURL is where the fallback expiry action sends visitors",
+                    "type": "string",
+                    "example": "https://shop.example.com/waitlist"
+                },
+                "favicon_url": {
+                    "description": "FaviconURL is the icon of the linked site",
+                    "type": "string",
+                    "example": "https://github.com/favicon.ico"
+                },
+                "health": {
+                    "description": "Health is the latest reachability check, only loaded for the owner",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.LinkHealth"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "image_url": {
+                    "description": "ImageURL is the preview image of the linked page",
+                    "type": "string",
+                    "example": "https://github.com/johndoe.png"
+                },
+                "max_clicks": {
+                    "description": "MaxClicks is how many visitors the link lets through before it expires",
+                    "type": "integer",
+                    "example": 100
+                },
+                "password_protected": {
+                    "description": "PasswordProtected is true when visitors need a password to follow the link",
+                    "type": "boolean",
+                    "example": false
+                },
+                "position": {
+                    "description": "Position of the link within its section, lowest first",
+                    "type": "integer",
+                    "example": 0
+                },
+                "rules": {
+                    "description": "Rules send matching visitors elsewhere; the first matching rule wins and\nURL is the fallback when none match",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LinkRule"
+                    }
+                },
+                "section_id": {
+                    "description": "SectionID is the optional section this link is grouped under",
+                    "type": "integer",
+                    "example": 1
+                },
+                "sensitive": {
+                    "description": "Sensitive links show a content warning before redirecting",
+                    "type": "boolean",
+                    "example": false
+                },
+                "slug": {
+                    "description": "Slug is the short name resolving to this link under /r/",
+                    "type": "string",
+                    "example": "johndoe-podcast"
+                },
+                "tags": {
+                    "description": "Tags help the owner find the link, only loaded for the owner",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "podcast",
+                        "audio"
+                    ]
+                },
+                "title": {
+                    "description": "Title of the link",
+                    "type": "string",
+                    "example": "My GitHub Profile"
+                },
+                "tracked_url": {
+                    "description": "TrackedURL is the click-tracking redirect path for this link",
+                    "type": "string",
+                    "example": "/r/johndoe-podcast"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "url": {
+                    "description": "URL of the link",
+                    "type": "string",
+                    "example": "https://github.com/username"
+                },
+                "user_id": {
+                    "description": "UserID is the foreign key to the owner",
+                    "type": "integer",
+                    "example": 1
+                },
+                "utm": {
+                    "description": "UTM overrides fields of the owner's UTM template for this link",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UTMTemplate"
+                        }
+                    ]
+                },
+                "variant_id": {
+                    "description": "VariantID is the variant this visitor was assigned on the profile",
+                    "type": "integer",
+                    "example": 1
+                },
+                "variants": {
+                    "description": "Variants are alternative titles and URLs under test, only loaded for the owner",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.LinkVariant"
+                    }
+                }
+            }
+        },
+        "models.LinkHealth": {
+            "description": "Result of the latest reachability check of a link",
+            "type": "object",
+            "properties": {
+                "checked_at": {
+                    "description": "CheckedAt is when the link was last checked",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "failure_streak": {
+                    "description": "FailureStreak counts consecutive failed checks",
+                    "type": "integer",
+                    "example": 0
+                },
+                "final_url": {
+                    "description": "FinalURL is where the last check ended up after redirects",
+                    "type": "string",
+                    "example": "https://github.com/username"
+                },
+                "healthy": {
+                    "description": "Healthy is true when the last check ended in a 2xx or 3xx response",
+                    "type": "boolean",
+                    "example": true
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "last_error": {
+                    "description": "LastError describes why the last check failed",
+                    "type": "string",
+                    "example": ""
+                },
+                "last_status": {
+                    "description": "LastStatus is the HTTP status of the last check, 0 if no response",
+                    "type": "integer",
+                    "example": 200
+                },
+                "latency_ms": {
+                    "description": "LatencyMs is how long the last check took in milliseconds",
+                    "type": "integer",
+                    "example": 120
+                },
+                "link_id": {
+                    "description": "LinkID is the foreign key to the checked link",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.LinkRevision": {
+            "description": "A previous version of a link's title and URL",
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is the username of whoever replaced this version",
+                    "type": "string",
+                    "example": "johndoe"
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "link_id": {
+                    "description": "LinkID is the foreign key to the revised link",
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "description": "Title the link had in this version",
+                    "type": "string",
+                    "example": "My GitHub Profile"
+                },
+                "url": {
+                    "description": "URL the link pointed at in this version",
+                    "type": "string",
+                    "example": "https://github.com/username"
+                },
+                "valid_from": {
+                    "description": "ValidFrom is when this version went live",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "valid_to": {
+                    "description": "ValidTo is when this version was replaced",
+                    "type": "string",
+                    "example": "2024-02-01T00:00:00Z"
+                }
+            }
+        },
+        "models.LinkRule": {
+            "description": "A targeting rule sending matching visitors of a link to another URL. Every condition that is set must match; a rule without conditions matches everyone.",
+            "type": "object",
+            "properties": {
+                "languages": {
+                    "description": "Languages matches the visitor's preferred Accept-Language, either a base\nlanguage like \"en\" or a full tag like \"pt-BR\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "en"
+                    ]
+                },
+                "platforms": {
+                    "description": "Platforms matches the visitor's operating system",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ios"
+                    ]
+                },
+                "referrers": {
+                    "description": "Referrers matches the referring site's domain, including subdomains",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "instagram.com"
+                    ]
+                },
+                "target": {
+                    "description": "Target is where matching visitors are sent",
+                    "type": "string",
+                    "example": "https://apps.apple.com/app/id123456789"
+                },
+                "time_from": {
+                    "description": "TimeFrom starts the matching time of day, as HH:MM",
+                    "type": "string",
+                    "example": "09:00"
+                },
+                "time_to": {
+                    "description": "TimeTo ends the matching time of day, as HH:MM; ranges may wrap past midnight",
+                    "type": "string",
+                    "example": "17:00"
+                },
+                "timezone": {
+                    "description": "Timezone the time of day is read in, as an IANA name; defaults to UTC",
+                    "type": "string",
+                    "example": "Europe/Berlin"
+                }
+            }
+        },
+        "models.LinkVariant": {
+            "description": "Alternative title and/or URL of a link shown to a share of visitors",
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Clicks counts visitors who followed the variant",
+                    "type": "integer",
+                    "example": 12
+                },
+                "created_at": {
+                    "description": "CreatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "impressions": {
+                    "description": "Impressions counts profile views the variant was shown in",
+                    "type": "integer",
+                    "example": 120
+                },
+                "link_id": {
+                    "description": "LinkID is the foreign key to the tested link",
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "description": "Title replaces the link's title, the link's own title is used if empty",
+                    "type": "string",
+                    "example": "Listen to my podcast"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "url": {
+                    "description": "URL replaces the link's destination, the link's own URL is used if empty",
+                    "type": "string",
+                    "example": "https://podcast.example.com/latest"
+                },
+                "weight": {
+                    "description": "Weight is the variant's share of traffic relative to the other variants",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.Section": {
+            "description": "A titled group of links shown as a header on a profile",
+            "type": "object",
+            "properties": {
+                "collapsed": {
+                    "description": "Collapsed marks the section as collapsed by default",
+                    "type": "boolean",
+                    "example": false
+                },
+                "created_at": {
+                    "description": "CreatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "links": {
+                    "description": "Links grouped under this section",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Link"
+                    }
+                },
+                "position": {
+                    "description": "Position of the section on the profile, lowest first",
+                    "type": "integer",
+                    "example": 0
+                },
+                "title": {
+                    "description": "Title shown as the section header",
+                    "type": "string",
+                    "example": "Podcasts"
+                },
+                "updated_at": {
+                    "description": "UpdatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "user_id": {
+                    "description": "UserID is the foreign key to the owner",
+                    "type": "integer",
+                    "example": 1
+                }
+            }
+        },
+        "models.UTMTemplate": {
+            "description": "UTM parameters added to outgoing links. Values may use the placeholders {username}, {link_id} and {slug}.",
+            "type": "object",
+            "properties": {
+                "campaign": {
+                    "description": "Campaign becomes utm_campaign",
+                    "type": "string",
+                    "example": "{username}-bio"
+                },
+                "content": {
+                    "description": "Content becomes utm_content",
+                    "type": "string",
+                    "example": "link-{link_id}"
+                },
+                "medium": {
+                    "description": "Medium becomes utm_medium",
+                    "type": "string",
+                    "example": "social"
+                },
+                "source": {
+                    "description": "Source becomes utm_source",
+                    "type": "string",
+                    "example": "linktree"
+                },
+                "term": {
+                    "description": "Term becomes utm_term",
+                    "type": "string",
+                    "example": ""
+                }
+            }
+        },
+        "models.User": {
+            "description": "A user account with profile information and associated links",
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "description": "Bio contains user's description",
+                    "type": "string",
+                    "example": "Software developer passionate about Go"
+                },
+                "created_at": {
+                    "description": "CreatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "full_name": {
+                    "description": "FullName of the user",
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "id": {
+                    "description": "ID is the unique identifier",
+                    "type": "integer",
+                    "example": 1
+                },
+                "links": {
+                    "description": "Links associated with this user that are not in a section",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Link"
+                    }
+                },
+                "public_click_counts": {
+                    "description": "PublicClickCounts shows the click counts of the user's links on their\npublic profile",
+                    "type": "boolean",
+                    "example": false
+                },
+                "sections": {
+                    "description": "Sections groups the user's links under ordered headers",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Section"
+                    }
+                },
+                "show_bot_traffic": {
+                    "description": "ShowBotTraffic includes clicks taken for automated traffic in the\nuser's click charts and breakdowns",
+                    "type": "boolean",
+                    "example": false
+                },
+                "updated_at": {
+                    "description": "UpdatedAt timestamp",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "username": {
+                    "description": "Username is the unique identifier for the user",
+                    "type": "string",
+                    "example": "johndoe"
+                },
+                "utm": {
+                    "description": "UTM is the default template of UTM parameters added to the user's\noutgoing links",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UTMTemplate"
+                        }
+                    ]
+                }
+            }
+        },
+        "services.BreakdownEntry": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Clicks is the number of clicks with the value",
+                    "type": "integer",
+                    "example": 420
+                },
+                "share": {
+                    "description": "Share is the fraction of all clicks in the period with the value",
+                    "type": "number",
+                    "example": 0.34
+                },
+                "value": {
+                    "description": "Value is the source domain, device class, browser, OS, country, region,\ncity or destination URL",
+                    "type": "string",
+                    "example": "instagram.com"
+                }
+            }
+        },
+        "services.CTRReport": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+01:00"
+                },
+                "includes_bots": {
+                    "description": "IncludesBots is true when the owner chose to see automated clicks",
+                    "type": "boolean",
+                    "example": false
+                },
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.LinkCTR"
+                    }
+                },
+                "profile_views": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Berlin"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-02-01T00:00:00+01:00"
+                },
+                "unique_viewers": {
+                    "description": "UniqueViewers counts each visitor of the profile once per UTC day",
+                    "type": "integer",
+                    "example": 150
+                },
+                "unique_visitors": {
+                    "description": "UniqueVisitors counts each visitor who clicked any of the links once\nper UTC day",
+                    "type": "integer",
+                    "example": 90
+                }
+            }
+        },
+        "services.ClickBreakdown": {
+            "type": "object",
+            "properties": {
+                "browsers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "cities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "countries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "destinations": {
+                    "description": "Destinations are the URLs the link pointed at when its clicks happened,\nas kept in its revision history; only link breakdowns have them",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00+01:00"
+                },
+                "includes_bots": {
+                    "description": "IncludesBots is true when the owner chose to see automated clicks",
+                    "type": "boolean",
+                    "example": false
+                },
+                "operating_systems": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "regions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "sources": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.BreakdownEntry"
+                    }
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Berlin"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-02-01T00:00:00+01:00"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1234
+                },
+                "unique_visitors": {
+                    "description": "UniqueVisitors counts each visitor once per UTC day they clicked",
+                    "type": "integer",
+                    "example": 980
+                }
+            }
+        },
+        "services.ClickIngesterStats": {
+            "type": "object",
+            "properties": {
+                "average_flush_ms": {
+                    "description": "AverageFlushMs is the mean batch write time",
+                    "type": "number",
+                    "example": 3.8
+                },
+                "dropped": {
+                    "description": "Dropped counts clicks lost to a full queue or a failed write",
+                    "type": "integer",
+                    "example": 0
+                },
+                "enabled": {
+                    "description": "Enabled is false when clicks are written while the visitor waits",
+                    "type": "boolean",
+                    "example": true
+                },
+                "enqueued": {
+                    "description": "Enqueued counts clicks queued since start",
+                    "type": "integer",
+                    "example": 52000
+                },
+                "failed_clicks": {
+                    "description": "FailedClicks counts the clicks of batch writes that failed; they are\nincluded in Dropped",
+                    "type": "integer",
+                    "example": 0
+                },
+                "flush_errors": {
+                    "description": "FlushErrors counts batch writes that failed",
+                    "type": "integer",
+                    "example": 0
+                },
+                "flushes": {
+                    "description": "Flushes counts batch writes",
+                    "type": "integer",
+                    "example": 260
+                },
+                "last_flush_ms": {
+                    "description": "LastFlushMs is how long the last batch write took",
+                    "type": "number",
+                    "example": 4.2
+                },
+                "max_flush_ms": {
+                    "description": "MaxFlushMs is the slowest batch write",
+                    "type": "number",
+                    "example": 41.5
+                },
+                "overflowed": {
+                    "description": "Overflowed counts clicks that found the queue full and were written\nwhile the visitor waited",
+                    "type": "integer",
+                    "example": 3
+                },
+                "queue_capacity": {
+                    "description": "QueueCapacity is how many clicks can wait",
+                    "type": "integer",
+                    "example": 10000
+                },
+                "queue_depth": {
+                    "description": "QueueDepth is how many clicks are waiting to be written",
+                    "type": "integer",
+                    "example": 12
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.CreateLinkRequest": {
+        },
+        "services.ClickSeries": {
             "type": "object",
-            "required": [
-                "title",
-                "url"
-            ],
             "properties": {
-                "title": {
+                "from": {
                     "type": "string",
-                    "example": "My GitHub"
+                    "example": "2024-01-01T00:00:00+01:00"
                 },
-                "url": {
+                "includes_bots": {
+                    "description": "IncludesBots is true when the owner chose to see automated clicks",
+                    "type": "boolean",
+                    "example": false
+                },
+                "interval": {
                     "type": "string",
-                    "example": "https://github.com/johndoe"
+                    "example": "day"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.SeriesPoint"
+                    }
+                },
+                "timezone": {
+                    "type": "string",
+                    "example": "Europe/Berlin"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2024-02-01T00:00:00+01:00"
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 1234
+                },
+                "unique_visitors": {
+                    "description": "UniqueVisitors counts each visitor once per UTC day they clicked",
+                    "type": "integer",
+                    "example": 980
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "services.ImportReport": {
             "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
             "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "securepassword123"
+                "dry_run": {
+                    "type": "boolean",
+                    "example": true
                 },
-                "username": {
-                    "type": "string",
-                    "example": "johndoe"
+                "duplicates": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "imported": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "invalid": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/services.ImportRowResult"
+                    }
+                },
+                "valid": {
+                    "type": "integer",
+                    "example": 10
                 }
             }
         },
-        "handlers.SignUpRequest": {
+        "services.ImportRowResult": {
             "type": "object",
-            "required": [
-                "full_name",
-                "password",
-                "username"
-            ],
             "properties": {
-                "bio": {
+                "error": {
                     "type": "string",
-                    "example": "Software Developer | Tech Enthusiast"
+                    "example": "invalid url"
                 },
-                "full_name": {
+                "row": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
                     "type": "string",
-                    "example": "John Doe"
+                    "enum": [
+                        "ok",
+                        "duplicate",
+                        "invalid"
+                    ],
+                    "example": "ok"
                 },
-                "password": {
+                "title": {
                     "type": "string",
-                    "example": "securepassword123"
+                    "example": "My GitHub"
                 },
-                "username": {
+                "url": {
                     "type": "string",
-                    "example": "johndoe"
+                    "example": "https://github.com/johndoe"
                 }
             }
         },
-        "models.Analytics": {
-            "description": "Analytics data for tracking link usage",
+        "services.LinkCTR": {
             "type": "object",
             "properties": {
-                "click_count": {
-                    "description": "ClickCount tracks number of clicks",
+                "clicks": {
+                    "description": "Clicks is the number of clicks on the link in the period",
                     "type": "integer",
                     "example": 42
                 },
-                "created_at": {
-                    "description": "CreatedAt timestamp",
-                    "type": "string",
-                    "example": "2024-01-01T00:00:00Z"
-                },
-                "id": {
-                    "description": "ID is the unique identifier",
-                    "type": "integer",
-                    "example": 1
+                "ctr": {
+                    "description": "CTR is clicks per profile view; 0 without views",
+                    "type": "number",
+                    "example": 0.21
                 },
                 "link_id": {
-                    "description": "LinkID is the foreign key to the associated link",
+                    "description": "LinkID is the link's ID",
                     "type": "integer",
                     "example": 1
                 },
-                "updated_at": {
-                    "description": "UpdatedAt timestamp",
+                "title": {
+                    "description": "Title is the link's title",
                     "type": "string",
-                    "example": "2024-01-01T00:00:00Z"
+                    "example": "My Website"
                 },
-                "visitors_usernames": {
-                    "description": "VisitorsUsernames stores usernames of visitors\nswagger:strfmt json",
-                    "type": "string",
-                    "example": "[\"user1\", \"user2\"]"
+                "unique_visitors": {
+                    "description": "UniqueVisitors counts each visitor who clicked the link once per UTC\nday",
+                    "type": "integer",
+                    "example": 30
                 }
             }
         },
-        "models.Link": {
-            "description": "A link entry with associated analytics",
+        "services.LinkPage": {
             "type": "object",
             "properties": {
-                "analytics": {
-                    "description": "Analytics data for this link",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/models.Analytics"
-                        }
-                    ]
+                "links": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Link"
+                    }
+                },
+                "page": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "per_page": {
+                    "type": "integer",
+                    "example": 20
                 },
+                "total": {
+                    "type": "integer",
+                    "example": 42
+                }
+            }
+        },
+        "services.LinkRecord": {
+            "type": "object",
+            "properties": {
                 "created_at": {
-                    "description": "CreatedAt timestamp",
                     "type": "string",
                     "example": "2024-01-01T00:00:00Z"
                 },
-                "id": {
-                    "description": "ID is the unique identifier",
+                "description": {
+                    "type": "string",
+                    "example": "Open source projects by John Doe"
+                },
+                "image_url": {
+                    "type": "string",
+                    "example": "https://github.com/johndoe.png"
+                },
+                "position": {
                     "type": "integer",
-                    "example": 1
+                    "example": 0
                 },
-                "title": {
-                    "description": "Title of the link",
+                "section": {
                     "type": "string",
-                    "example": "My GitHub Profile"
+                    "example": "Code"
                 },
-                "updated_at": {
-                    "description": "UpdatedAt timestamp",
+                "sensitive": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "slug": {
                     "type": "string",
-                    "example": "2024-01-01T00:00:00Z"
+                    "example": "johndoe-github"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "code",
+                        "personal"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "My GitHub"
                 },
                 "url": {
-                    "description": "URL of the link",
                     "type": "string",
-                    "example": "https://github.com/username"
+                    "example": "https://github.com/johndoe"
+                }
+            }
+        },
+        "services.RuleResult": {
+            "type": "object",
+            "properties": {
+                "language": {
+                    "type": "string",
+                    "example": "de-DE"
                 },
-                "user_id": {
-                    "description": "UserID is the foreign key to the owner",
+                "platform": {
+                    "type": "string",
+                    "example": "ios"
+                },
+                "rule_index": {
                     "type": "integer",
-                    "example": 1
+                    "example": 0
+                },
+                "target": {
+                    "type": "string",
+                    "example": "https://apps.apple.com/app/id123456789"
                 }
             }
         },
-        "models.User": {
-            "description": "A user account with profile information and associated links",
+        "services.SeriesPoint": {
             "type": "object",
             "properties": {
-                "bio": {
-                    "description": "Bio contains user's description",
+                "clicks": {
+                    "description": "Clicks is the number of clicks in the bucket",
+                    "type": "integer",
+                    "example": 42
+                },
+                "start": {
+                    "description": "Start is when the bucket starts, in the series' timezone",
                     "type": "string",
-                    "example": "Software developer passionate about Go"
+                    "example": "2024-01-01T00:00:00+01:00"
+                }
+            }
+        },
+        "services.VariantStats": {
+            "type": "object",
+            "properties": {
+                "clicks": {
+                    "description": "Clicks counts visitors who followed the variant",
+                    "type": "integer",
+                    "example": 12
                 },
                 "created_at": {
                     "description": "CreatedAt timestamp",
                     "type": "string",
                     "example": "2024-01-01T00:00:00Z"
                 },
-                "full_name": {
-                    "description": "FullName of the user",
-                    "type": "string",
-                    "example": "John Doe"
+                "ctr": {
+                    "description": "CTR is clicks per impression",
+                    "type": "number",
+                    "example": 0.1
                 },
                 "id": {
                     "description": "ID is the unique identifier",
                     "type": "integer",
                     "example": 1
                 },
-                "links": {
-                    "description": "Links associated with this user",
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Link"
-                    }
+                "impressions": {
+                    "description": "Impressions counts profile views the variant was shown in",
+                    "type": "integer",
+                    "example": 120
+                },
+                "link_id": {
+                    "description": "LinkID is the foreign key to the tested link",
+                    "type": "integer",
+                    "example": 1
+                },
+                "significant": {
+                    "description": "Significant is true when the difference from the first variant holds\nat 95% confidence",
+                    "type": "boolean",
+                    "example": true
+                },
+                "title": {
+                    "description": "Title replaces the link's title, the link's own title is used if empty",
+                    "type": "string",
+                    "example": "Listen to my podcast"
                 },
                 "updated_at": {
                     "description": "UpdatedAt timestamp",
                     "type": "string",
                     "example": "2024-01-01T00:00:00Z"
                 },
-                "username": {
-                    "description": "Username is the unique identifier for the user",
+                "url": {
+                    "description": "URL replaces the link's destination, the link's own URL is used if empty",
                     "type": "string",
-                    "example": "johndoe"
+                    "example": "https://podcast.example.com/latest"
+                },
+                "weight": {
+                    "description": "Weight is the variant's share of traffic relative to the other variants",
+                    "type": "integer",
+                    "example": 1
+                },
+                "z_score": {
+                    "description": "ZScore compares the CTR with the first variant's; positive is better",
+                    "type": "number",
+                    "example": 2.3
                 }
             }
         }
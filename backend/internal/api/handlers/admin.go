@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"linktree-mohamedfadel-backend/internal/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminHandler struct {
+	URLPolicy *services.URLPolicy
+}
+
+type BlockDomainsRequest struct {
+	Domains []string `json:"domains" binding:"required,min=1" example:"phishing.example"`
+}
+
+type BlocklistResponse struct {
+	Domains []string `json:"domains" example:"phishing.example"`
+}
+
+func NewAdminHandler(urlPolicy *services.URLPolicy) *AdminHandler {
+	return &AdminHandler{URLPolicy: urlPolicy}
+}
+
+// GetBlocklistHandler godoc
+// @Summary Get the domain blocklist
+// @Description List the domains that links may not point to. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} BlocklistResponse "Blocked domains"
+// @Failure 401 "error: Unauthorized"
+// @Failure 403 "error: Admin access required"
+// @Router /admin/blocklist [get]
+func (h *AdminHandler) GetBlocklistHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, BlocklistResponse{Domains: h.URLPolicy.BlockedDomains()})
+}
+
+// BlockDomainsHandler godoc
+// @Summary Block domains
+// @Description Add domains, including all their subdomains, to the blocklist. New and updated links pointing to them are rejected. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param domains body BlockDomainsRequest true "Domains to block"
+// @Security BearerAuth
+// @Success 200 {object} BlocklistResponse "Blocked domains"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Failure 403 "error: Admin access required"
+// @Router /admin/blocklist [post]
+func (h *AdminHandler) BlockDomainsHandler(c *gin.Context) {
+	var requestBody BlockDomainsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.URLPolicy.BlockDomains(requestBody.Domains); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, BlocklistResponse{Domains: h.URLPolicy.BlockedDomains()})
+}
+
+// UnblockDomainHandler godoc
+// @Summary Unblock a domain
+// @Description Remove a domain from the blocklist. Admin only.
+// @Tags admin
+// @Produce json
+// @Param domain path string true "Domain" example(phishing.example)
+// @Security BearerAuth
+// @Success 200 {object} BlocklistResponse "Blocked domains"
+// @Failure 401 "error: Unauthorized"
+// @Failure 403 "error: Admin access required"
+// @Failure 404 "error: Domain is not blocked"
+// @Router /admin/blocklist/{domain} [delete]
+func (h *AdminHandler) UnblockDomainHandler(c *gin.Context) {
+	if err := h.URLPolicy.UnblockDomain(c.Param("domain")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, BlocklistResponse{Domains: h.URLPolicy.BlockedDomains()})
+}
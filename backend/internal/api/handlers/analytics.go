@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"linktree-mohamedfadel-backend/internal/services"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,11 +21,12 @@ func NewAnalyticsHandler(analyticsService *services.AnalyticsService) *Analytics
 
 // TrackLinkClickHandler godoc
 // @Summary Track a link click
-// @Description Records a click event for a specific link. If the request includes authentication, the click will be associated with the authenticated user.
+// @Description Records a click event for a specific link. The referrer, user agent, the location from CDN headers or the geolocation database and a hash of the IP address and user agent keyed with a salt that changes every day are stored with the click. Neither the IP address nor who clicked is stored. Pass the variant_id from the profile as variant to attribute the click to an A/B test variant.
 // @Tags analytics
 // @Accept json
 // @Produce json
 // @Param id path int true "Link ID" example(1)
+// @Param variant query int false "ID of the A/B test variant that was clicked" example(1)
 // @Security BearerAuth
 // @Success 200 "message: Click tracked successfully"
 // @Failure 400 "error: Invalid link ID"
@@ -37,17 +41,336 @@ func (h *AnalyticsHandler) TrackLinkClickHandler(c *gin.Context) {
 		return
 	}
 
-	var username string
-	if usernameInterface, exists := c.Get("username"); exists {
-		if usernameStr, ok := usernameInterface.(string); ok {
-			username = usernameStr
+	details := h.AnalyticsService.ClassifyClick(clickDetails(c))
+	if err := h.AnalyticsService.TrackLinkClicks(uint64(linkId), details); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if variant := c.Query("variant"); variant != "" {
+		variantId, err := strconv.ParseUint(variant, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+			return
+		}
+		// Automated clicks would skew the A/B test's click-through rates.
+		if details.BotReason == "" {
+			if err := h.AnalyticsService.TrackVariantClick(linkId, uint(variantId)); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
 		}
 	}
 
-	if err := h.AnalyticsService.TrackLinkClicks(uint64(linkId), username); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	c.JSON(http.StatusOK, gin.H{"message": "Click tracked successfully"})
+}
+
+// GetVariantReportHandler godoc
+// @Summary Get A/B test report
+// @Description Report impressions, clicks and click-through rate of each variant of a link. Each variant is compared with the first one using a two-proportion z-test; significant means the difference holds at 95% confidence with at least 30 impressions on both sides.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Security BearerAuth
+// @Success 200 {array} services.VariantStats "Variant statistics"
+// @Failure 400 "error: Invalid link ID"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Link not found"
+// @Router /links/{id}/variants [get]
+func (h *AnalyticsHandler) GetVariantReportHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Click tracked successfully"})
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	report, err := h.AnalyticsService.GetVariantReport(username.(string), linkId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetIngestionStatsHandler godoc
+// @Summary Get click ingestion stats
+// @Description Report the depth of the click ingestion queue, how many clicks overflowed it or were dropped, how many of those were lost to failed batch writes, and how long batch writes take. Admin only.
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.ClickIngesterStats "Ingestion stats"
+// @Failure 401 "error: Unauthorized"
+// @Failure 403 "error: Admin access required"
+// @Router /admin/analytics/ingestion [get]
+func (h *AnalyticsHandler) GetIngestionStatsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, h.AnalyticsService.IngestionStats())
+}
+
+// GetLinkClickSeriesHandler godoc
+// @Summary Get a link's clicks over time
+// @Description Return the clicks on one of the authenticated user's links bucketed by hour, day or week. The range covers whole days from the start date to the end date in the given timezone, by default the last 30 days in UTC. Weeks start on Monday. Clicks are aggregated per UTC hour, so in timezones offset by a fraction of an hour each hour is counted in the bucket its start falls in.
+// @Tags analytics
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param from query string false "First day, YYYY-MM-DD" example(2024-01-01)
+// @Param to query string false "Last day, YYYY-MM-DD" example(2024-01-31)
+// @Param interval query string false "hour, day (default) or week" example(day)
+// @Param tz query string false "IANA timezone, UTC by default" example(Europe/Berlin)
+// @Security BearerAuth
+// @Success 200 {object} services.ClickSeries "Click series"
+// @Failure 400 "error: Invalid link ID"
+// @Failure 400 "error: Invalid date range"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Link not found"
+// @Router /analytics/links/{id} [get]
+func (h *AnalyticsHandler) GetLinkClickSeriesHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	query, err := seriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := h.AnalyticsService.GetLinkClickSeries(username.(string), linkId, query)
+	if err != nil {
+		writeSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetProfileClickSeriesHandler godoc
+// @Summary Get a profile's clicks over time
+// @Description Return the clicks on all of the authenticated user's links bucketed by hour, day or week, with the same range and timezone rules as the per-link series.
+// @Tags analytics
+// @Produce json
+// @Param from query string false "First day, YYYY-MM-DD" example(2024-01-01)
+// @Param to query string false "Last day, YYYY-MM-DD" example(2024-01-31)
+// @Param interval query string false "hour, day (default) or week" example(day)
+// @Param tz query string false "IANA timezone, UTC by default" example(Europe/Berlin)
+// @Security BearerAuth
+// @Success 200 {object} services.ClickSeries "Click series"
+// @Failure 400 "error: Invalid date range"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /analytics/profile [get]
+func (h *AnalyticsHandler) GetProfileClickSeriesHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	query, err := seriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	series, err := h.AnalyticsService.GetProfileClickSeries(username.(string), query)
+	if err != nil {
+		writeSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, series)
+}
+
+// GetLinkClickBreakdownHandler godoc
+// @Summary Get where a link's clicks came from
+// @Description Return the most frequent source domains, device classes (desktop, mobile, tablet, tv, bot, unknown), browsers, operating systems, countries, regions, cities and destinations of the clicks on one of the authenticated user's links. Clicks without a referrer come from "direct"; cities are listed with their country, e.g. "Berlin, DE". Destinations are the URLs the link pointed at when the clicks happened, following its revision history. The period covers whole days from the start date to the end date in the given timezone, by default the last 30 days in UTC.
+// @Tags analytics
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param from query string false "First day, YYYY-MM-DD" example(2024-01-01)
+// @Param to query string false "Last day, YYYY-MM-DD" example(2024-01-31)
+// @Param tz query string false "IANA timezone, UTC by default" example(Europe/Berlin)
+// @Param limit query int false "Values per dimension, 10 by default and at most 100" example(10)
+// @Security BearerAuth
+// @Success 200 {object} services.ClickBreakdown "Click breakdown"
+// @Failure 400 "error: Invalid link ID"
+// @Failure 400 "error: Invalid date range"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Link not found"
+// @Router /analytics/links/{id}/breakdown [get]
+func (h *AnalyticsHandler) GetLinkClickBreakdownHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	query, err := seriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := breakdownLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.AnalyticsService.GetLinkClickBreakdown(username.(string), linkId, query, limit)
+	if err != nil {
+		writeSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// GetProfileClickBreakdownHandler godoc
+// @Summary Get where a profile's clicks came from
+// @Description Return the most frequent source domains, device classes, browsers, operating systems, countries, regions and cities of the clicks on all of the authenticated user's links, with the same range and timezone rules as the per-link breakdown.
+// @Tags analytics
+// @Produce json
+// @Param from query string false "First day, YYYY-MM-DD" example(2024-01-01)
+// @Param to query string false "Last day, YYYY-MM-DD" example(2024-01-31)
+// @Param tz query string false "IANA timezone, UTC by default" example(Europe/Berlin)
+// @Param limit query int false "Values per dimension, 10 by default and at most 100" example(10)
+// @Security BearerAuth
+// @Success 200 {object} services.ClickBreakdown "Click breakdown"
+// @Failure 400 "error: Invalid date range"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /analytics/profile/breakdown [get]
+func (h *AnalyticsHandler) GetProfileClickBreakdownHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	query, err := seriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := breakdownLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	breakdown, err := h.AnalyticsService.GetProfileClickBreakdown(username.(string), query, limit)
+	if err != nil {
+		writeSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+func breakdownLimit(c *gin.Context) (int, error) {
+	value := c.Query("limit")
+	if value == "" {
+		return services.DefaultBreakdownLimit, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > services.MaxBreakdownLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", services.MaxBreakdownLimit)
+	}
+	return limit, nil
+}
+
+// seriesQuery reads the range, interval and timezone of a click series.
+func seriesQuery(c *gin.Context) (services.SeriesQuery, error) {
+	query := services.SeriesQuery{Interval: c.Query("interval"), Location: time.UTC}
+
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return query, fmt.Errorf("unknown timezone %q", tz)
+		}
+		query.Location = loc
+	}
+
+	for name, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := c.Query(name); value != "" {
+			parsed, err := time.ParseInLocation("2006-01-02", value, query.Location)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s date, expected YYYY-MM-DD", name)
+			}
+			*target = parsed
+		}
+	}
+
+	return query, nil
+}
+
+func writeSeriesError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidSeriesQuery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+}
+
+// GetCTRReportHandler godoc
+// @Summary Get link click-through rates
+// @Description Return how often the authenticated user's profile was viewed in a period and, for each of their links, the clicks and the click-through rate (clicks per profile view). Views by the owner and by bots are not counted. The period covers whole days from the start date to the end date in the given timezone, by default the last 30 days in UTC.
+// @Tags analytics
+// @Produce json
+// @Param from query string false "First day, YYYY-MM-DD" example(2024-01-01)
+// @Param to query string false "Last day, YYYY-MM-DD" example(2024-01-31)
+// @Param tz query string false "IANA timezone, UTC by default" example(Europe/Berlin)
+// @Security BearerAuth
+// @Success 200 {object} services.CTRReport "Click-through rates"
+// @Failure 400 "error: Invalid date range"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /analytics/ctr [get]
+func (h *AnalyticsHandler) GetCTRReportHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	query, err := seriesQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.AnalyticsService.GetCTRReport(username.(string), query)
+	if err != nil {
+		writeSeriesError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
 }
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"linktree-mohamedfadel-backend/internal/models"
+	"linktree-mohamedfadel-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+type BlockHandler struct {
+	BlockService     *services.BlockService
+	AnalyticsService *services.AnalyticsService
+}
+
+type CreateBlockRequest struct {
+	Type    string          `json:"type" binding:"required" example:"text" enums:"text,header,divider,image,video,email_signup,contact_form"`
+	Payload json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+type UpdateBlockRequest struct {
+	Payload json.RawMessage `json:"payload" binding:"required" swaggertype:"object"`
+}
+
+func NewBlockHandler(blockService *services.BlockService, analyticsService *services.AnalyticsService) *BlockHandler {
+	return &BlockHandler{BlockService: blockService, AnalyticsService: analyticsService}
+}
+
+// CreateBlockHandler godoc
+// @Summary Create a new block
+// @Description Append a typed content block to the authenticated user's profile. The payload is validated against the block type, and image and video URLs must pass the URL policy; link blocks are created through the links API.
+// @Tags blocks
+// @Accept json
+// @Produce json
+// @Param block body CreateBlockRequest true "Block type and payload"
+// @Security BearerAuth
+// @Success 201 {object} models.Block "Created block"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /blocks [post]
+func (h *BlockHandler) CreateBlockHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody CreateBlockRequest
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	block, err := h.BlockService.CreateBlock(username.(string), models.Block{
+		Type:    requestBody.Type,
+		Payload: datatypes.JSON(requestBody.Payload),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, block)
+}
+
+// GetBlocksHandler godoc
+// @Summary List a user's blocks
+// @Description Retrieve the ordered list of typed blocks making up a user's public profile. Links under A/B test are shown as the variant the visitor is assigned to, kept stable by a visitor_id cookie, with variant_id set and the tracked URL attributing clicks to that variant, and count as impressions of it. Bots see links under A/B test as saved.
+// @Tags blocks
+// @Accept json
+// @Produce json
+// @Param username path string true "Username" example:"johndoe"
+// @Success 200 {array} models.Block "Ordered profile blocks"
+// @Failure 404 "error: User not found"
+// @Router /users/{username}/blocks [get]
+func (h *BlockHandler) GetBlocksHandler(c *gin.Context) {
+	username := c.Param("username")
+
+	// Bots see the links as saved, so they never count as impressions of an
+	// A/B test variant.
+	details := h.AnalyticsService.ClassifyClick(clickDetails(c))
+	visitorId := details.VisitorID
+	if details.BotReason != "" {
+		visitorId = ""
+	}
+
+	blocks, err := h.BlockService.GetBlocksForVisitor(username, visitorId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+// UpdateBlockHandler godoc
+// @Summary Update a block
+// @Description Replace the payload of one of the authenticated user's blocks. The block type cannot change.
+// @Tags blocks
+// @Accept json
+// @Produce json
+// @Param id path int true "Block ID" example(1)
+// @Param block body UpdateBlockRequest true "New payload"
+// @Security BearerAuth
+// @Success 200 "message: Block updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /blocks/{id} [put]
+func (h *BlockHandler) UpdateBlockHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	blockId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block ID"})
+		return
+	}
+
+	var requestBody UpdateBlockRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err = h.BlockService.UpdateBlock(username.(string), blockId, datatypes.JSON(requestBody.Payload))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Block updated successfully"})
+}
+
+// ReorderBlocksHandler godoc
+// @Summary Reorder blocks
+// @Description Set the display order of the authenticated user's blocks. The list must contain every block ID exactly once.
+// @Tags blocks
+// @Accept json
+// @Produce json
+// @Param order body ReorderRequest true "Block IDs in display order"
+// @Security BearerAuth
+// @Success 200 "message: Blocks reordered successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /blocks/order [put]
+func (h *BlockHandler) ReorderBlocksHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody ReorderRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.BlockService.ReorderBlocks(username.(string), requestBody.IDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blocks reordered successfully"})
+}
+
+// DeleteBlockHandler godoc
+// @Summary Delete a block
+// @Description Delete one of the authenticated user's blocks. Link blocks are removed by deleting the link.
+// @Tags blocks
+// @Accept json
+// @Produce json
+// @Param id path int true "Block ID" example(1)
+// @Security BearerAuth
+// @Success 200 "message: Block deleted successfully"
+// @Failure 400 "error: Invalid block ID"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Block not found"
+// @Router /blocks/{id} [delete]
+func (h *BlockHandler) DeleteBlockHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	blockId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid block ID"})
+		return
+	}
+
+	if err := h.BlockService.DeleteBlock(username.(string), blockId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Block deleted successfully"})
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
 	"linktree-mohamedfadel-backend/internal/models"
 	"linktree-mohamedfadel-backend/internal/services"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,17 +18,66 @@ type LinkHandler struct {
 }
 
 type CreateLinkRequest struct {
-	Title string `json:"title" binding:"required" example:"My GitHub"`
-	URL   string `json:"url" binding:"required" example:"https://github.com/johndoe"`
+	Title         string `json:"title" example:"My GitHub"`
+	URL           string `json:"url" binding:"required" example:"https://github.com/johndoe"`
+	Slug          string `json:"slug" example:"johndoe-github"`
+	SectionID     *uint  `json:"section_id" example:"1"`
+	Sensitive     bool   `json:"sensitive" example:"false"`
+	FetchMetadata bool   `json:"fetch_metadata" example:"true"`
 }
 
+type UpdateLinkRequest struct {
+	Title     string `json:"title" example:"My GitHub"`
+	URL       string `json:"url" example:"https://github.com/johndoe"`
+	Slug      string `json:"slug" example:"johndoe-github"`
+	SectionID *uint  `json:"section_id" example:"1"`
+	Sensitive *bool  `json:"sensitive" example:"true"`
+}
+
+type LinkProtectionRequest struct {
+	Password  *string `json:"password" example:"open-sesame"`
+	Sensitive *bool   `json:"sensitive" example:"false"`
+}
+
+type LinkRulesRequest struct {
+	Rules []models.LinkRule `json:"rules"`
+}
+
+type TestLinkRulesRequest struct {
+	UserAgent      string            `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	AcceptLanguage string            `json:"accept_language" example:"de-DE,de;q=0.9"`
+	Referrer       string            `json:"referrer" example:"https://www.instagram.com/"`
+	Time           time.Time         `json:"time" example:"2024-01-01T12:00:00Z"`
+	Rules          []models.LinkRule `json:"rules"`
+}
+
+type LinkVariantRequest struct {
+	Title  string `json:"title" example:"Listen to my podcast"`
+	URL    string `json:"url" example:"https://podcast.example.com/latest"`
+	Weight uint   `json:"weight" example:"1"`
+}
+
+type LinkLimitsRequest struct {
+	MaxClicks    *uint      `json:"max_clicks" example:"100"`
+	ExpiresAt    *time.Time `json:"expires_at" example:"2024-12-31T23:59:59Z"`
+	ExpiryAction string     `json:"expiry_action" example:"fallback" enums:"hide,sold_out,fallback"`
+	FallbackURL  string     `json:"fallback_url" example:"https://shop.example.com/waitlist"`
+	ResetClicks  bool       `json:"reset_clicks" example:"false"`
+}
+
+type LinkTagsRequest struct {
+	Tags []string `json:"tags" example:"podcast,audio"`
+}
+
+const maxImportSize = 5 << 20
+
 func NewLinkHandler(linkService *services.LinkService) *LinkHandler {
 	return &LinkHandler{LinkService: linkService}
 }
 
 // CreateLinkHandler godoc
 // @Summary Create a new link
-// @Description Create a new link for the authenticated user's profile
+// @Description Create a new link for the authenticated user's profile. A random short slug is generated when none is given. When the title is left out, or fetch_metadata is set, the page is fetched to fill in the title, description, preview image and favicon.
 // @Tags links
 // @Accept json
 // @Produce json
@@ -34,6 +87,7 @@ func NewLinkHandler(linkService *services.LinkService) *LinkHandler {
 // @Failure 400 "error: Invalid input"
 // @Failure 401 "error: Unauthorized"
 // @Failure 400 "error: Link already exists"
+// @Failure 400 "error: url rejected: scheme \"javascript\" is not allowed"
 // @Router /links [post]
 func (h *LinkHandler) CreateLinkHandler(c *gin.Context) {
 	username, exists := c.Get("username")
@@ -49,11 +103,25 @@ func (h *LinkHandler) CreateLinkHandler(c *gin.Context) {
 	}
 
 	newLink := models.Link{
-		Title: requestBody.Title,
-		URL:   requestBody.URL,
+		Title:     requestBody.Title,
+		URL:       requestBody.URL,
+		Slug:      requestBody.Slug,
+		SectionID: requestBody.SectionID,
+		Sensitive: requestBody.Sensitive,
 	}
 
-	if err := h.LinkService.CreateLink(username.(string), newLink); err != nil {
+	if requestBody.FetchMetadata && newLink.Title != "" {
+		if err := h.LinkService.FillLinkMetadata(c.Request.Context(), &newLink); err != nil {
+			if errors.Is(err, services.ErrURLRejected) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to fetch metadata: " + err.Error()})
+			return
+		}
+	}
+
+	if err := h.LinkService.CreateLink(c.Request.Context(), username.(string), newLink); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -68,7 +136,7 @@ func (h *LinkHandler) CreateLinkHandler(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path int true "Link ID" example(1)
-// @Param link body CreateLinkRequest true "Updated link details"
+// @Param link body UpdateLinkRequest true "Updated link details"
 // @Security BearerAuth
 // @Success 200 "message: Link updated successfully"
 // @Failure 400 "error: Invalid input"
@@ -89,18 +157,32 @@ func (h *LinkHandler) UpdateLinkHandler(c *gin.Context) {
 		return
 	}
 
-	var updatedLink models.Link
-	if err := c.ShouldBindJSON(&updatedLink); err != nil {
+	var requestBody UpdateLinkRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
+	updatedLink := models.Link{
+		Title:     requestBody.Title,
+		URL:       requestBody.URL,
+		Slug:      requestBody.Slug,
+		SectionID: requestBody.SectionID,
+	}
+
 	err = h.LinkService.UpdateLink(username.(string), linkId, updatedLink)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if requestBody.Sensitive != nil {
+		if err := h.LinkService.SetLinkSensitive(username.(string), linkId, *requestBody.Sensitive); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Link updated successfully"})
 }
 
@@ -139,3 +221,727 @@ func (h *LinkHandler) DeleteLinkHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Link deleted successfully"})
 }
+
+// SetLinkProtectionHandler godoc
+// @Summary Set link protection
+// @Description Gate a link behind a password and/or a sensitive content warning. An empty password removes the password. Protected links have their URL hidden on the public profile.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param protection body LinkProtectionRequest true "Protection settings"
+// @Security BearerAuth
+// @Success 200 "message: Link protection updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 400 "error: Link not found"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/protection [put]
+func (h *LinkHandler) SetLinkProtectionHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var requestBody LinkProtectionRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if requestBody.Password == nil && requestBody.Sensitive == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if requestBody.Password != nil {
+		if err := h.LinkService.SetLinkPassword(username.(string), linkId, *requestBody.Password); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if requestBody.Sensitive != nil {
+		if err := h.LinkService.SetLinkSensitive(username.(string), linkId, *requestBody.Sensitive); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Link protection updated successfully"})
+}
+
+// SetLinkRulesHandler godoc
+// @Summary Set link rules
+// @Description Replace the targeting rules of a link. The redirect endpoint sends visitors to the target of the first rule matching their platform, language, referrer and time of day, and to the link's URL when none match. An empty list removes all rules.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param rules body LinkRulesRequest true "Ordered rules"
+// @Security BearerAuth
+// @Success 200 "message: Link rules updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/rules [put]
+func (h *LinkHandler) SetLinkRulesHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var requestBody LinkRulesRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.LinkService.SetLinkRules(username.(string), linkId, requestBody.Rules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Link rules updated successfully"})
+}
+
+// TestLinkRulesHandler godoc
+// @Summary Test link rules
+// @Description Evaluate a link's rules against a simulated visit and return where it would be redirected. Pass rules to try them without saving; otherwise the saved rules are used. The time defaults to now.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param visit body TestLinkRulesRequest true "Simulated visit"
+// @Security BearerAuth
+// @Success 200 {object} services.RuleResult "Redirect target"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/rules/test [post]
+func (h *LinkHandler) TestLinkRulesHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var requestBody TestLinkRulesRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	result, err := h.LinkService.TestLinkRules(username.(string), linkId, requestBody.Rules, services.RuleRequest{
+		UserAgent:      requestBody.UserAgent,
+		AcceptLanguage: requestBody.AcceptLanguage,
+		Referrer:       requestBody.Referrer,
+		Time:           requestBody.Time,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SetLinkLimitsHandler godoc
+// @Summary Set link limits
+// @Description Make a link stop working after max_clicks visitors or at expires_at. Clicks taken for bots and link previews do not count towards max_clicks. Once expired, the link is hidden from the profile (hide), shows a sold out page (sold_out, the default) or redirects to fallback_url (fallback). Omit both limits to remove them; reset_clicks restarts the count.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param limits body LinkLimitsRequest true "Limits"
+// @Security BearerAuth
+// @Success 200 "message: Link limits updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/limits [put]
+func (h *LinkHandler) SetLinkLimitsHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var requestBody LinkLimitsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err = h.LinkService.SetLinkLimits(username.(string), linkId, services.LinkLimits{
+		MaxClicks:    requestBody.MaxClicks,
+		ExpiresAt:    requestBody.ExpiresAt,
+		ExpiryAction: requestBody.ExpiryAction,
+		FallbackURL:  requestBody.FallbackURL,
+		ResetClicks:  requestBody.ResetClicks,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Link limits updated successfully"})
+}
+
+// SetLinkUTMHandler godoc
+// @Summary Set link UTM template
+// @Description Override fields of the profile's UTM template for one link. Empty fields fall back to the profile template, and an empty template removes the override.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param utm body models.UTMTemplate true "UTM template"
+// @Security BearerAuth
+// @Success 200 "message: Link UTM template updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/utm [put]
+func (h *LinkHandler) SetLinkUTMHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var template models.UTMTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.LinkService.SetLinkUTM(username.(string), linkId, template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Link UTM template updated successfully"})
+}
+
+// AddLinkVariantHandler godoc
+// @Summary Add a link variant
+// @Description Add an alternative title and/or URL to A/B test on a link. Profile visitors are split across a link's variants in proportion to their weights and keep seeing the same variant.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param variant body LinkVariantRequest true "Variant"
+// @Security BearerAuth
+// @Success 201 {object} models.LinkVariant "Created variant"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/variants [post]
+func (h *LinkHandler) AddLinkVariantHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var requestBody LinkVariantRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	variant, err := h.LinkService.AddLinkVariant(username.(string), linkId, models.LinkVariant{
+		Title:  requestBody.Title,
+		URL:    requestBody.URL,
+		Weight: requestBody.Weight,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variant)
+}
+
+// DeleteLinkVariantHandler godoc
+// @Summary Delete a link variant
+// @Description Remove a variant and its statistics from a link's A/B test
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param variantId path int true "Variant ID" example(1)
+// @Security BearerAuth
+// @Success 200 "message: Variant deleted successfully"
+// @Failure 400 "error: Invalid variant ID"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Variant not found"
+// @Router /links/{id}/variants/{variantId} [delete]
+func (h *LinkHandler) DeleteLinkVariantHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	linkId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	variantId, err := strconv.ParseUint(c.Param("variantId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	if err := h.LinkService.DeleteLinkVariant(username.(string), linkId, variantId); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
+
+// PromoteLinkVariantHandler godoc
+// @Summary Promote a link variant
+// @Description End a link's A/B test by copying the winning variant's title and URL onto the link. All of the link's variants are removed.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param variantId path int true "Variant ID" example(1)
+// @Security BearerAuth
+// @Success 200 {object} models.Link "Updated link"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/variants/{variantId}/promote [post]
+func (h *LinkHandler) PromoteLinkVariantHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	linkId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	variantId, err := strconv.ParseUint(c.Param("variantId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid variant ID"})
+		return
+	}
+
+	link, err := h.LinkService.PromoteLinkVariant(username.(string), linkId, variantId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// RefreshLinkMetadataHandler godoc
+// @Summary Refresh link preview
+// @Description Fetch the linked page again and update the link's description, preview image and favicon from its OpenGraph, Twitter card and HTML metadata
+// @Tags links
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Security BearerAuth
+// @Success 200 {object} models.Link "Updated link"
+// @Failure 400 "error: Invalid link ID"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Link not found"
+// @Failure 502 "error: Failed to fetch metadata"
+// @Router /links/{id}/metadata [post]
+func (h *LinkHandler) RefreshLinkMetadataHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	link, err := h.LinkService.RefreshLinkMetadata(c.Request.Context(), username.(string), linkId)
+	if err != nil {
+		// Only a fetch failure comes back with the link loaded.
+		status := http.StatusBadGateway
+		if link.ID == 0 {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// ListLinksHandler godoc
+// @Summary List links
+// @Description List the authenticated user's links with their tags, a page at a time. Repeat tag to only get links carrying all of the given tags.
+// @Tags links
+// @Produce json
+// @Param tag query []string false "Tags the links must carry" collectionFormat(multi)
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Links per page, at most 100" default(20)
+// @Security BearerAuth
+// @Success 200 {object} services.LinkPage "Page of links"
+// @Failure 400 "error: Invalid page"
+// @Failure 401 "error: Unauthorized"
+// @Router /links [get]
+func (h *LinkHandler) ListLinksHandler(c *gin.Context) {
+	h.searchLinks(c, "")
+}
+
+// SearchLinksHandler godoc
+// @Summary Search links
+// @Description Search the authenticated user's links by title, URL, description and tags, a page at a time. On Postgres results are ranked by full-text relevance; every word must otherwise appear in the link.
+// @Tags links
+// @Produce json
+// @Param q query string true "Search text" example(podcast)
+// @Param tag query []string false "Tags the links must carry" collectionFormat(multi)
+// @Param page query int false "Page number" default(1)
+// @Param per_page query int false "Links per page, at most 100" default(20)
+// @Security BearerAuth
+// @Success 200 {object} services.LinkPage "Page of matching links"
+// @Failure 400 "error: Search text is required"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/search [get]
+func (h *LinkHandler) SearchLinksHandler(c *gin.Context) {
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search text is required"})
+		return
+	}
+
+	h.searchLinks(c, text)
+}
+
+func (h *LinkHandler) searchLinks(c *gin.Context, text string) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+		return
+	}
+
+	perPage, err := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid per_page"})
+		return
+	}
+
+	result, err := h.LinkService.SearchLinks(username.(string), services.LinkQuery{
+		Text:    text,
+		Tags:    c.QueryArray("tag"),
+		Page:    page,
+		PerPage: perPage,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// SetLinkTagsHandler godoc
+// @Summary Set link tags
+// @Description Replace the free-form tags of a link. Tags are lowercased, duplicates dropped, and each is at most 32 characters.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param tags body LinkTagsRequest true "Tags"
+// @Security BearerAuth
+// @Success 200 {object} LinkTagsRequest "Saved tags"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/tags [put]
+func (h *LinkHandler) SetLinkTagsHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	var requestBody LinkTagsRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	tags, err := h.LinkService.SetLinkTags(username.(string), linkId, requestBody.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, LinkTagsRequest{Tags: tags})
+}
+
+// GetLinkRevisionsHandler godoc
+// @Summary List link revisions
+// @Description List the previous titles and URLs of a link with when each was live and who replaced it, most recent first
+// @Tags links
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Security BearerAuth
+// @Success 200 {array} models.LinkRevision "Previous versions"
+// @Failure 400 "error: Invalid link ID"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Link not found"
+// @Router /links/{id}/revisions [get]
+func (h *LinkHandler) GetLinkRevisionsHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	revisions, err := h.LinkService.GetLinkRevisions(username.(string), linkId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// RollbackLinkHandler godoc
+// @Summary Roll back a link
+// @Description Restore the title and URL of one of a link's revisions. The replaced version is kept as a new revision.
+// @Tags links
+// @Produce json
+// @Param id path int true "Link ID" example(1)
+// @Param revisionId path int true "Revision ID" example(1)
+// @Security BearerAuth
+// @Success 200 {object} models.Link "Restored link"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/{id}/revisions/{revisionId}/rollback [post]
+func (h *LinkHandler) RollbackLinkHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	linkId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	revisionId, err := strconv.ParseUint(c.Param("revisionId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID"})
+		return
+	}
+
+	link, err := h.LinkService.RollbackLink(username.(string), linkId, revisionId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// GetLinksHealthHandler godoc
+// @Summary Get link health
+// @Description Get the authenticated user's links with the result of their latest reachability check: status code, latency, final URL after redirects and failure streak
+// @Tags links
+// @Produce json
+// @Param broken query bool false "Only return links whose last check failed"
+// @Security BearerAuth
+// @Success 200 {array} models.Link "Links with health"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /links/health [get]
+func (h *LinkHandler) GetLinksHealthHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	links, err := h.LinkService.GetLinksHealth(username.(string), c.Query("broken") == "true")
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// ExportLinksHandler godoc
+// @Summary Export links
+// @Description Download the authenticated user's links in profile order, with section, slug, preview description and image, tags and creation time, as JSON or CSV; CSV lists tags comma separated in one cell. CSV cells starting with =, +, - or @ are prefixed with a single quote so spreadsheets treat them as text.
+// @Tags links
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "Export format" Enums(json, csv) default(json)
+// @Security BearerAuth
+// @Success 200 {array} services.LinkRecord "Exported links"
+// @Failure 400 "error: Unsupported format"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /links/export [get]
+func (h *LinkHandler) ExportLinksHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format"})
+		return
+	}
+
+	records, err := h.LinkService.ExportLinks(username.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=links."+format)
+
+	if format == "json" {
+		c.JSON(http.StatusOK, records)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := services.WriteLinkRecordsCSV(&body, records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", body.Bytes())
+}
+
+// ImportLinksHandler godoc
+// @Summary Import links
+// @Description Import links from a JSON array, a CSV file with a header row, or a browser bookmarks HTML file sent as the request body. With dry_run the per-row report is returned without saving anything. Preview images must be http or https URLs the URL policy accepts. Duplicates are skipped; any invalid row rejects the whole import. Imported links are added after the links their section already has, in position order, with rows of the same position kept in file order.
+// @Tags links
+// @Accept json
+// @Accept text/csv
+// @Accept text/html
+// @Produce json
+// @Param format query string true "Import format" Enums(json, csv, html)
+// @Param dry_run query bool false "Validate only"
+// @Security BearerAuth
+// @Success 200 {object} services.ImportReport "Import report"
+// @Failure 400 {object} services.ImportReport "Invalid rows"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/import [post]
+func (h *LinkHandler) ImportLinksHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var records []services.LinkRecord
+	var err error
+	switch c.Query("format") {
+	case "json":
+		records, err = services.ParseLinkRecordsJSON(body)
+	case "csv":
+		records, err = services.ParseLinkRecordsCSV(body)
+	case "html":
+		records, err = services.ParseBookmarksHTML(body)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of json, csv or html"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.LinkService.ImportLinks(username.(string), records, dryRun)
+	if errors.Is(err, services.ErrImportHasInvalidRows) {
+		c.JSON(http.StatusBadRequest, report)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
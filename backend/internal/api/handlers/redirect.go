@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"linktree-mohamedfadel-backend/internal/models"
+	"linktree-mohamedfadel-backend/internal/services"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Sensitive content</title>
+</head>
+<body>
+<main>
+<h1>Sensitive content</h1>
+<p>The link &ldquo;{{.Title}}&rdquo; may contain sensitive content.</p>
+<p><a href="{{.ContinueURL}}" rel="nofollow noreferrer">Continue</a></p>
+</main>
+</body>
+</html>
+`))
+
+var passwordTemplate = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Protected link</title>
+</head>
+<body>
+<main>
+<h1>Protected link</h1>
+<p>The link &ldquo;{{.Title}}&rdquo; is password protected.</p>
+{{if .Sensitive}}<p>It may contain sensitive content.</p>
+{{end}}{{if .Error}}<p role="alert">{{.Error}}</p>
+{{end}}<form method="post" action="{{.Action}}">
+<label>Password <input type="password" name="password" required autofocus></label>
+<button type="submit">Continue</button>
+</form>
+</main>
+</body>
+</html>
+`))
+
+var soldOutTemplate = template.Must(template.New("sold-out").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Sold out</title>
+</head>
+<body>
+<main>
+<h1>Sold out</h1>
+<p>The link &ldquo;{{.Title}}&rdquo; is no longer available.</p>
+</main>
+</body>
+</html>
+`))
+
+type RedirectHandler struct {
+	LinkService      *services.LinkService
+	AnalyticsService *services.AnalyticsService
+}
+
+func NewRedirectHandler(linkService *services.LinkService, analyticsService *services.AnalyticsService) *RedirectHandler {
+	return &RedirectHandler{LinkService: linkService, AnalyticsService: analyticsService}
+}
+
+// RedirectLinkHandler records a click on a link and redirects the visitor to
+// its URL, or to the target of the first of its rules matching the visitor.
+// Clicks on links under A/B test are attributed to the variant named by the
+// v query parameter, or else the visitor's assigned one. The owner's UTM
+// parameters are added to the destination on the way out. Links past their
+// click cap or expiry date get their expiry action instead, and links whose
+// destination the URL policy no longer accepts are refused.
+// Sensitive links first get an interstitial warning page unless the
+// request carries confirm=true, and password-protected links get a password
+// form that posts to UnlockLinkHandler. It is served from the site root
+// rather than under /api/v1 so tracked URLs stay short.
+func (h *RedirectHandler) RedirectLinkHandler(c *gin.Context) {
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	link, err := h.LinkService.GetLink(linkId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.redirect(c, link)
+}
+
+// RedirectSlugHandler is RedirectLinkHandler for links addressed by their
+// short slug, including slugs replaced within the grace period.
+func (h *RedirectHandler) RedirectSlugHandler(c *gin.Context) {
+	link, err := h.LinkService.GetLinkBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.redirect(c, link)
+}
+
+// UnlockLinkHandler checks the password posted from the password form and,
+// when it matches, records the unlock and redirects to the link. Wrong
+// guesses are rate limited per visitor and link, and per link from all visitors.
+func (h *RedirectHandler) UnlockLinkHandler(c *gin.Context) {
+	id := c.Param("id")
+	linkId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid link ID"})
+		return
+	}
+
+	link, err := h.LinkService.GetLink(linkId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.unlock(c, link)
+}
+
+// UnlockSlugHandler is UnlockLinkHandler for links addressed by their slug.
+func (h *RedirectHandler) UnlockSlugHandler(c *gin.Context) {
+	link, err := h.LinkService.GetLinkBySlug(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.unlock(c, link)
+}
+
+func (h *RedirectHandler) redirect(c *gin.Context, link models.Link) {
+	if link.Expired {
+		h.renderExpired(c, link)
+		return
+	}
+
+	if link.PasswordProtected {
+		renderPasswordForm(c, link, http.StatusOK, "")
+		return
+	}
+
+	if link.Sensitive {
+		if c.Query("confirm") != "true" {
+			renderInterstitial(c, link)
+			return
+		}
+		h.trackUnlock(c, link)
+	}
+
+	h.follow(c, link, http.StatusFound)
+}
+
+func (h *RedirectHandler) unlock(c *gin.Context, link models.Link) {
+	if !link.PasswordProtected {
+		h.redirect(c, link)
+		return
+	}
+
+	wait, err := h.LinkService.UnlockLink(link, c.PostForm("password"), c.ClientIP())
+	if errors.Is(err, services.ErrTooManyUnlocks) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		renderPasswordForm(c, link, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		renderPasswordForm(c, link, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	h.trackUnlock(c, link)
+	h.follow(c, link, http.StatusSeeOther)
+}
+
+// trackUnlock records that a visitor got past a link's protection. Like
+// clicks, a failure to record it must not keep the visitor from the link.
+func (h *RedirectHandler) trackUnlock(c *gin.Context, link models.Link) {
+	if err := h.AnalyticsService.TrackLinkUnlock(uint64(link.ID)); err != nil {
+		c.Error(err)
+	}
+}
+
+func (h *RedirectHandler) follow(c *gin.Context, link models.Link, status int) {
+	if !h.checkDestination(c, link.URL) {
+		return
+	}
+
+	details := h.AnalyticsService.ClassifyClick(clickDetails(c))
+
+	// Crawlers and link previews see whether the link still works without
+	// using up its click cap.
+	allowed := !link.ExpiredAt(time.Now())
+	if details.BotReason == "" {
+		var err error
+		allowed, err = h.LinkService.ClaimClick(link)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !allowed {
+		h.renderExpired(c, link)
+		return
+	}
+
+	// A failure to record the click must not keep the visitor from the link.
+	if err := h.AnalyticsService.TrackLinkClicks(uint64(link.ID), details); err != nil {
+		c.Error(err)
+	}
+
+	variant, err := h.LinkService.ResolveLinkVariant(link, c.Query("v"), visitorID(c))
+	if err != nil {
+		c.Error(err)
+	}
+	if variant != nil {
+		if details.BotReason == "" {
+			if err := h.AnalyticsService.TrackVariantClick(uint64(link.ID), variant.ID); err != nil {
+				c.Error(err)
+			}
+		}
+		if variant.URL != "" {
+			link.URL = variant.URL
+		}
+	}
+
+	result := h.LinkService.EvaluateLinkRules(link, services.RuleRequest{
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Referrer:       c.Request.Referer(),
+		Time:           time.Now(),
+	})
+
+	if !h.checkDestination(c, result.Target) {
+		return
+	}
+
+	target, err := h.LinkService.TagOutgoingURL(link, result.Target)
+	if err != nil {
+		c.Error(err)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(status, target)
+}
+
+// checkDestination refuses the visit when the URL policy has come to reject
+// rawURL since it was saved, e.g. because its domain was blocked.
+func (h *RedirectHandler) checkDestination(c *gin.Context, rawURL string) bool {
+	if err := h.LinkService.CheckRedirectURL(rawURL); err != nil {
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// renderExpired handles a visit to a link past its click cap or expiry date
+// according to the link's expiry action.
+func (h *RedirectHandler) renderExpired(c *gin.Context, link models.Link) {
+	c.Header("Cache-Control", "no-store")
+
+	switch link.ExpiryAction {
+	case models.ExpiryActionFallback:
+		if !h.checkDestination(c, link.FallbackURL) {
+			return
+		}
+		c.Redirect(http.StatusFound, link.FallbackURL)
+		return
+	case models.ExpiryActionHide:
+		c.JSON(http.StatusNotFound, gin.H{"error": "link not found"})
+		return
+	}
+
+	var page bytes.Buffer
+	if err := soldOutTemplate.Execute(&page, map[string]string{"Title": link.Title}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render page"})
+		return
+	}
+
+	c.Data(http.StatusGone, "text/html; charset=utf-8", page.Bytes())
+}
+
+func renderInterstitial(c *gin.Context, link models.Link) {
+	query := c.Request.URL.Query()
+	query.Set("confirm", "true")
+	continueURL := c.Request.URL.Path + "?" + query.Encode()
+
+	var page bytes.Buffer
+	err := interstitialTemplate.Execute(&page, map[string]string{
+		"Title":       link.Title,
+		"ContinueURL": continueURL,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render page"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+func renderPasswordForm(c *gin.Context, link models.Link, status int, message string) {
+	var page bytes.Buffer
+	err := passwordTemplate.Execute(&page, map[string]interface{}{
+		"Title":     link.Title,
+		"Sensitive": link.Sensitive,
+		"Error":     message,
+		"Action":    c.Request.URL.RequestURI(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to render page"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
+
+const visitorCookie = "visitor_id"
+
+// visitorID returns the anonymous ID that keeps a visitor on the same A/B
+// test variants, setting a new one when the request has none.
+func visitorID(c *gin.Context) string {
+	if id := c.GetString(visitorCookie); id != "" {
+		return id
+	}
+	if id, err := c.Cookie(visitorCookie); err == nil && id != "" {
+		return id
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return ""
+	}
+	id := hex.EncodeToString(buf)
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(visitorCookie, id, 365*24*60*60, "/", "", c.Request.TLS != nil, true)
+	c.Set(visitorCookie, id)
+	return id
+}
+
+// countryHeaders are set by CDNs in front of the API to the visitor's ISO
+// country code.
+var countryHeaders = []string{"CF-IPCountry", "CloudFront-Viewer-Country", "X-Country-Code"}
+
+// clickDetails describes the visitor behind a click for analytics. Its IP
+// is the client address gin trusts, so forwarding headers count only when
+// they come from one of the configured trusted proxies.
+func clickDetails(c *gin.Context) services.ClickDetails {
+	return services.ClickDetails{
+		Referrer:       c.Request.Referer(),
+		UserAgent:      c.Request.UserAgent(),
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Country:        countryHint(c),
+		VisitorID:      visitorID(c),
+		IP:             net.ParseIP(c.ClientIP()),
+	}
+}
+
+// countryHint returns the visitor's country as reported by a CDN header,
+// ignoring the codes used for unknown locations and Tor.
+func countryHint(c *gin.Context) string {
+	for _, header := range countryHeaders {
+		code := strings.ToUpper(strings.TrimSpace(c.GetHeader(header)))
+		if len(code) != 2 || code == "XX" || code == "T1" {
+			continue
+		}
+		if code[0] < 'A' || code[0] > 'Z' || code[1] < 'A' || code[1] > 'Z' {
+			continue
+		}
+		return code
+	}
+	return ""
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"linktree-mohamedfadel-backend/internal/models"
+	"linktree-mohamedfadel-backend/internal/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SectionHandler struct {
+	SectionService *services.SectionService
+}
+
+type CreateSectionRequest struct {
+	Title     string `json:"title" binding:"required" example:"Podcasts"`
+	Collapsed bool   `json:"collapsed" example:"false"`
+}
+
+type UpdateSectionRequest struct {
+	Title     string `json:"title" example:"Podcasts"`
+	Collapsed *bool  `json:"collapsed" example:"true"`
+}
+
+type ReorderRequest struct {
+	IDs []uint `json:"ids" binding:"required" example:"3,1,2"`
+}
+
+func NewSectionHandler(sectionService *services.SectionService) *SectionHandler {
+	return &SectionHandler{SectionService: sectionService}
+}
+
+// CreateSectionHandler godoc
+// @Summary Create a new section
+// @Description Create a section header to group links on the authenticated user's profile. New sections are appended at the end.
+// @Tags sections
+// @Accept json
+// @Produce json
+// @Param section body CreateSectionRequest true "Section details"
+// @Security BearerAuth
+// @Success 201 {object} models.Section "Created section"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /sections [post]
+func (h *SectionHandler) CreateSectionHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody CreateSectionRequest
+	if err := c.BindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	section, err := h.SectionService.CreateSection(username.(string), models.Section{
+		Title:     requestBody.Title,
+		Collapsed: requestBody.Collapsed,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, section)
+}
+
+// GetSectionsHandler godoc
+// @Summary List sections
+// @Description List the authenticated user's sections in display order with their links nested
+// @Tags sections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Section "Ordered sections"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /sections [get]
+func (h *SectionHandler) GetSectionsHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sections, err := h.SectionService.GetSections(username.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sections)
+}
+
+// UpdateSectionHandler godoc
+// @Summary Update a section
+// @Description Rename a section or change whether it is collapsed by default
+// @Tags sections
+// @Accept json
+// @Produce json
+// @Param id path int true "Section ID" example(1)
+// @Param section body UpdateSectionRequest true "Updated section details"
+// @Security BearerAuth
+// @Success 200 "message: Section updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Section not found"
+// @Router /sections/{id} [put]
+func (h *SectionHandler) UpdateSectionHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	sectionId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section ID"})
+		return
+	}
+
+	var requestBody UpdateSectionRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err = h.SectionService.UpdateSection(username.(string), sectionId, requestBody.Title, requestBody.Collapsed)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Section updated successfully"})
+}
+
+// ReorderSectionsHandler godoc
+// @Summary Reorder sections
+// @Description Set the display order of the authenticated user's sections. The list must contain every section ID exactly once.
+// @Tags sections
+// @Accept json
+// @Produce json
+// @Param order body ReorderRequest true "Section IDs in display order"
+// @Security BearerAuth
+// @Success 200 "message: Sections reordered successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /sections/order [put]
+func (h *SectionHandler) ReorderSectionsHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody ReorderRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.SectionService.ReorderSections(username.(string), requestBody.IDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sections reordered successfully"})
+}
+
+// ReorderSectionLinksHandler godoc
+// @Summary Reorder links in a section
+// @Description Set the display order of the links inside a section. The list must contain every link ID in the section exactly once.
+// @Tags sections
+// @Accept json
+// @Produce json
+// @Param id path int true "Section ID" example(1)
+// @Param order body ReorderRequest true "Link IDs in display order"
+// @Security BearerAuth
+// @Success 200 "message: Links reordered successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /sections/{id}/links/order [put]
+func (h *SectionHandler) ReorderSectionLinksHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	sectionId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section ID"})
+		return
+	}
+
+	var requestBody ReorderRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.SectionService.ReorderSectionLinks(username.(string), sectionId, requestBody.IDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Links reordered successfully"})
+}
+
+// ReorderUngroupedLinksHandler godoc
+// @Summary Reorder ungrouped links
+// @Description Set the display order of the links that are not in a section. The list must contain every such link ID exactly once.
+// @Tags links
+// @Accept json
+// @Produce json
+// @Param order body ReorderRequest true "Link IDs in display order"
+// @Security BearerAuth
+// @Success 200 "message: Links reordered successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /links/order [put]
+func (h *SectionHandler) ReorderUngroupedLinksHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody ReorderRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.SectionService.ReorderUngroupedLinks(username.(string), requestBody.IDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Links reordered successfully"})
+}
+
+// DeleteSectionHandler godoc
+// @Summary Delete a section
+// @Description Delete a section. The links query parameter decides whether its links are moved out to the ungrouped list or deleted with it.
+// @Tags sections
+// @Accept json
+// @Produce json
+// @Param id path int true "Section ID" example(1)
+// @Param links query string true "What to do with the section's links" Enums(move, delete)
+// @Security BearerAuth
+// @Success 200 "message: Section deleted successfully"
+// @Failure 400 "error: Invalid section ID"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: Section not found"
+// @Router /sections/{id} [delete]
+func (h *SectionHandler) DeleteSectionHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	id := c.Param("id")
+	sectionId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid section ID"})
+		return
+	}
+
+	mode := c.Query("links")
+	if mode != services.SectionDeleteMoveLinks && mode != services.SectionDeleteLinks {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "links query parameter must be 'move' or 'delete'"})
+		return
+	}
+
+	if err := h.SectionService.DeleteSection(username.(string), sectionId, mode); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Section deleted successfully"})
+}
@@ -9,7 +9,8 @@ import (
 )
 
 type UserHandler struct {
-	UserService *services.UserService
+	UserService      *services.UserService
+	AnalyticsService *services.AnalyticsService
 }
 
 type SignUpRequest struct {
@@ -24,8 +25,16 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required" example:"securepassword123"`
 }
 
-func NewUserHandler(userService *services.UserService) *UserHandler {
-	return &UserHandler{UserService: userService}
+type AnalyticsPrivacyRequest struct {
+	PublicClickCounts bool `json:"public_click_counts" example:"true"`
+}
+
+type BotTrafficRequest struct {
+	ShowBotTraffic bool `json:"show_bot_traffic" example:"true"`
+}
+
+func NewUserHandler(userService *services.UserService, analyticsService *services.AnalyticsService) *UserHandler {
+	return &UserHandler{UserService: userService, AnalyticsService: analyticsService}
 }
 
 // SignUpHandler godoc
@@ -90,23 +99,45 @@ func (h *UserHandler) LoginHandler(c *gin.Context) {
 
 // GetUserProfileInfoHandler godoc
 // @Summary Get user profile
-// @Description Retrieve user profile information and their associated links
+// @Description Retrieve user profile information and their associated links. Links under A/B test are shown as the variant the visitor is assigned to, kept stable by a visitor_id cookie, with variant_id set and the tracked URL attributing clicks to that variant. Link analytics are only included in full for the signed-in owner; other visitors see click counts if the owner made them public and nothing otherwise. The visit counts as a profile view unless it comes from the profile's owner or a bot; bots see links under A/B test as saved and are not counted as variant impressions.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param username path string true "Username" example:"johndoe"
+// @Security BearerAuth
 // @Success 200 {object} models.User "User profile with associated links"
 // @Failure 404 "error: User not found"
 // @Router /users/{username} [get]
 func (h *UserHandler) GetUserProfileInfoHandler(c *gin.Context) {
 	username := c.Param("username")
 
-	user, err := h.UserService.GetUserProfileInfo(username)
+	var viewer string
+	if viewerInterface, exists := c.Get("username"); exists {
+		if viewerStr, ok := viewerInterface.(string); ok {
+			viewer = viewerStr
+		}
+	}
+
+	details := h.AnalyticsService.ClassifyClick(clickDetails(c))
+
+	// Bots see the links as saved, so they never count as impressions of an
+	// A/B test variant.
+	visitorId := details.VisitorID
+	if details.BotReason != "" {
+		visitorId = ""
+	}
+
+	user, err := h.UserService.GetUserProfileForVisitor(username, visitorId, viewer)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	// A failure to record the view must not keep the visitor from the profile.
+	if err := h.AnalyticsService.TrackProfileView(username, viewer, details); err != nil {
+		c.Error(err)
+	}
+
 	c.JSON(http.StatusOK, user)
 }
 
@@ -145,9 +176,111 @@ func (h *UserHandler) UpdateUserHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
 
+// SetProfileUTMHandler godoc
+// @Summary Set profile UTM template
+// @Description Set the UTM parameters added to all of the authenticated user's outgoing links when visitors are redirected. Values may use {username}, {link_id} and {slug}. Parameters a destination already has are kept. An empty template turns tagging off.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param utm body models.UTMTemplate true "UTM template"
+// @Security BearerAuth
+// @Success 200 "message: UTM template updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Router /users/utm [put]
+func (h *UserHandler) SetProfileUTMHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var template models.UTMTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.UserService.SetProfileUTM(username.(string), template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "UTM template updated successfully"})
+}
+
+// SetAnalyticsPrivacyHandler godoc
+// @Summary Set analytics privacy
+// @Description Choose whether the authenticated user's public profile shows the click counts of their links.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param privacy body AnalyticsPrivacyRequest true "Privacy settings"
+// @Security BearerAuth
+// @Success 200 "message: Privacy settings updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /users/privacy [put]
+func (h *UserHandler) SetAnalyticsPrivacyHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody AnalyticsPrivacyRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	err := h.UserService.SetAnalyticsPrivacy(username.(string), requestBody.PublicClickCounts)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Privacy settings updated successfully"})
+}
+
+// SetBotTrafficHandler godoc
+// @Summary Show or hide bot traffic
+// @Description Choose whether the authenticated user's click charts, breakdowns and click-through rates include clicks taken for crawlers, link previews and other automated traffic. Bot clicks are always stored and counted apart from people's clicks; this only changes what the reports show.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param settings body BotTrafficRequest true "Bot traffic setting"
+// @Security BearerAuth
+// @Success 200 "message: Bot traffic setting updated successfully"
+// @Failure 400 "error: Invalid input"
+// @Failure 401 "error: Unauthorized"
+// @Failure 404 "error: User not found"
+// @Router /users/bot-traffic [put]
+func (h *UserHandler) SetBotTrafficHandler(c *gin.Context) {
+	username, exists := c.Get("username")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var requestBody BotTrafficRequest
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.UserService.SetShowBotTraffic(username.(string), requestBody.ShowBotTraffic); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bot traffic setting updated successfully"})
+}
+
 // DeleteUserHandler godoc
 // @Summary Delete user account
-// @Description Permanently delete the authenticated user's account and all associated data
+// @Description Permanently delete the authenticated user's account and all associated data: links and their analytics, click history, tags, revisions, slug aliases and A/B variants, sections, blocks and profile views
 // @Tags users
 // @Accept json
 // @Produce json
@@ -51,3 +51,37 @@ func OptionalJWTFromContext() gin.HandlerFunc {
 		ctx.Next()
 	}
 }
+
+// RequireAdminFromContext only lets through users isAdmin accepts. It must
+// run after ValidateJWTFromContext.
+func RequireAdminFromContext(isAdmin func(username string) (bool, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		username, exists := ctx.Get("username")
+		if !exists {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			ctx.Abort()
+			return
+		}
+
+		usernameStr, ok := username.(string)
+		if !ok {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			ctx.Abort()
+			return
+		}
+
+		admin, err := isAdmin(usernameStr)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			ctx.Abort()
+			return
+		}
+		if !admin {
+			ctx.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			ctx.Abort()
+			return
+		}
+
+		ctx.Next()
+	}
+}
@@ -14,17 +14,28 @@ type Router struct {
 	userHandler      *handlers.UserHandler
 	linkHandler      *handlers.LinkHandler
 	analyticsHandler *handlers.AnalyticsHandler
+	sectionHandler   *handlers.SectionHandler
+	blockHandler     *handlers.BlockHandler
+	redirectHandler  *handlers.RedirectHandler
+	adminHandler     *handlers.AdminHandler
 }
 
 func NewRouter(
 	userService *services.UserService,
 	linkService *services.LinkService,
 	analyticsService *services.AnalyticsService,
+	sectionService *services.SectionService,
+	blockService *services.BlockService,
+	urlPolicy *services.URLPolicy,
 ) *Router {
 	return &Router{
-		userHandler:      handlers.NewUserHandler(userService),
+		userHandler:      handlers.NewUserHandler(userService, analyticsService),
 		linkHandler:      handlers.NewLinkHandler(linkService),
 		analyticsHandler: handlers.NewAnalyticsHandler(analyticsService),
+		sectionHandler:   handlers.NewSectionHandler(sectionService),
+		blockHandler:     handlers.NewBlockHandler(blockService, analyticsService),
+		redirectHandler:  handlers.NewRedirectHandler(linkService, analyticsService),
+		adminHandler:     handlers.NewAdminHandler(urlPolicy),
 	}
 }
 
@@ -34,13 +45,23 @@ func (r *Router) SetupRoutes(router *gin.Engine) {
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	redirects := router.Group("")
+	redirects.Use(middleware.OptionalJWTFromContext())
+	{
+		redirects.GET("/l/:id", r.redirectHandler.RedirectLinkHandler)
+		redirects.GET("/r/:slug", r.redirectHandler.RedirectSlugHandler)
+		redirects.POST("/l/:id", r.redirectHandler.UnlockLinkHandler)
+		redirects.POST("/r/:slug", r.redirectHandler.UnlockSlugHandler)
+	}
+
 	public := router.Group("/api/v1")
 	{
 		users := public.Group("/users")
 		{
 			users.POST("/signup", r.userHandler.SignUpHandler)
 			users.POST("/login", r.userHandler.LoginHandler)
-			users.GET("/:username", r.userHandler.GetUserProfileInfoHandler)
+			users.GET("/:username", middleware.OptionalJWTFromContext(), r.userHandler.GetUserProfileInfoHandler)
+			users.GET("/:username/blocks", r.blockHandler.GetBlocksHandler)
 		}
 	}
 
@@ -51,14 +72,72 @@ func (r *Router) SetupRoutes(router *gin.Engine) {
 		{
 			users.PUT("", r.userHandler.UpdateUserHandler)
 			users.DELETE("", r.userHandler.DeleteUserHandler)
+			users.PUT("/utm", r.userHandler.SetProfileUTMHandler)
+			users.PUT("/privacy", r.userHandler.SetAnalyticsPrivacyHandler)
+			users.PUT("/bot-traffic", r.userHandler.SetBotTrafficHandler)
 		}
 
 		links := protected.Group("/links")
 		{
+			links.GET("", r.linkHandler.ListLinksHandler)
 			links.POST("", r.linkHandler.CreateLinkHandler)
+			links.GET("/search", r.linkHandler.SearchLinksHandler)
+			links.GET("/health", r.linkHandler.GetLinksHealthHandler)
+			links.GET("/export", r.linkHandler.ExportLinksHandler)
+			links.POST("/import", r.linkHandler.ImportLinksHandler)
+			links.PUT("/order", r.sectionHandler.ReorderUngroupedLinksHandler)
 			links.PUT("/:id", r.linkHandler.UpdateLinkHandler)
+			links.POST("/:id/metadata", r.linkHandler.RefreshLinkMetadataHandler)
+			links.PUT("/:id/protection", r.linkHandler.SetLinkProtectionHandler)
+			links.PUT("/:id/rules", r.linkHandler.SetLinkRulesHandler)
+			links.POST("/:id/rules/test", r.linkHandler.TestLinkRulesHandler)
+			links.PUT("/:id/utm", r.linkHandler.SetLinkUTMHandler)
+			links.PUT("/:id/limits", r.linkHandler.SetLinkLimitsHandler)
+			links.PUT("/:id/tags", r.linkHandler.SetLinkTagsHandler)
+			links.GET("/:id/revisions", r.linkHandler.GetLinkRevisionsHandler)
+			links.POST("/:id/revisions/:revisionId/rollback", r.linkHandler.RollbackLinkHandler)
+			links.GET("/:id/variants", r.analyticsHandler.GetVariantReportHandler)
+			links.POST("/:id/variants", r.linkHandler.AddLinkVariantHandler)
+			links.DELETE("/:id/variants/:variantId", r.linkHandler.DeleteLinkVariantHandler)
+			links.POST("/:id/variants/:variantId/promote", r.linkHandler.PromoteLinkVariantHandler)
 			links.DELETE("/:id", r.linkHandler.DeleteLinkHandler)
 		}
+
+		sections := protected.Group("/sections")
+		{
+			sections.GET("", r.sectionHandler.GetSectionsHandler)
+			sections.POST("", r.sectionHandler.CreateSectionHandler)
+			sections.PUT("/order", r.sectionHandler.ReorderSectionsHandler)
+			sections.PUT("/:id", r.sectionHandler.UpdateSectionHandler)
+			sections.PUT("/:id/links/order", r.sectionHandler.ReorderSectionLinksHandler)
+			sections.DELETE("/:id", r.sectionHandler.DeleteSectionHandler)
+		}
+
+		blocks := protected.Group("/blocks")
+		{
+			blocks.POST("", r.blockHandler.CreateBlockHandler)
+			blocks.PUT("/order", r.blockHandler.ReorderBlocksHandler)
+			blocks.PUT("/:id", r.blockHandler.UpdateBlockHandler)
+			blocks.DELETE("/:id", r.blockHandler.DeleteBlockHandler)
+		}
+
+		analytics := protected.Group("/analytics")
+		{
+			analytics.GET("/links/:id", r.analyticsHandler.GetLinkClickSeriesHandler)
+			analytics.GET("/profile", r.analyticsHandler.GetProfileClickSeriesHandler)
+			analytics.GET("/links/:id/breakdown", r.analyticsHandler.GetLinkClickBreakdownHandler)
+			analytics.GET("/profile/breakdown", r.analyticsHandler.GetProfileClickBreakdownHandler)
+			analytics.GET("/ctr", r.analyticsHandler.GetCTRReportHandler)
+		}
+
+		admin := protected.Group("/admin")
+		admin.Use(middleware.RequireAdminFromContext(r.userHandler.UserService.IsAdmin))
+		{
+			admin.GET("/blocklist", r.adminHandler.GetBlocklistHandler)
+			admin.POST("/blocklist", r.adminHandler.BlockDomainsHandler)
+			admin.DELETE("/blocklist/:domain", r.adminHandler.UnblockDomainHandler)
+			admin.GET("/analytics/ingestion", r.analyticsHandler.GetIngestionStatsHandler)
+		}
 	}
 
 	optionalAuth := router.Group("/api/v1")
@@ -3,6 +3,7 @@ package database
 import (
 	"fmt"
 	"linktree-mohamedfadel-backend/internal/models"
+	"linktree-mohamedfadel-backend/internal/services"
 	"log"
 	"os"
 
@@ -31,10 +32,173 @@ func ConnectDatabase() error {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
 
-	err = DB.AutoMigrate(&models.User{}, &models.Link{}, &models.Analytics{})
+	if err := MergeDuplicateAnalytics(DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	err = DB.AutoMigrate(&models.User{}, &models.Section{}, &models.Link{}, &models.Analytics{}, &models.Block{}, &models.SlugAlias{}, &models.LinkHealth{}, &models.LinkVariant{}, &models.LinkTag{}, &models.LinkRevision{}, &models.ClickEvent{}, &models.ClickRollup{}, &models.RollupCursor{}, &models.ProfileView{}, &models.VisitorSalt{})
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %v", err)
 	}
 
+	if err := MigrateLinkBlocks(DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	if err := MigrateLinkSlugs(DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	if err := MigrateClickEvents(DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	if err := DropVisitorIdentifiers(DB); err != nil {
+		return fmt.Errorf("failed to run migrations: %v", err)
+	}
+
+	return nil
+}
+
+// MigrateLinkBlocks creates a link block for every link that does not have
+// one yet, appending them to the end of each owner's profile in link order.
+func MigrateLinkBlocks(db *gorm.DB) error {
+	var links []models.Link
+	err := db.Where("id NOT IN (?)", db.Model(&models.Block{}).Where("link_id IS NOT NULL").Select("link_id")).
+		Order("user_id, position, id").Find(&links).Error
+	if err != nil {
+		return fmt.Errorf("failed to load links without blocks: %v", err)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, link := range links {
+			if err := services.CreateLinkBlock(tx, link); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// MigrateLinkSlugs gives a generated slug to every link created before slugs
+// existed.
+func MigrateLinkSlugs(db *gorm.DB) error {
+	var links []models.Link
+	if err := db.Where("slug IS NULL OR slug = ''").Find(&links).Error; err != nil {
+		return fmt.Errorf("failed to load links without slugs: %v", err)
+	}
+
+	for _, link := range links {
+		slug, err := services.GenerateSlug(db)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&models.Link{}).Where("id = ?", link.ID).Update("slug", slug).Error; err != nil {
+			return fmt.Errorf("failed to assign slug: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// MigrateClickEvents marks the clicks counted before clicks were stored as
+// events as historical, so click totals stay the same while per-event
+// queries only see the clicks that have events.
+func MigrateClickEvents(db *gorm.DB) error {
+	err := db.Model(&models.Analytics{}).
+		Where("historical_click_count = 0 AND click_count > 0").
+		Where("link_id NOT IN (?)", db.Model(&models.ClickEvent{}).Select("link_id")).
+		UpdateColumn("historical_click_count", gorm.Expr("click_count")).Error
+	if err != nil {
+		return fmt.Errorf("failed to keep historical click counts: %v", err)
+	}
+
+	return nil
+}
+
+// visitorIdentifiers are the columns that used to tie clicks and views to a
+// visitor for longer than a day.
+var visitorIdentifiers = []struct {
+	model  interface{}
+	column string
+}{
+	{&models.Analytics{}, "visitors_usernames"},
+	{&models.ClickEvent{}, "user_id"},
+	{&models.ClickEvent{}, "visitor_id"},
+	{&models.ClickEvent{}, "ip_hash"},
+	{&models.ProfileView{}, "visitor_id"},
+	{&models.User{}, "anonymous_visits"},
+}
+
+// DropVisitorIdentifiers removes the visitor names and lasting visitor
+// hashes stored before visitors were only told apart by the day's salted
+// hash, along with the setting that kept names out of them.
+func DropVisitorIdentifiers(db *gorm.DB) error {
+	for _, identifier := range visitorIdentifiers {
+		if !db.Migrator().HasColumn(identifier.model, identifier.column) {
+			continue
+		}
+		if err := db.Migrator().DropColumn(identifier.model, identifier.column); err != nil {
+			return fmt.Errorf("failed to drop %s: %v", identifier.column, err)
+		}
+	}
+	return nil
+}
+
+// MergeDuplicateAnalytics folds the analytics rows of links that ended up
+// with more than one into the oldest, so a unique index on link_id can be
+// created. Counts are added up.
+func MergeDuplicateAnalytics(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.Analytics{}) {
+		return nil
+	}
+
+	var linkIds []uint
+	err := db.Model(&models.Analytics{}).Group("link_id").Having("COUNT(*) > 1").Pluck("link_id", &linkIds).Error
+	if err != nil {
+		return fmt.Errorf("failed to find duplicate analytics: %v", err)
+	}
+
+	for _, linkId := range linkIds {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var rows []models.Analytics
+			if err := tx.Where("link_id = ?", linkId).Order("id").Find(&rows).Error; err != nil {
+				return fmt.Errorf("failed to load analytics: %v", err)
+			}
+
+			merged := rows[0]
+			for _, row := range rows[1:] {
+				merged.ClickCount += row.ClickCount
+				merged.BotClickCount += row.BotClickCount
+				merged.HistoricalClickCount += row.HistoricalClickCount
+				merged.UnlockCount += row.UnlockCount
+			}
+
+			// This runs before AutoMigrate, so counters added since the
+			// table was created may not exist yet.
+			columns := map[string]interface{}{"click_count": merged.ClickCount}
+			counters := map[string]uint{
+				"bot_click_count":        merged.BotClickCount,
+				"historical_click_count": merged.HistoricalClickCount,
+				"unlock_count":           merged.UnlockCount,
+			}
+			for column, count := range counters {
+				if tx.Migrator().HasColumn(&models.Analytics{}, column) {
+					columns[column] = count
+				}
+			}
+			if err := tx.Model(&models.Analytics{}).Where("id = ?", merged.ID).UpdateColumns(columns).Error; err != nil {
+				return fmt.Errorf("failed to save analytics: %v", err)
+			}
+			if err := tx.Where("link_id = ? AND id <> ?", linkId, merged.ID).Delete(&models.Analytics{}).Error; err != nil {
+				return fmt.Errorf("failed to delete duplicate analytics: %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -1,25 +1,29 @@
 package models
 
-import (
-	"time"
-
-	"gorm.io/datatypes"
-)
+import "time"
 
 // @Description Analytics data for tracking link usage
 type Analytics struct {
 	// ID is the unique identifier
 	ID uint `gorm:"primarykey" json:"id" example:"1"`
 
-	// ClickCount tracks number of clicks
+	// ClickCount tracks number of clicks by people
 	ClickCount uint `json:"click_count" example:"42"`
 
-	// VisitorsUsernames stores usernames of visitors
-	// swagger:strfmt json
-	VisitorsUsernames datatypes.JSON `json:"visitors_usernames" swaggertype:"string" example:"[\"user1\", \"user2\"]"`
+	// BotClickCount tracks clicks taken for crawlers, link previews and
+	// other automated traffic
+	BotClickCount uint `json:"bot_click_count" example:"5"`
+
+	// HistoricalClickCount is the part of ClickCount recorded before clicks
+	// were stored as events
+	HistoricalClickCount uint `json:"-"`
+
+	// UnlockCount tracks visitors who entered a link's password or
+	// acknowledged its sensitive content warning
+	UnlockCount uint `json:"unlock_count" example:"7"`
 
 	// LinkID is the foreign key to the associated link
-	LinkID uint `json:"link_id" example:"1"`
+	LinkID uint `json:"link_id" gorm:"uniqueIndex" example:"1"`
 
 	// CreatedAt timestamp
 	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
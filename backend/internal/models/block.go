@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	BlockTypeLink        = "link"
+	BlockTypeText        = "text"
+	BlockTypeHeader      = "header"
+	BlockTypeDivider     = "divider"
+	BlockTypeImage       = "image"
+	BlockTypeVideo       = "video"
+	BlockTypeEmailSignup = "email_signup"
+	BlockTypeContactForm = "contact_form"
+)
+
+// @Description A typed content block on a profile, rendered in position order
+type Block struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// Type selects how the block is rendered and which payload it carries
+	Type string `json:"type" example:"text" enums:"link,text,header,divider,image,video,email_signup,contact_form"`
+
+	// Position of the block on the profile, lowest first
+	Position int `json:"position" example:"0"`
+
+	// Payload holds the type-specific settings
+	// swagger:strfmt json
+	Payload datatypes.JSON `json:"payload" swaggertype:"object"`
+
+	// UserID is the foreign key to the owner
+	UserID uint `json:"user_id" example:"1"`
+
+	// LinkID points at the link rendered by a link block
+	LinkID *uint `json:"link_id,omitempty" example:"1"`
+
+	// Link rendered by a link block
+	Link *Link `json:"link,omitempty" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	// CreatedAt timestamp
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+
+	// UpdatedAt timestamp
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
+
+// TextBlockPayload is the payload of a text paragraph block
+type TextBlockPayload struct {
+	Text string `json:"text" example:"Welcome to my page!"`
+}
+
+// HeaderBlockPayload is the payload of a header block
+type HeaderBlockPayload struct {
+	Text string `json:"text" example:"My projects"`
+}
+
+// DividerBlockPayload is the payload of a divider block
+type DividerBlockPayload struct{}
+
+// ImageBlockPayload is the payload of an image block
+type ImageBlockPayload struct {
+	URL     string `json:"url" example:"https://example.com/photo.jpg"`
+	Alt     string `json:"alt,omitempty" example:"Me on stage"`
+	LinkURL string `json:"link_url,omitempty" example:"https://example.com"`
+}
+
+// VideoBlockPayload is the payload of a video embed block
+type VideoBlockPayload struct {
+	URL   string `json:"url" example:"https://www.youtube.com/watch?v=dQw4w9WgXcQ"`
+	Title string `json:"title,omitempty" example:"Latest talk"`
+}
+
+// EmailSignupBlockPayload is the payload of an email signup form block
+type EmailSignupBlockPayload struct {
+	Title       string `json:"title" example:"Join my newsletter"`
+	ButtonLabel string `json:"button_label,omitempty" example:"Subscribe"`
+	Placeholder string `json:"placeholder,omitempty" example:"you@example.com"`
+}
+
+// ContactFormBlockPayload is the payload of a contact form block
+type ContactFormBlockPayload struct {
+	Title  string   `json:"title" example:"Get in touch"`
+	Fields []string `json:"fields" example:"name,email,message"`
+}
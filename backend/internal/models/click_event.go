@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// @Description A single click on a link
+type ClickEvent struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// LinkID is the foreign key to the clicked link
+	LinkID uint `json:"link_id" gorm:"index:idx_click_link_time" example:"1"`
+
+	// OccurredAt is when the click happened
+	OccurredAt time.Time `json:"occurred_at" gorm:"index:idx_click_link_time;index" example:"2024-01-01T12:00:00Z"`
+
+	// Referrer is the page the visitor came from
+	Referrer string `json:"referrer" gorm:"size:512" example:"https://www.instagram.com/"`
+
+	// UserAgent is the visitor's browser
+	UserAgent string `json:"user_agent" gorm:"size:512" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+
+	// Country is the visitor's ISO country code, from the CDN or the
+	// geolocation database
+	Country string `json:"country" gorm:"size:2" example:"DE"`
+
+	// Region is the ISO 3166-2 code of the visitor's region, if known
+	Region string `json:"region" gorm:"size:8" example:"DE-BE"`
+
+	// City is the visitor's city, if known
+	City string `json:"city" gorm:"size:128" example:"Berlin"`
+
+	// VisitorHash is a hash of the visitor's IP address and user agent keyed
+	// with the day's salt, telling visitors apart within the day only
+	VisitorHash string `json:"-" gorm:"size:32"`
+
+	// Source is the domain the visitor came from, or "direct"
+	Source string `json:"source" gorm:"size:255" example:"instagram.com"`
+
+	// Device is the class of device clicked with: desktop, mobile, tablet,
+	// tv, bot or unknown
+	Device string `json:"device" gorm:"size:16" example:"mobile"`
+
+	// Browser is the visitor's browser or in-app browser
+	Browser string `json:"browser" gorm:"size:32" example:"Instagram"`
+
+	// OS is the visitor's operating system
+	OS string `json:"os" gorm:"column:os;size:32" example:"iOS"`
+
+	// RulesVersion is the version of the rules Source, Device, Browser and
+	// OS were derived with; 0 before the click is classified
+	RulesVersion int `json:"-" gorm:"not null;default:0"`
+
+	// Bot is true for clicks taken for crawlers, link previews and other
+	// automated traffic
+	Bot bool `json:"bot" gorm:"not null;default:false;index" example:"false"`
+
+	// BotReason is why the click was taken for automated traffic:
+	// user_agent, headers or rate
+	BotReason string `json:"bot_reason,omitempty" gorm:"size:16" example:"user_agent"`
+}
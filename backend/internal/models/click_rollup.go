@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// @Description Clicks on a link during one UTC hour
+type ClickRollup struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// LinkID is the foreign key to the clicked link
+	LinkID uint `json:"link_id" gorm:"uniqueIndex:idx_click_rollup" example:"1"`
+
+	// Hour is the start of the UTC hour
+	Hour time.Time `json:"hour" gorm:"uniqueIndex:idx_click_rollup;index" example:"2024-01-01T12:00:00Z"`
+
+	// Clicks is the number of clicks by people during the hour
+	Clicks uint `json:"clicks" example:"42"`
+
+	// BotClicks is the number of automated clicks during the hour
+	BotClicks uint `json:"bot_clicks" example:"3"`
+}
+
+// RollupCursor records up to when a rollup job has aggregated events.
+type RollupCursor struct {
+	// Name identifies the rollup
+	Name string `gorm:"primarykey;size:64"`
+
+	// RolledUntil is the end of the last aggregated hour
+	RolledUntil time.Time
+}
@@ -1,6 +1,17 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ExpiryActionHide     = "hide"
+	ExpiryActionSoldOut  = "sold_out"
+	ExpiryActionFallback = "fallback"
+)
 
 // @Description A link entry with associated analytics
 type Link struct {
@@ -13,11 +24,78 @@ type Link struct {
 	// URL of the link
 	URL string `json:"url" gorm:"unique" example:"https://github.com/username"`
 
+	// Description is the page summary shown in the link preview
+	Description string `json:"description" example:"Open source projects by John Doe"`
+
+	// ImageURL is the preview image of the linked page
+	ImageURL string `json:"image_url" example:"https://github.com/johndoe.png"`
+
+	// FaviconURL is the icon of the linked site
+	FaviconURL string `json:"favicon_url" example:"https://github.com/favicon.ico"`
+
+	// Slug is the short name resolving to this link under /r/
+	Slug string `json:"slug" gorm:"size:64;uniqueIndex" example:"johndoe-podcast"`
+
 	// UserID is the foreign key to the owner
 	UserID uint `json:"user_id" example:"1"`
 
-	// Analytics data for this link
-	Analytics Analytics `json:"analytics" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// SectionID is the optional section this link is grouped under
+	SectionID *uint `json:"section_id" example:"1"`
+
+	// Position of the link within its section, lowest first
+	Position int `json:"position" example:"0"`
+
+	// Sensitive links show a content warning before redirecting
+	Sensitive bool `json:"sensitive" example:"false"`
+
+	// Tags help the owner find the link, only loaded for the owner
+	Tags []string `json:"tags,omitempty" gorm:"-" example:"podcast,audio"`
+
+	// Rules send matching visitors elsewhere; the first matching rule wins and
+	// URL is the fallback when none match
+	Rules []LinkRule `json:"rules,omitempty" gorm:"serializer:json"`
+
+	// UTM overrides fields of the owner's UTM template for this link
+	UTM *UTMTemplate `json:"utm,omitempty" gorm:"serializer:json"`
+
+	// Variants are alternative titles and URLs under test, only loaded for the owner
+	Variants []LinkVariant `json:"variants,omitempty" gorm:"foreignKey:LinkID"`
+
+	// VariantID is the variant this visitor was assigned on the profile
+	VariantID *uint `json:"variant_id,omitempty" gorm:"-" example:"1"`
+
+	// MaxClicks is how many visitors the link lets through before it expires
+	MaxClicks *uint `json:"max_clicks,omitempty" example:"100"`
+
+	// ClicksUsed counts the visitors let through towards MaxClicks
+	ClicksUsed uint `json:"clicks_used" example:"42"`
+
+	// ExpiresAt is when the link stops letting visitors through
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2024-12-31T23:59:59Z"`
+
+	// ExpiryAction is what visitors get once the link expired
+	ExpiryAction string `json:"expiry_action,omitempty" example:"sold_out" enums:"hide,sold_out,fallback"`
+
+	// FallbackURL is where the fallback expiry action sends visitors
+	FallbackURL string `json:"fallback_url,omitempty" example:"https://shop.example.com/waitlist"`
+
+	// Expired is true once the click cap is reached or the expiry date passed
+	Expired bool `json:"expired" gorm:"-" example:"false"`
+
+	// PasswordHash gates the link behind a password (not exposed in JSON)
+	PasswordHash string `json:"-"`
+
+	// PasswordProtected is true when visitors need a password to follow the link
+	PasswordProtected bool `json:"password_protected" gorm:"-" example:"false"`
+
+	// TrackedURL is the click-tracking redirect path for this link
+	TrackedURL string `json:"tracked_url" gorm:"-" example:"/r/johndoe-podcast"`
+
+	// Health is the latest reachability check, only loaded for the owner
+	Health *LinkHealth `json:"health,omitempty" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	// Analytics data for this link, shown in full to the owner only
+	Analytics *Analytics `json:"analytics,omitempty" gorm:"foreignKey:LinkID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 
 	// CreatedAt timestamp
 	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
@@ -25,3 +103,70 @@ type Link struct {
 	// UpdatedAt timestamp
 	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
 }
+
+// AfterFind fills in the click-tracking redirect path, preferring the slug,
+// and whether the link is password protected or expired
+func (l *Link) AfterFind(tx *gorm.DB) error {
+	if l.Slug != "" {
+		l.TrackedURL = "/r/" + l.Slug
+	} else {
+		l.TrackedURL = fmt.Sprintf("/l/%d", l.ID)
+	}
+	l.PasswordProtected = l.PasswordHash != ""
+	l.Expired = l.ExpiredAt(time.Now())
+	return nil
+}
+
+// ExpiredAt reports whether the link's click cap is used up or its expiry
+// date has passed at the given time
+func (l *Link) ExpiredAt(now time.Time) bool {
+	if l.MaxClicks != nil && l.ClicksUsed >= *l.MaxClicks {
+		return true
+	}
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// Hidden reports whether the link expired and should be left off public views
+func (l *Link) Hidden() bool {
+	return l.Expired && l.ExpiryAction == ExpiryActionHide
+}
+
+// PublicAnalytics trims the link's analytics for a public view: the click and
+// unlock counts if the owner shows them, nothing otherwise.
+func (l *Link) PublicAnalytics(showCounts bool) {
+	if l.Analytics == nil || !showCounts {
+		l.Analytics = nil
+		return
+	}
+
+	l.Analytics = &Analytics{
+		ID:          l.Analytics.ID,
+		LinkID:      l.Analytics.LinkID,
+		ClickCount:  l.Analytics.ClickCount,
+		UnlockCount: l.Analytics.UnlockCount,
+		CreatedAt:   l.Analytics.CreatedAt,
+		UpdatedAt:   l.Analytics.UpdatedAt,
+	}
+}
+
+// HideProtectedURL blanks the target and preview of password-protected and
+// sensitive links so public views only expose the tracked redirect path.
+// Expired links point at their fallback or lose their target too. Targeting
+// rules are owner settings and are dropped from every link.
+func (l *Link) HideProtectedURL() {
+	l.Rules = nil
+	if l.Expired {
+		if l.ExpiryAction == ExpiryActionFallback {
+			l.URL = l.FallbackURL
+		} else {
+			l.URL = ""
+		}
+	}
+	if l.PasswordHash == "" && !l.Sensitive {
+		return
+	}
+	l.URL = ""
+	l.Description = ""
+	l.ImageURL = ""
+	l.FaviconURL = ""
+}
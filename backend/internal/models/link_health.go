@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// @Description Result of the latest reachability check of a link
+type LinkHealth struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// LinkID is the foreign key to the checked link
+	LinkID uint `json:"link_id" gorm:"uniqueIndex" example:"1"`
+
+	// Healthy is true when the last check ended in a 2xx or 3xx response
+	Healthy bool `json:"healthy" example:"true"`
+
+	// LastStatus is the HTTP status of the last check, 0 if no response
+	LastStatus int `json:"last_status" example:"200"`
+
+	// LatencyMs is how long the last check took in milliseconds
+	LatencyMs int64 `json:"latency_ms" example:"120"`
+
+	// FinalURL is where the last check ended up after redirects
+	FinalURL string `json:"final_url" example:"https://github.com/username"`
+
+	// LastError describes why the last check failed
+	LastError string `json:"last_error" example:""`
+
+	// FailureStreak counts consecutive failed checks
+	FailureStreak int `json:"failure_streak" example:"0"`
+
+	// CheckedAt is when the link was last checked
+	CheckedAt time.Time `json:"checked_at" example:"2024-01-01T00:00:00Z"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// @Description A previous version of a link's title and URL
+type LinkRevision struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// LinkID is the foreign key to the revised link
+	LinkID uint `json:"link_id" gorm:"index" example:"1"`
+
+	// Title the link had in this version
+	Title string `json:"title" example:"My GitHub Profile"`
+
+	// URL the link pointed at in this version
+	URL string `json:"url" example:"https://github.com/username"`
+
+	// Actor is the username of whoever replaced this version
+	Actor string `json:"actor" example:"johndoe"`
+
+	// ValidFrom is when this version went live
+	ValidFrom time.Time `json:"valid_from" example:"2024-01-01T00:00:00Z"`
+
+	// ValidTo is when this version was replaced
+	ValidTo time.Time `json:"valid_to" gorm:"index" example:"2024-02-01T00:00:00Z"`
+}
@@ -0,0 +1,36 @@
+package models
+
+const (
+	PlatformIOS      = "ios"
+	PlatformAndroid  = "android"
+	PlatformWindows  = "windows"
+	PlatformMacOS    = "macos"
+	PlatformLinux    = "linux"
+	PlatformChromeOS = "chromeos"
+	PlatformOther    = "other"
+)
+
+// @Description A targeting rule sending matching visitors of a link to another URL. Every condition that is set must match; a rule without conditions matches everyone.
+type LinkRule struct {
+	// Platforms matches the visitor's operating system
+	Platforms []string `json:"platforms,omitempty" example:"ios"`
+
+	// Languages matches the visitor's preferred Accept-Language, either a base
+	// language like "en" or a full tag like "pt-BR"
+	Languages []string `json:"languages,omitempty" example:"en"`
+
+	// Referrers matches the referring site's domain, including subdomains
+	Referrers []string `json:"referrers,omitempty" example:"instagram.com"`
+
+	// TimeFrom starts the matching time of day, as HH:MM
+	TimeFrom string `json:"time_from,omitempty" example:"09:00"`
+
+	// TimeTo ends the matching time of day, as HH:MM; ranges may wrap past midnight
+	TimeTo string `json:"time_to,omitempty" example:"17:00"`
+
+	// Timezone the time of day is read in, as an IANA name; defaults to UTC
+	Timezone string `json:"timezone,omitempty" example:"Europe/Berlin"`
+
+	// Target is where matching visitors are sent
+	Target string `json:"target" example:"https://apps.apple.com/app/id123456789"`
+}
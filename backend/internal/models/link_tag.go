@@ -0,0 +1,13 @@
+package models
+
+// @Description A free-form tag on a link
+type LinkTag struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// LinkID is the foreign key to the tagged link
+	LinkID uint `json:"link_id" gorm:"uniqueIndex:idx_link_tag" example:"1"`
+
+	// Name is the lowercased tag
+	Name string `json:"name" gorm:"size:32;uniqueIndex:idx_link_tag;index" example:"podcast"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// @Description Alternative title and/or URL of a link shown to a share of visitors
+type LinkVariant struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// LinkID is the foreign key to the tested link
+	LinkID uint `json:"link_id" gorm:"index" example:"1"`
+
+	// Title replaces the link's title, the link's own title is used if empty
+	Title string `json:"title" example:"Listen to my podcast"`
+
+	// URL replaces the link's destination, the link's own URL is used if empty
+	URL string `json:"url" example:"https://podcast.example.com/latest"`
+
+	// Weight is the variant's share of traffic relative to the other variants
+	Weight uint `json:"weight" example:"1"`
+
+	// Impressions counts profile views the variant was shown in
+	Impressions uint `json:"impressions" example:"120"`
+
+	// Clicks counts visitors who followed the variant
+	Clicks uint `json:"clicks" example:"12"`
+
+	// CreatedAt timestamp
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+
+	// UpdatedAt timestamp
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// @Description A visit to a user's public profile
+type ProfileView struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// UserID is the foreign key to the viewed profile's owner
+	UserID uint `json:"user_id" gorm:"index:idx_profile_view_user_time" example:"1"`
+
+	// ViewedAt is when the profile was opened
+	ViewedAt time.Time `json:"viewed_at" gorm:"index:idx_profile_view_user_time" example:"2024-01-01T12:00:00Z"`
+
+	// Referrer is the page the visitor came from
+	Referrer string `json:"referrer" gorm:"size:512" example:"https://www.instagram.com/"`
+
+	// VisitorHash is a hash of the visitor's IP address and user agent keyed
+	// with the day's salt, telling visitors apart within the day only
+	VisitorHash string `json:"-" gorm:"size:32"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// @Description A titled group of links shown as a header on a profile
+type Section struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// Title shown as the section header
+	Title string `json:"title" example:"Podcasts"`
+
+	// Position of the section on the profile, lowest first
+	Position int `json:"position" example:"0"`
+
+	// Collapsed marks the section as collapsed by default
+	Collapsed bool `json:"collapsed" example:"false"`
+
+	// UserID is the foreign key to the owner
+	UserID uint `json:"user_id" example:"1"`
+
+	// Links grouped under this section
+	Links []Link `json:"links" gorm:"foreignKey:SectionID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
+
+	// CreatedAt timestamp
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+
+	// UpdatedAt timestamp
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+}
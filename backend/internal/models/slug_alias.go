@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// @Description A retired link slug that keeps redirecting until it expires
+type SlugAlias struct {
+	// ID is the unique identifier
+	ID uint `gorm:"primarykey" json:"id" example:"1"`
+
+	// Slug is the retired short slug
+	Slug string `json:"slug" gorm:"size:64;uniqueIndex" example:"johndoe-podcast"`
+
+	// LinkID is the foreign key to the link the slug redirects to
+	LinkID uint `json:"link_id" gorm:"index" example:"1"`
+
+	// ExpiresAt is when the slug stops redirecting
+	ExpiresAt time.Time `json:"expires_at" example:"2024-02-01T00:00:00Z"`
+
+	// CreatedAt timestamp
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+}
@@ -16,9 +16,28 @@ type User struct {
 	// Bio contains user's description
 	Bio string `json:"bio" example:"Software developer passionate about Go"`
 
-	// Links associated with this user
+	// Links associated with this user that are not in a section
 	Links []Link `json:"links" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 
+	// Sections groups the user's links under ordered headers
+	Sections []Section `json:"sections" gorm:"foreignKey:UserID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+
+	// UTM is the default template of UTM parameters added to the user's
+	// outgoing links
+	UTM *UTMTemplate `json:"utm,omitempty" gorm:"serializer:json"`
+
+	// PublicClickCounts shows the click counts of the user's links on their
+	// public profile
+	PublicClickCounts bool `json:"public_click_counts" example:"false"`
+
+	// ShowBotTraffic includes clicks taken for automated traffic in the
+	// user's click charts and breakdowns
+	ShowBotTraffic bool `json:"show_bot_traffic" example:"false"`
+
+	// Admin lets the user manage the domain blocklist and read server stats.
+	// It is granted at startup to the accounts named in ADMIN_USERNAMES
+	Admin bool `json:"-" gorm:"not null;default:false"`
+
 	// PasswordHash stores the hashed password (not exposed in JSON)
 	PasswordHash string `json:"-"`
 
@@ -0,0 +1,19 @@
+package models
+
+// @Description UTM parameters added to outgoing links. Values may use the placeholders {username}, {link_id} and {slug}.
+type UTMTemplate struct {
+	// Source becomes utm_source
+	Source string `json:"source,omitempty" example:"linktree"`
+
+	// Medium becomes utm_medium
+	Medium string `json:"medium,omitempty" example:"social"`
+
+	// Campaign becomes utm_campaign
+	Campaign string `json:"campaign,omitempty" example:"{username}-bio"`
+
+	// Content becomes utm_content
+	Content string `json:"content,omitempty" example:"link-{link_id}"`
+
+	// Term becomes utm_term
+	Term string `json:"term,omitempty" example:""`
+}
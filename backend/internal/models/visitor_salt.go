@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// VisitorSalt is the secret visitor hashes of one UTC day are keyed with. It
+// is deleted once the day is over, so hashes of past days can no longer be
+// linked to a visitor.
+type VisitorSalt struct {
+	// Day is the UTC date, YYYY-MM-DD
+	Day string `gorm:"primarykey;size:10"`
+
+	// Salt is the hex encoded secret
+	Salt string `gorm:"size:64"`
+
+	// CreatedAt timestamp
+	CreatedAt time.Time
+}
@@ -1,72 +1,288 @@
 package services
 
 import (
-	"encoding/json"
 	"fmt"
 	"linktree-mohamedfadel-backend/internal/models"
+	"math"
+	"time"
 
-	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type AnalyticsService struct {
-	db *gorm.DB
+	db       *gorm.DB
+	ingester *ClickIngester
+	parser   *ClickParser
+	geo      GeoLocator
+	ipSalt   []byte
+	bots     *BotClassifier
+	salts    *visitorSalts
 }
 
 func NewAnalyticsService(db *gorm.DB) *AnalyticsService {
-	return &AnalyticsService{db: db}
+	salt, err := newIPHashSalt()
+	if err != nil {
+		panic(err)
+	}
+	return &AnalyticsService{db: db, parser: DefaultClickParser(), ipSalt: salt, salts: newVisitorSalts(db)}
+}
+
+// SetClickParser classifies clicks with parser instead of the built-in rules.
+func (s *AnalyticsService) SetClickParser(parser *ClickParser) {
+	s.parser = parser
+}
+
+// SetClickIngester queues clicks on ingester instead of writing them while
+// the visitor waits.
+func (s *AnalyticsService) SetClickIngester(ingester *ClickIngester) {
+	s.ingester = ingester
+}
+
+// SetGeoLocator locates clicks by IP address with geo, in addition to the
+// country headers of a CDN.
+func (s *AnalyticsService) SetGeoLocator(geo GeoLocator) {
+	s.geo = geo
+}
+
+// SetBotClassifier has bots pick out automated clicks, which are then
+// stored and counted apart from people's. Without one every click counts as
+// a person's.
+func (s *AnalyticsService) SetBotClassifier(bots *BotClassifier) {
+	s.bots = bots
+}
+
+// IngestionStats reports the state of the click ingester, if one is set.
+func (s *AnalyticsService) IngestionStats() ClickIngesterStats {
+	if s.ingester == nil {
+		return ClickIngesterStats{}
+	}
+	return s.ingester.Stats()
 }
 
-func (s *AnalyticsService) TrackLinkClicks(linkId uint64, visitorUsername string) error {
+// TrackLinkClicks stores a click on a link as an event and keeps the link's
+// click count up to date. With a click ingester set the click is queued and
+// written in a batch later; otherwise it is written right away. The
+// visitor's IP address is replaced by its location and the day's visitor
+// hash before the click goes anywhere, and who clicked is never recorded.
+func (s *AnalyticsService) TrackLinkClicks(linkId uint64, details ClickDetails) error {
 	var link models.Link
-	if err := s.db.Where("id = ?", linkId).First(&link).Error; err != nil {
+	if err := s.db.Select("id").Where("id = ?", linkId).First(&link).Error; err != nil {
 		return fmt.Errorf("link not found: %v", err)
 	}
 
-	var analytics models.Analytics
-	if err := s.db.Where("link_id = ?", linkId).First(&analytics).Error; err != nil {
-		emptyVisitors := make([]string, 0)
-		visitorsJSON, err := json.Marshal(emptyVisitors)
-		if err != nil {
-			return fmt.Errorf("failed to marshal empty visitors array: %v", err)
-		}
+	if !details.classified {
+		details = s.ClassifyClick(details)
+	}
 
-		analytics = models.Analytics{
-			LinkID:            uint(linkId),
-			ClickCount:        1,
-			VisitorsUsernames: datatypes.JSON(visitorsJSON),
-		}
-	} else {
-		analytics.ClickCount++
+	job := clickJob{
+		linkId:       linkId,
+		details:      details,
+		class:        s.parser.Parse(details.Referrer, details.UserAgent),
+		rulesVersion: s.parser.Version(),
+		location:     s.locate(details),
+		botReason:    details.BotReason,
+		at:           time.Now(),
 	}
 
-	if visitorUsername != "" {
-		var visitors []string
-		if err := json.Unmarshal(analytics.VisitorsUsernames, &visitors); err != nil {
-			return fmt.Errorf("failed to unmarshal visitors: %v", err)
-		}
+	visitorHash, err := s.salts.hash(details.IP, details.UserAgent, job.at)
+	if err != nil {
+		return err
+	}
+	job.visitorHash = visitorHash
+	job.details.IP = nil
+	if s.ingester != nil {
+		return s.ingester.Enqueue(job)
+	}
 
-		userExists := false
-		for _, visitor := range visitors {
-			if visitor == visitorUsername {
-				userExists = true
-				break
-			}
-		}
+	return recordClicks(s.db, []clickJob{job})
+}
 
-		if !userExists {
-			visitors = append(visitors, visitorUsername)
-			visitorsJSON, err := json.Marshal(visitors)
-			if err != nil {
-				return fmt.Errorf("failed to marshal visitors: %v", err)
-			}
-			analytics.VisitorsUsernames = datatypes.JSON(visitorsJSON)
-		}
+// ClassifyClick sets BotReason of a click before it is tracked, for callers
+// that treat automated clicks differently, e.g. by not counting them towards
+// a link's click cap. Tracking the returned details does not classify the
+// click again, so the rate check counts it once.
+func (s *AnalyticsService) ClassifyClick(details ClickDetails) ClickDetails {
+	details.BotReason = s.classifyBot(details, time.Now())
+	details.classified = true
+	return details
+}
+
+// classifyBot returns why a request looks automated, or "" when it looks
+// like a person or no bot classifier is set. Visitors are told apart by
+// their hashed address, or their hashed cookie without one; neither hash
+// leaves memory.
+func (s *AnalyticsService) classifyBot(details ClickDetails, at time.Time) string {
+	if s.bots == nil {
+		return ""
+	}
+
+	visitor := hashIP(s.ipSalt, details.IP)
+	if visitor == "" {
+		visitor = anonymizeVisitor(details.VisitorID)
 	}
+	return s.bots.Classify(details, visitor, at)
+}
 
-	if err := s.db.Save(&analytics).Error; err != nil {
+// locate finds where a click came from. A CDN's country header wins over the
+// geolocation database; the database's region and city are only kept when
+// it agrees on the country.
+func (s *AnalyticsService) locate(details ClickDetails) GeoLocation {
+	var location GeoLocation
+	if s.geo != nil && details.IP != nil {
+		// A failed lookup leaves the click without a location rather than
+		// losing it.
+		location, _ = s.geo.Locate(details.IP)
+	}
+
+	if details.Country != "" && details.Country != location.Country {
+		return GeoLocation{Country: details.Country}
+	}
+	return location
+}
+
+// TrackLinkUnlock counts a visitor getting past a link's password or
+// sensitive content warning.
+func (s *AnalyticsService) TrackLinkUnlock(linkId uint64) error {
+	return incrementAnalytics(s.db, linkId, "unlock_count", 1)
+}
+
+// incrementAnalytics adds n to a counter of a link's analytics row in a
+// single upsert, so concurrent clicks neither lose increments nor create a
+// second row.
+func incrementAnalytics(db *gorm.DB, linkId uint64, column string, n uint) error {
+	analytics := models.Analytics{LinkID: uint(linkId)}
+	switch column {
+	case "click_count":
+		analytics.ClickCount = n
+	case "bot_click_count":
+		analytics.BotClickCount = n
+	case "unlock_count":
+		analytics.UnlockCount = n
+	default:
+		return fmt.Errorf("unknown analytics counter %q", column)
+	}
+
+	err := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "link_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			column:       gorm.Expr("analytics."+column+" + ?", n),
+			"updated_at": time.Now(),
+		}),
+	}).Create(&analytics).Error
+	if err != nil {
 		return fmt.Errorf("failed to save analytics: %v", err)
 	}
 
 	return nil
 }
+
+// minVariantImpressions is how many impressions both sides of a comparison
+// need before a difference in click-through rate is called significant.
+const minVariantImpressions = 30
+
+// VariantStats is the performance of one variant of a link under A/B test.
+type VariantStats struct {
+	models.LinkVariant
+
+	// CTR is clicks per impression
+	CTR float64 `json:"ctr" example:"0.1"`
+
+	// ZScore compares the CTR with the first variant's; positive is better
+	ZScore float64 `json:"z_score" example:"2.3"`
+
+	// Significant is true when the difference from the first variant holds
+	// at 95% confidence
+	Significant bool `json:"significant" example:"true"`
+}
+
+// TrackVariantClick attributes a click on a link to one of its variants.
+func (s *AnalyticsService) TrackVariantClick(linkId uint64, variantId uint) error {
+	result := s.db.Model(&models.LinkVariant{}).Where("id = ? AND link_id = ?", variantId, linkId).
+		UpdateColumn("clicks", gorm.Expr("clicks + 1"))
+	if result.Error != nil {
+		return fmt.Errorf("failed to track variant click: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("variant not found")
+	}
+
+	return nil
+}
+
+// GetVariantReport returns the impressions, clicks and click-through rate of
+// each variant of one of the user's links. Every variant is compared with the
+// first one, which serves as the control.
+func (s *AnalyticsService) GetVariantReport(username string, linkId uint64) ([]VariantStats, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return nil, fmt.Errorf("link not found: %v", err)
+	}
+
+	var variants []models.LinkVariant
+	if err := s.db.Where("link_id = ?", link.ID).Order("id").Find(&variants).Error; err != nil {
+		return nil, fmt.Errorf("failed to load variants: %v", err)
+	}
+
+	report := make([]VariantStats, 0, len(variants))
+	for _, variant := range variants {
+		stats := VariantStats{LinkVariant: variant}
+		if variant.Impressions > 0 {
+			stats.CTR = float64(variant.Clicks) / float64(variant.Impressions)
+		}
+		if len(report) > 0 {
+			stats.ZScore, stats.Significant = compareCTR(report[0].LinkVariant, variant)
+		}
+		report = append(report, stats)
+	}
+
+	return report, nil
+}
+
+// compareCTR runs a two-proportion z-test of variant's click-through rate
+// against control's.
+func compareCTR(control, variant models.LinkVariant) (float64, bool) {
+	n1, n2 := float64(control.Impressions), float64(variant.Impressions)
+	if n1 == 0 || n2 == 0 {
+		return 0, false
+	}
+
+	p1, p2 := float64(control.Clicks)/n1, float64(variant.Clicks)/n2
+	pooled := float64(control.Clicks+variant.Clicks) / (n1 + n2)
+	stderr := math.Sqrt(pooled * (1 - pooled) * (1/n1 + 1/n2))
+	if stderr == 0 {
+		return 0, false
+	}
+
+	z := (p2 - p1) / stderr
+	significant := math.Abs(z) >= 1.96 &&
+		control.Impressions >= minVariantImpressions && variant.Impressions >= minVariantImpressions
+	return z, significant
+}
+
+// LinkURLAt returns the URL a link pointed at the given time, so clicks can
+// be matched with the destination that was live when they happened. Times
+// after the last change resolve to the link's current URL.
+func (s *AnalyticsService) LinkURLAt(linkId uint64, at time.Time) (string, error) {
+	var link models.Link
+	if err := s.db.Where("id = ?", linkId).First(&link).Error; err != nil {
+		return "", fmt.Errorf("link not found: %v", err)
+	}
+
+	var revisions []models.LinkRevision
+	err := s.db.Where("link_id = ? AND valid_to > ?", linkId, at).Order("valid_to").Limit(1).Find(&revisions).Error
+	if err != nil {
+		return "", fmt.Errorf("failed to load revisions: %v", err)
+	}
+
+	if len(revisions) == 1 {
+		return revisions[0].URL, nil
+	}
+	return link.URL, nil
+}
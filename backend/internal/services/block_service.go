@@ -0,0 +1,347 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"net/url"
+	"strings"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	maxTextBlockLength   = 5000
+	maxHeaderBlockLength = 200
+)
+
+var contactFormFields = map[string]bool{
+	"name":    true,
+	"email":   true,
+	"phone":   true,
+	"subject": true,
+	"message": true,
+}
+
+type BlockService struct {
+	db        *gorm.DB
+	urlPolicy *URLPolicy
+}
+
+func NewBlockService(db *gorm.DB) *BlockService {
+	return &BlockService{db: db, urlPolicy: NewURLPolicy()}
+}
+
+// SetURLPolicy replaces the policy that image and video URLs are checked
+// against.
+func (s *BlockService) SetURLPolicy(policy *URLPolicy) {
+	s.urlPolicy = policy
+}
+
+func (s *BlockService) CreateBlock(username string, block models.Block) (models.Block, error) {
+	if block.Type == models.BlockTypeLink {
+		return models.Block{}, errors.New("link blocks are created through the links API")
+	}
+
+	payload, err := s.ValidateBlockPayload(block.Type, block.Payload)
+	if err != nil {
+		return models.Block{}, err
+	}
+
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return models.Block{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	position, err := nextBlockPosition(s.db, user.ID)
+	if err != nil {
+		return models.Block{}, err
+	}
+
+	newBlock := models.Block{
+		Type:     block.Type,
+		Payload:  payload,
+		Position: position,
+		UserID:   user.ID,
+	}
+
+	if err := s.db.Create(&newBlock).Error; err != nil {
+		return models.Block{}, err
+	}
+
+	return newBlock, nil
+}
+
+// GetBlocks returns the user's public profile blocks, with links under A/B
+// test shown as saved.
+func (s *BlockService) GetBlocks(username string) ([]models.Block, error) {
+	return s.GetBlocksForVisitor(username, "")
+}
+
+// GetBlocksForVisitor is GetBlocks for a visitor: links under A/B test are
+// shown as the variant visitorId is assigned to, and the impressions are
+// counted.
+func (s *BlockService) GetBlocksForVisitor(username string, visitorId string) ([]models.Block, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %v", err)
+	}
+
+	var blocks []models.Block
+	err := s.db.Preload("Link").Preload("Link.Analytics").
+		Where("user_id = ?", user.ID).Order("position, id").Find(&blocks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blocks: %v", err)
+	}
+
+	visible := blocks[:0]
+	var links []*models.Link
+	for _, block := range blocks {
+		if block.Link != nil && block.Link.Hidden() {
+			continue
+		}
+		visible = append(visible, block)
+		if block.Link != nil {
+			links = append(links, block.Link)
+		}
+	}
+
+	if visitorId != "" {
+		if err := applyLinkVariants(s.db, links, visitorId); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, link := range links {
+		link.PublicAnalytics(user.PublicClickCounts)
+		link.HideProtectedURL()
+	}
+
+	return visible, nil
+}
+
+func (s *BlockService) UpdateBlock(username string, blockId uint64, payload datatypes.JSON) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var block models.Block
+	if err := s.db.Where("id = ? AND user_id = ?", blockId, user.ID).First(&block).Error; err != nil {
+		return fmt.Errorf("block not found: %v", err)
+	}
+
+	if block.Type == models.BlockTypeLink {
+		return errors.New("link blocks are updated through the links API")
+	}
+
+	validated, err := s.ValidateBlockPayload(block.Type, payload)
+	if err != nil {
+		return err
+	}
+	block.Payload = validated
+
+	return s.db.Save(&block).Error
+}
+
+// ReorderBlocks sets block positions to match blockIds, which must list every
+// block owned by the user exactly once.
+func (s *BlockService) ReorderBlocks(username string, blockIds []uint) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var ownedIds []uint
+	if err := s.db.Model(&models.Block{}).Where("user_id = ?", user.ID).Pluck("id", &ownedIds).Error; err != nil {
+		return fmt.Errorf("failed to load blocks: %v", err)
+	}
+
+	if !samePermutation(ownedIds, blockIds) {
+		return errors.New("block order must list every block exactly once")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range blockIds {
+			if err := tx.Model(&models.Block{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return fmt.Errorf("failed to reorder blocks: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BlockService) DeleteBlock(username string, blockId uint64) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var block models.Block
+	if err := s.db.Where("id = ? AND user_id = ?", blockId, user.ID).First(&block).Error; err != nil {
+		return fmt.Errorf("block not found: %v", err)
+	}
+
+	if block.Type == models.BlockTypeLink {
+		return errors.New("link blocks are deleted through the links API")
+	}
+
+	return s.db.Delete(&block).Error
+}
+
+// ValidateBlockPayload checks a payload against the schema of its block type
+// and returns it in normalized form. Unknown fields are rejected, and image
+// and video URLs must pass the URL policy.
+func (s *BlockService) ValidateBlockPayload(blockType string, payload datatypes.JSON) (datatypes.JSON, error) {
+	if len(payload) == 0 {
+		payload = datatypes.JSON("{}")
+	}
+
+	var normalized interface{}
+	var err error
+
+	switch blockType {
+	case models.BlockTypeText:
+		var p models.TextBlockPayload
+		if err = decodePayload(payload, &p); err == nil {
+			err = checkText(p.Text, maxTextBlockLength)
+		}
+		normalized = p
+	case models.BlockTypeHeader:
+		var p models.HeaderBlockPayload
+		if err = decodePayload(payload, &p); err == nil {
+			err = checkText(p.Text, maxHeaderBlockLength)
+		}
+		normalized = p
+	case models.BlockTypeDivider:
+		var p models.DividerBlockPayload
+		err = decodePayload(payload, &p)
+		normalized = p
+	case models.BlockTypeImage:
+		var p models.ImageBlockPayload
+		if err = decodePayload(payload, &p); err == nil {
+			err = s.checkHTTPURL("url", p.URL, true)
+		}
+		if err == nil {
+			err = s.checkHTTPURL("link_url", p.LinkURL, false)
+		}
+		normalized = p
+	case models.BlockTypeVideo:
+		var p models.VideoBlockPayload
+		if err = decodePayload(payload, &p); err == nil {
+			err = s.checkHTTPURL("url", p.URL, true)
+		}
+		normalized = p
+	case models.BlockTypeEmailSignup:
+		var p models.EmailSignupBlockPayload
+		if err = decodePayload(payload, &p); err == nil && strings.TrimSpace(p.Title) == "" {
+			err = errors.New("title is required")
+		}
+		normalized = p
+	case models.BlockTypeContactForm:
+		var p models.ContactFormBlockPayload
+		if err = decodePayload(payload, &p); err == nil {
+			err = checkContactFormFields(p)
+		}
+		normalized = p
+	default:
+		return nil, fmt.Errorf("unknown block type %q", blockType)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s block payload: %w", blockType, err)
+	}
+
+	encoded, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+
+	return datatypes.JSON(encoded), nil
+}
+
+func decodePayload(payload datatypes.JSON, target interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+func checkText(text string, maxLength int) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.New("text is required")
+	}
+	if len(text) > maxLength {
+		return fmt.Errorf("text must be at most %d characters", maxLength)
+	}
+	return nil
+}
+
+// checkHTTPURL checks an http or https URL of a block against the URL policy.
+func (s *BlockService) checkHTTPURL(field, value string, required bool) error {
+	if value == "" {
+		if required {
+			return fmt.Errorf("%s is required", field)
+		}
+		return nil
+	}
+
+	parsed, err := url.ParseRequestURI(value)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%s must be an http or https url", field)
+	}
+	if err := s.urlPolicy.Check(value); err != nil {
+		return fmt.Errorf("%s: %w", field, err)
+	}
+	return nil
+}
+
+func checkContactFormFields(p models.ContactFormBlockPayload) error {
+	if strings.TrimSpace(p.Title) == "" {
+		return errors.New("title is required")
+	}
+	if len(p.Fields) == 0 {
+		return errors.New("fields must not be empty")
+	}
+
+	seen := make(map[string]bool, len(p.Fields))
+	for _, field := range p.Fields {
+		if !contactFormFields[field] {
+			return fmt.Errorf("unknown contact form field %q", field)
+		}
+		if seen[field] {
+			return fmt.Errorf("duplicate contact form field %q", field)
+		}
+		seen[field] = true
+	}
+	return nil
+}
+
+// CreateLinkBlock appends a link block rendering link to the end of its
+// owner's profile.
+func CreateLinkBlock(db *gorm.DB, link models.Link) error {
+	position, err := nextBlockPosition(db, link.UserID)
+	if err != nil {
+		return err
+	}
+
+	block := models.Block{
+		Type:     models.BlockTypeLink,
+		Payload:  datatypes.JSON("{}"),
+		Position: position,
+		UserID:   link.UserID,
+		LinkID:   &link.ID,
+	}
+
+	if err := db.Create(&block).Error; err != nil {
+		return fmt.Errorf("failed to create link block: %v", err)
+	}
+
+	return nil
+}
+
+func nextBlockPosition(db *gorm.DB, userId uint) (int, error) {
+	return nextPosition(db.Model(&models.Block{}).Where("user_id = ?", userId))
+}
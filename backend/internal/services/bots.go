@@ -0,0 +1,172 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Why a click was taken for automated traffic.
+const (
+	// BotReasonUserAgent is a user agent matching a bot signature
+	BotReasonUserAgent = "user_agent"
+	// BotReasonHeaders is a request without the headers every browser sends
+	BotReasonHeaders = "headers"
+	// BotReasonRate is a visitor clicking faster than a person would
+	BotReasonRate = "rate"
+)
+
+type BotClassifierConfig struct {
+	// Signatures are user agent fragments of automated clients; matching
+	// ignores case
+	Signatures []string
+	// RateLimit is how many clicks one visitor can make per RateWindow
+	// before further clicks count as automated; 0 turns the check off
+	RateLimit int
+	// RateWindow is the period RateLimit applies to
+	RateWindow time.Duration
+}
+
+// DefaultBotClassifierConfig takes the signatures of the built-in click
+// rules' bot device.
+func DefaultBotClassifierConfig() BotClassifierConfig {
+	return BotClassifierConfig{
+		Signatures: DefaultClickParser().BotSignatures(),
+		RateLimit:  30,
+		RateWindow: time.Minute,
+	}
+}
+
+// LoadBotClassifierConfig takes the signatures of parser's bot device and
+// overrides the defaults with BOT_RATE_LIMIT and BOT_RATE_WINDOW when they
+// are set. BOT_SIGNATURES_FILE replaces the signatures with the file's, one
+// per line; # starts a comment.
+func LoadBotClassifierConfig(parser *ClickParser) (BotClassifierConfig, error) {
+	config := DefaultBotClassifierConfig()
+	config.Signatures = parser.BotSignatures()
+
+	if value := os.Getenv("BOT_RATE_LIMIT"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid BOT_RATE_LIMIT: %v", err)
+		}
+		config.RateLimit = parsed
+	}
+
+	if value := os.Getenv("BOT_RATE_WINDOW"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid BOT_RATE_WINDOW: %v", err)
+		}
+		config.RateWindow = parsed
+	}
+
+	if path := os.Getenv("BOT_SIGNATURES_FILE"); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return config, fmt.Errorf("failed to open bot signatures: %v", err)
+		}
+		defer file.Close()
+
+		config.Signatures, err = readDomains(file)
+		if err != nil {
+			return config, fmt.Errorf("failed to read bot signatures: %v", err)
+		}
+	}
+
+	return config, nil
+}
+
+// BotClassifier tells automated clicks from human ones by their user agent,
+// their headers and how fast the same visitor keeps clicking. The rate check
+// is only as good as the visitor's address: the API takes it from forwarding
+// headers only when they come from TRUSTED_PROXIES, so a client cannot reset
+// its window by sending a new X-Forwarded-For with each click.
+type BotClassifier struct {
+	signatures []string
+	rateLimit  int
+	rateWindow time.Duration
+
+	// mu guards the click counts of the current rate windows
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+type rateWindow struct {
+	start  time.Time
+	clicks int
+}
+
+func NewBotClassifier(config BotClassifierConfig) *BotClassifier {
+	signatures := make([]string, 0, len(config.Signatures))
+	for _, signature := range config.Signatures {
+		if signature = strings.ToLower(strings.TrimSpace(signature)); signature != "" {
+			signatures = append(signatures, signature)
+		}
+	}
+	if config.RateWindow <= 0 {
+		config.RateWindow = time.Minute
+	}
+
+	return &BotClassifier{
+		signatures: signatures,
+		rateLimit:  config.RateLimit,
+		rateWindow: config.RateWindow,
+		windows:    make(map[string]*rateWindow),
+	}
+}
+
+// Classify returns why a request looks automated, or "" when it looks like a
+// person. visitor identifies whoever made it for the rate check, e.g. a
+// hash of their address; requests without one are not rate checked.
+func (b *BotClassifier) Classify(details ClickDetails, visitor string, at time.Time) string {
+	agent := strings.ToLower(strings.TrimSpace(details.UserAgent))
+	if agent != "" && matchesSignature(b.signatures, agent) {
+		return BotReasonUserAgent
+	}
+	// Browsers send both on every navigation and every fetch.
+	if agent == "" || strings.TrimSpace(details.AcceptLanguage) == "" {
+		return BotReasonHeaders
+	}
+	if visitor != "" && b.rateLimit > 0 && b.exceedsRate(visitor, at) {
+		return BotReasonRate
+	}
+	return ""
+}
+
+// exceedsRate counts a click by visitor and reports whether it is over the
+// limit of the visitor's current window.
+func (b *BotClassifier) exceedsRate(visitor string, at time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if at.Sub(b.lastSweep) >= b.rateWindow {
+		for key, window := range b.windows {
+			if at.Sub(window.start) >= b.rateWindow {
+				delete(b.windows, key)
+			}
+		}
+		b.lastSweep = at
+	}
+
+	window, ok := b.windows[visitor]
+	if !ok || at.Sub(window.start) >= b.rateWindow {
+		window = &rateWindow{start: at}
+		b.windows[visitor] = window
+	}
+	window.clicks++
+	return window.clicks > b.rateLimit
+}
+
+func matchesSignature(signatures []string, agent string) bool {
+	for _, signature := range signatures {
+		if strings.Contains(agent, signature) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultBreakdownLimit is how many values a breakdown lists per dimension
+	// unless asked otherwise
+	DefaultBreakdownLimit = 10
+	// MaxBreakdownLimit bounds the values listed per dimension
+	MaxBreakdownLimit = 100
+)
+
+// BreakdownEntry is the number of clicks with one value of a dimension.
+type BreakdownEntry struct {
+	// Value is the source domain, device class, browser, OS, country, region,
+	// city or destination URL
+	Value string `json:"value" example:"instagram.com"`
+
+	// Clicks is the number of clicks with the value
+	Clicks int64 `json:"clicks" example:"420"`
+
+	// Share is the fraction of all clicks in the period with the value
+	Share float64 `json:"share" example:"0.34"`
+}
+
+// ClickBreakdown is where a link's or profile's clicks came from and what
+// they were made with. Each dimension lists its most frequent values; the
+// values left out make up the rest of Total.
+type ClickBreakdown struct {
+	Timezone string    `json:"timezone" example:"Europe/Berlin"`
+	From     time.Time `json:"from" example:"2024-01-01T00:00:00+01:00"`
+	To       time.Time `json:"to" example:"2024-02-01T00:00:00+01:00"`
+	Total    int64     `json:"total" example:"1234"`
+	// UniqueVisitors counts each visitor once per UTC day they clicked
+	UniqueVisitors int64 `json:"unique_visitors" example:"980"`
+	// IncludesBots is true when the owner chose to see automated clicks
+	IncludesBots     bool             `json:"includes_bots" example:"false"`
+	Sources          []BreakdownEntry `json:"sources"`
+	Devices          []BreakdownEntry `json:"devices"`
+	Browsers         []BreakdownEntry `json:"browsers"`
+	OperatingSystems []BreakdownEntry `json:"operating_systems"`
+	Countries        []BreakdownEntry `json:"countries"`
+	Regions          []BreakdownEntry `json:"regions"`
+	Cities           []BreakdownEntry `json:"cities"`
+	// Destinations are the URLs the link pointed at when its clicks happened,
+	// as kept in its revision history; only link breakdowns have them
+	Destinations []BreakdownEntry `json:"destinations,omitempty"`
+}
+
+// GetLinkClickBreakdown returns the top sources, devices, browsers,
+// operating systems, locations and destinations of the clicks on one of the
+// user's links in the days selected by query.
+func (s *AnalyticsService) GetLinkClickBreakdown(username string, linkId uint64, query SeriesQuery, limit int) (ClickBreakdown, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return ClickBreakdown{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return ClickBreakdown{}, fmt.Errorf("link not found: %v", err)
+	}
+
+	links := func(db *gorm.DB) *gorm.DB {
+		return db.Where("link_id = ?", link.ID)
+	}
+	breakdown, err := s.clickBreakdown(query, limit, user.ShowBotTraffic, links)
+	if err != nil {
+		return breakdown, err
+	}
+
+	breakdown.Destinations, err = s.destinationBreakdown(link, breakdown, limit, links)
+	return breakdown, err
+}
+
+// GetProfileClickBreakdown returns the top sources, devices, browsers,
+// operating systems and locations of the clicks on all of the user's links
+// in the days selected by query.
+func (s *AnalyticsService) GetProfileClickBreakdown(username string, query SeriesQuery, limit int) (ClickBreakdown, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return ClickBreakdown{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	return s.clickBreakdown(query, limit, user.ShowBotTraffic, func(db *gorm.DB) *gorm.DB {
+		return db.Where("link_id IN (?)", s.db.Model(&models.Link{}).Select("id").Where("user_id = ?", user.ID))
+	})
+}
+
+// clickBreakdown breaks down the click events of the links selected by
+// links, leaving out automated clicks unless includeBots is set.
+func (s *AnalyticsService) clickBreakdown(query SeriesQuery, limit int, includeBots bool, links func(*gorm.DB) *gorm.DB) (ClickBreakdown, error) {
+	from, to, err := dateRange(query)
+	if err != nil {
+		return ClickBreakdown{}, err
+	}
+	if limit < 1 {
+		limit = DefaultBreakdownLimit
+	}
+	if limit > MaxBreakdownLimit {
+		limit = MaxBreakdownLimit
+	}
+
+	breakdown := ClickBreakdown{Timezone: from.Location().String(), From: from, To: to, IncludesBots: includeBots}
+	inRange := func(db *gorm.DB) *gorm.DB {
+		return clickEvents(db, from, to, includeBots, links)
+	}
+
+	if err := inRange(s.db).Count(&breakdown.Total).Error; err != nil {
+		return breakdown, fmt.Errorf("failed to count clicks: %v", err)
+	}
+	breakdown.UniqueVisitors, err = countUniqueVisitors(inRange(s.db))
+	if err != nil {
+		return breakdown, err
+	}
+
+	// Clicks not classified or located count as unknown. Cities are named
+	// with their country, as many countries share city names.
+	unknown := func(column string) string {
+		return "CASE WHEN " + column + " = '' THEN '" + ClassUnknown + "' ELSE " + column + " END"
+	}
+	dimensions := []struct {
+		name   string
+		value  string
+		target *[]BreakdownEntry
+	}{
+		{"source", unknown("source"), &breakdown.Sources},
+		{"device", unknown("device"), &breakdown.Devices},
+		{"browser", unknown("browser"), &breakdown.Browsers},
+		{"os", unknown("os"), &breakdown.OperatingSystems},
+		{"country", unknown("country"), &breakdown.Countries},
+		{"region", unknown("region"), &breakdown.Regions},
+		{"city", "CASE WHEN city = '' THEN '" + ClassUnknown + "' ELSE city || ', ' || country END", &breakdown.Cities},
+	}
+	for _, dimension := range dimensions {
+		target := dimension.target
+		*target = []BreakdownEntry{}
+		if breakdown.Total == 0 {
+			continue
+		}
+
+		var rows []struct {
+			Value  string
+			Clicks int64
+		}
+		err := inRange(s.db).Select(dimension.value + " AS value, COUNT(*) AS clicks").
+			Group("value").Order("clicks DESC, value").Limit(limit).Scan(&rows).Error
+		if err != nil {
+			return breakdown, fmt.Errorf("failed to break down clicks by %s: %v", dimension.name, err)
+		}
+
+		for _, row := range rows {
+			*target = append(*target, BreakdownEntry{
+				Value:  row.Value,
+				Clicks: row.Clicks,
+				Share:  float64(row.Clicks) / float64(breakdown.Total),
+			})
+		}
+	}
+
+	return breakdown, nil
+}
+
+// destinationBreakdown counts the clicks in breakdown by the URL link
+// pointed at when they happened. Each revision was live until its ValidTo
+// and the current URL since the last one, the same periods LinkURLAt goes by.
+func (s *AnalyticsService) destinationBreakdown(link models.Link, breakdown ClickBreakdown, limit int, links func(*gorm.DB) *gorm.DB) ([]BreakdownEntry, error) {
+	entries := []BreakdownEntry{}
+	if breakdown.Total == 0 {
+		return entries, nil
+	}
+
+	var revisions []models.LinkRevision
+	if err := s.db.Where("link_id = ?", link.ID).Order("valid_to").Find(&revisions).Error; err != nil {
+		return entries, fmt.Errorf("failed to load revisions: %v", err)
+	}
+
+	clicks := map[string]int64{}
+	var since time.Time
+	for i := 0; i <= len(revisions); i++ {
+		query := clickEvents(s.db, breakdown.From, breakdown.To, breakdown.IncludesBots, links)
+		if !since.IsZero() {
+			query = query.Where("occurred_at >= ?", since.UTC())
+		}
+
+		url := link.URL
+		if i < len(revisions) {
+			url = revisions[i].URL
+			since = revisions[i].ValidTo
+			query = query.Where("occurred_at < ?", since.UTC())
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return entries, fmt.Errorf("failed to break down clicks by destination: %v", err)
+		}
+		if count > 0 {
+			clicks[url] += count
+		}
+	}
+
+	for url, count := range clicks {
+		entries = append(entries, BreakdownEntry{
+			Value:  url,
+			Clicks: count,
+			Share:  float64(count) / float64(breakdown.Total),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Clicks != entries[j].Clicks {
+			return entries[i].Clicks > entries[j].Clicks
+		}
+		return entries[i].Value < entries[j].Value
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
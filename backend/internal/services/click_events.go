@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"net"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxClickFieldLength bounds the referrer and user agent kept per click
+	maxClickFieldLength = 512
+	// maxRegionLength bounds an ISO 3166-2 region code
+	maxRegionLength = 8
+	// maxCityLength bounds a city name kept per click
+	maxCityLength = 128
+)
+
+// ClickDetails describes the visitor behind a click.
+type ClickDetails struct {
+	Referrer       string
+	UserAgent      string
+	AcceptLanguage string
+	// Country is an ISO country code hint, e.g. from a CDN header
+	Country string
+	// VisitorID is the visitor's anonymous cookie; it is never stored
+	VisitorID string
+	// IP is the visitor's address; only its location and the day's visitor
+	// hash are stored
+	IP net.IP
+	// BotReason is why ClassifyClick took the click for automated traffic,
+	// or "" when it looks like a person
+	BotReason string
+
+	classified bool
+}
+
+// CountClicks counts the clicks by people on a link in [from, to). A zero
+// from or to leaves that end open; counting from the beginning includes the
+// clicks recorded before clicks were stored as events.
+func (s *AnalyticsService) CountClicks(linkId uint64, from, to time.Time) (int64, error) {
+	query := s.db.Model(&models.ClickEvent{}).Where("link_id = ? AND bot = ?", linkId, false)
+	if !from.IsZero() {
+		query = query.Where("occurred_at >= ?", from.UTC())
+	}
+	if !to.IsZero() {
+		query = query.Where("occurred_at < ?", to.UTC())
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count clicks: %v", err)
+	}
+
+	if from.IsZero() {
+		var analytics models.Analytics
+		err := s.db.Where("link_id = ?", linkId).Limit(1).Find(&analytics).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to load analytics: %v", err)
+		}
+		count += int64(analytics.HistoricalClickCount)
+	}
+
+	return count, nil
+}
+
+// clickJob is a click waiting to be stored.
+type clickJob struct {
+	linkId       uint64
+	details      ClickDetails
+	class        ClickClass
+	rulesVersion int
+	location     GeoLocation
+	visitorHash  string
+	botReason    string
+	at           time.Time
+}
+
+// recordClicks stores a batch of clicks: one event per click and one counter
+// increment per link. Bot clicks are counted apart. Clicks on links deleted
+// in the meantime are dropped.
+func recordClicks(db *gorm.DB, jobs []clickJob) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	linkIds := make([]uint64, 0, len(jobs))
+	for _, job := range jobs {
+		linkIds = append(linkIds, job.linkId)
+	}
+
+	var existing []uint64
+	if err := db.Model(&models.Link{}).Where("id IN ?", linkIds).Pluck("id", &existing).Error; err != nil {
+		return fmt.Errorf("failed to load links: %v", err)
+	}
+	links := make(map[uint64]bool, len(existing))
+	for _, id := range existing {
+		links[id] = true
+	}
+
+	events := make([]models.ClickEvent, 0, len(jobs))
+	counts := make(map[uint64]uint)
+	botCounts := make(map[uint64]uint)
+	for _, job := range jobs {
+		if !links[job.linkId] {
+			continue
+		}
+
+		event := newClickEvent(job)
+		events = append(events, event)
+		if event.Bot {
+			botCounts[job.linkId]++
+		} else {
+			counts[job.linkId]++
+		}
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	// Incrementing links in a fixed order keeps concurrent batches from
+	// deadlocking on each other's analytics rows.
+	counted := make([]uint64, 0, len(counts)+len(botCounts))
+	for linkId := range counts {
+		counted = append(counted, linkId)
+	}
+	for linkId := range botCounts {
+		if counts[linkId] == 0 {
+			counted = append(counted, linkId)
+		}
+	}
+	sort.Slice(counted, func(i, j int) bool { return counted[i] < counted[j] })
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&events, 500).Error; err != nil {
+			return fmt.Errorf("failed to save clicks: %v", err)
+		}
+		for _, linkId := range counted {
+			if counts[linkId] > 0 {
+				if err := incrementAnalytics(tx, linkId, "click_count", counts[linkId]); err != nil {
+					return err
+				}
+			}
+			if botCounts[linkId] > 0 {
+				if err := incrementAnalytics(tx, linkId, "bot_click_count", botCounts[linkId]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// newClickEvent builds the stored event for a click. Nothing that identifies
+// the visitor beyond the day's visitor hash is kept.
+func newClickEvent(job clickJob) models.ClickEvent {
+	event := models.ClickEvent{
+		LinkID:       uint(job.linkId),
+		OccurredAt:   job.at.UTC(),
+		Referrer:     truncateRunes(job.details.Referrer, maxClickFieldLength),
+		UserAgent:    truncateRunes(job.details.UserAgent, maxClickFieldLength),
+		Country:      job.location.Country,
+		Region:       job.location.Region,
+		City:         truncateRunes(job.location.City, maxCityLength),
+		VisitorHash:  job.visitorHash,
+		Bot:          job.botReason != "",
+		BotReason:    job.botReason,
+		Source:       job.class.Source,
+		Device:       job.class.Device,
+		Browser:      job.class.Browser,
+		OS:           job.class.OS,
+		RulesVersion: job.rulesVersion,
+	}
+	if len(event.Country) != 2 {
+		event.Country, event.Region, event.City = "", "", ""
+	}
+	if len(event.Region) > maxRegionLength {
+		event.Region = ""
+	}
+	return event
+}
+
+// anonymizeVisitor replaces a visitor cookie with a one-way hash, so the bot
+// classifier can tell visitors apart without keeping the cookie.
+func anonymizeVisitor(visitorId string) string {
+	if visitorId == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(visitorId))
+	return hex.EncodeToString(sum[:16])
+}
+
+func truncateRunes(value string, max int) string {
+	if utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	return string([]rune(value)[:max])
+}
@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// What happens to a click that arrives while the ingestion queue is full.
+const (
+	// OverflowSync writes the click while the visitor waits
+	OverflowSync = "sync"
+	// OverflowBlock waits up to BlockTimeout for room and then drops the click
+	OverflowBlock = "block"
+	// OverflowDrop drops the click straight away
+	OverflowDrop = "drop"
+)
+
+type ClickIngesterConfig struct {
+	// QueueSize is how many clicks can wait to be written
+	QueueSize int
+	// Workers is how many batches are written at once
+	Workers int
+	// BatchSize is how many clicks a worker collects before writing them
+	BatchSize int
+	// FlushInterval is the longest a click waits in a partial batch
+	FlushInterval time.Duration
+	// Overflow is the policy for clicks arriving at a full queue
+	Overflow string
+	// BlockTimeout is how long the block policy waits for room
+	BlockTimeout time.Duration
+}
+
+func DefaultClickIngesterConfig() ClickIngesterConfig {
+	return ClickIngesterConfig{
+		QueueSize:     10000,
+		Workers:       4,
+		BatchSize:     200,
+		FlushInterval: time.Second,
+		Overflow:      OverflowSync,
+		BlockTimeout:  100 * time.Millisecond,
+	}
+}
+
+// LoadClickIngesterConfig overrides the defaults with CLICK_QUEUE_SIZE,
+// CLICK_WORKERS, CLICK_BATCH_SIZE, CLICK_FLUSH_INTERVAL, CLICK_OVERFLOW and
+// CLICK_BLOCK_TIMEOUT when they are set.
+func LoadClickIngesterConfig() (ClickIngesterConfig, error) {
+	config := DefaultClickIngesterConfig()
+
+	durations := map[string]*time.Duration{
+		"CLICK_FLUSH_INTERVAL": &config.FlushInterval,
+		"CLICK_BLOCK_TIMEOUT":  &config.BlockTimeout,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	ints := map[string]*int{
+		"CLICK_QUEUE_SIZE": &config.QueueSize,
+		"CLICK_WORKERS":    &config.Workers,
+		"CLICK_BATCH_SIZE": &config.BatchSize,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := os.Getenv("CLICK_OVERFLOW"); value != "" {
+		config.Overflow = value
+	}
+	switch config.Overflow {
+	case OverflowSync, OverflowBlock, OverflowDrop:
+	default:
+		return config, fmt.Errorf("invalid CLICK_OVERFLOW: %q", config.Overflow)
+	}
+
+	return config, nil
+}
+
+// ClickIngesterStats describes the click ingestion queue, for sizing it.
+type ClickIngesterStats struct {
+	// Enabled is false when clicks are written while the visitor waits
+	Enabled bool `json:"enabled" example:"true"`
+
+	// QueueDepth is how many clicks are waiting to be written
+	QueueDepth int `json:"queue_depth" example:"12"`
+
+	// QueueCapacity is how many clicks can wait
+	QueueCapacity int `json:"queue_capacity" example:"10000"`
+
+	// Enqueued counts clicks queued since start
+	Enqueued int64 `json:"enqueued" example:"52000"`
+
+	// Overflowed counts clicks that found the queue full and were written
+	// while the visitor waited
+	Overflowed int64 `json:"overflowed" example:"3"`
+
+	// Dropped counts clicks lost to a full queue or a failed write
+	Dropped int64 `json:"dropped" example:"0"`
+
+	// FailedClicks counts the clicks of batch writes that failed; they are
+	// included in Dropped
+	FailedClicks int64 `json:"failed_clicks" example:"0"`
+
+	// Flushes counts batch writes
+	Flushes int64 `json:"flushes" example:"260"`
+
+	// FlushErrors counts batch writes that failed
+	FlushErrors int64 `json:"flush_errors" example:"0"`
+
+	// LastFlushMs is how long the last batch write took
+	LastFlushMs float64 `json:"last_flush_ms" example:"4.2"`
+
+	// AverageFlushMs is the mean batch write time
+	AverageFlushMs float64 `json:"average_flush_ms" example:"3.8"`
+
+	// MaxFlushMs is the slowest batch write
+	MaxFlushMs float64 `json:"max_flush_ms" example:"41.5"`
+}
+
+// ClickIngester takes clicks off the request path: handlers enqueue them and
+// a pool of workers writes them to the database in batches.
+type ClickIngester struct {
+	db      *gorm.DB
+	config  ClickIngesterConfig
+	queue   chan clickJob
+	workers sync.WaitGroup
+
+	// mu keeps clicks from being enqueued while the queue is being closed
+	mu     sync.RWMutex
+	closed bool
+
+	enqueued    atomic.Int64
+	overflowed  atomic.Int64
+	dropped     atomic.Int64
+	failed      atomic.Int64
+	flushes     atomic.Int64
+	flushErrors atomic.Int64
+	lastFlush   atomic.Int64
+	totalFlush  atomic.Int64
+	maxFlush    atomic.Int64
+}
+
+func NewClickIngester(db *gorm.DB, config ClickIngesterConfig) *ClickIngester {
+	if config.QueueSize < 1 {
+		config.QueueSize = 1
+	}
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+
+	return &ClickIngester{
+		db:     db,
+		config: config,
+		queue:  make(chan clickJob, config.QueueSize),
+	}
+}
+
+// Start launches the workers. They run until Close.
+func (i *ClickIngester) Start() {
+	for w := 0; w < i.config.Workers; w++ {
+		i.workers.Add(1)
+		go i.work()
+	}
+}
+
+// Enqueue queues a click to be written. When the queue is full the overflow
+// policy decides; after Close clicks are written right away.
+func (i *ClickIngester) Enqueue(job clickJob) error {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	if i.closed {
+		return recordClicks(i.db, []clickJob{job})
+	}
+
+	select {
+	case i.queue <- job:
+		i.enqueued.Add(1)
+		return nil
+	default:
+	}
+
+	switch i.config.Overflow {
+	case OverflowDrop:
+		i.dropped.Add(1)
+		return nil
+	case OverflowBlock:
+		timer := time.NewTimer(i.config.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case i.queue <- job:
+			i.enqueued.Add(1)
+		case <-timer.C:
+			i.dropped.Add(1)
+		}
+		return nil
+	default:
+		i.overflowed.Add(1)
+		return recordClicks(i.db, []clickJob{job})
+	}
+}
+
+// Close stops taking clicks into the queue and waits for the workers to
+// write the ones already in it, or for ctx to end.
+func (i *ClickIngester) Close(ctx context.Context) error {
+	i.mu.Lock()
+	if !i.closed {
+		i.closed = true
+		close(i.queue)
+	}
+	i.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		i.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.New("timed out writing queued clicks")
+	}
+}
+
+// Stats reports the queue depth and how batch writes are doing.
+func (i *ClickIngester) Stats() ClickIngesterStats {
+	stats := ClickIngesterStats{
+		Enabled:       true,
+		QueueDepth:    len(i.queue),
+		QueueCapacity: cap(i.queue),
+		Enqueued:      i.enqueued.Load(),
+		Overflowed:    i.overflowed.Load(),
+		Dropped:       i.dropped.Load(),
+		FailedClicks:  i.failed.Load(),
+		Flushes:       i.flushes.Load(),
+		FlushErrors:   i.flushErrors.Load(),
+		LastFlushMs:   milliseconds(i.lastFlush.Load()),
+		MaxFlushMs:    milliseconds(i.maxFlush.Load()),
+	}
+	if stats.Flushes > 0 {
+		stats.AverageFlushMs = milliseconds(i.totalFlush.Load() / stats.Flushes)
+	}
+	return stats
+}
+
+func (i *ClickIngester) work() {
+	defer i.workers.Done()
+
+	ticker := time.NewTicker(i.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]clickJob, 0, i.config.BatchSize)
+	for {
+		select {
+		case job, ok := <-i.queue:
+			if !ok {
+				i.flush(batch)
+				return
+			}
+			batch = append(batch, job)
+			if len(batch) >= i.config.BatchSize {
+				i.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			i.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (i *ClickIngester) flush(batch []clickJob) {
+	if len(batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	err := recordClicks(i.db, batch)
+	elapsed := int64(time.Since(start))
+
+	i.flushes.Add(1)
+	i.lastFlush.Store(elapsed)
+	i.totalFlush.Add(elapsed)
+	for {
+		max := i.maxFlush.Load()
+		if elapsed <= max || i.maxFlush.CompareAndSwap(max, elapsed) {
+			break
+		}
+	}
+
+	if err != nil {
+		i.flushErrors.Add(1)
+		i.dropped.Add(int64(len(batch)))
+		i.failed.Add(int64(len(batch)))
+		log.Printf("click flush failed, dropped %d clicks on links %v: %v", len(batch), batchLinkIds(batch), err)
+	}
+}
+
+// batchLinkIds lists the links clicked in a batch, each once.
+func batchLinkIds(batch []clickJob) []uint64 {
+	seen := make(map[uint64]bool)
+	var linkIds []uint64
+	for _, job := range batch {
+		if !seen[job.linkId] {
+			seen[job.linkId] = true
+			linkIds = append(linkIds, job.linkId)
+		}
+	}
+	sort.Slice(linkIds, func(a, b int) bool { return linkIds[a] < linkIds[b] })
+	return linkIds
+}
+
+func milliseconds(nanoseconds int64) float64 {
+	return float64(nanoseconds) / float64(time.Millisecond)
+}
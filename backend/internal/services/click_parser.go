@@ -0,0 +1,266 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"os"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// defaultClickRules ships with the binary, so clicks are classified without
+// any lookup service. CLICK_RULES_FILE replaces it with an updated copy.
+//
+//go:embed click_rules.json
+var defaultClickRules []byte
+
+// Values recorded when a click has nothing to classify.
+const (
+	// SourceDirect is the source of clicks without a referrer
+	SourceDirect = "direct"
+	// ClassUnknown is the device, browser and OS of clicks without a user agent
+	ClassUnknown = "unknown"
+	// ClassOther is the browser and OS of user agents no rule matches
+	ClassOther = "other"
+	// DeviceBot is the device whose patterns also tell the bot classifier
+	// which user agents are automated
+	DeviceBot = "bot"
+)
+
+// ClickRule names the class of user agents containing any of its patterns.
+type ClickRule struct {
+	Name     string   `json:"name"`
+	Patterns []string `json:"patterns"`
+}
+
+// SourceRule groups referrers from any of its domains, or their subdomains,
+// under one source.
+type SourceRule struct {
+	Name    string   `json:"name"`
+	Domains []string `json:"domains"`
+}
+
+// ClickRules is the rules file classifying clicks. Rules are tried in order
+// and the first match wins, so more specific ones (Edge, in-app browsers)
+// come before the ones they would otherwise match (Chrome, Safari).
+type ClickRules struct {
+	// Version is stored with each classified click; raising it reclassifies
+	// the clicks stored under an older version
+	Version          int          `json:"version"`
+	Sources          []SourceRule `json:"sources"`
+	Devices          []ClickRule  `json:"devices"`
+	DefaultDevice    string       `json:"default_device"`
+	Browsers         []ClickRule  `json:"browsers"`
+	OperatingSystems []ClickRule  `json:"operating_systems"`
+}
+
+// ClickClass is what a click's Referer and User-Agent headers say about where
+// it came from and what it was made with.
+type ClickClass struct {
+	Source  string
+	Device  string
+	Browser string
+	OS      string
+}
+
+// ClickParser classifies clicks with a set of ClickRules.
+type ClickParser struct {
+	rules   ClickRules
+	sources map[string]string
+}
+
+// NewClickParser parses a rules file.
+func NewClickParser(data []byte) (*ClickParser, error) {
+	var rules ClickRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid click rules: %v", err)
+	}
+	if rules.Version < 1 {
+		return nil, fmt.Errorf("invalid click rules: version must be at least 1")
+	}
+	if rules.DefaultDevice == "" {
+		rules.DefaultDevice = ClassOther
+	}
+
+	for _, group := range [][]ClickRule{rules.Devices, rules.Browsers, rules.OperatingSystems} {
+		for i := range group {
+			if group[i].Name == "" {
+				return nil, fmt.Errorf("invalid click rules: rule without a name")
+			}
+			for j, pattern := range group[i].Patterns {
+				group[i].Patterns[j] = strings.ToLower(pattern)
+			}
+		}
+	}
+
+	parser := &ClickParser{rules: rules, sources: make(map[string]string)}
+	for _, source := range rules.Sources {
+		for _, domain := range source.Domains {
+			parser.sources[strings.ToLower(domain)] = source.Name
+		}
+	}
+
+	return parser, nil
+}
+
+// DefaultClickParser classifies clicks with the rules built into the binary.
+func DefaultClickParser() *ClickParser {
+	parser, err := NewClickParser(defaultClickRules)
+	if err != nil {
+		panic(err)
+	}
+	return parser
+}
+
+// LoadClickParser reads the rules from CLICK_RULES_FILE when it is set and
+// uses the built-in rules otherwise.
+func LoadClickParser() (*ClickParser, error) {
+	path := os.Getenv("CLICK_RULES_FILE")
+	if path == "" {
+		return DefaultClickParser(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read click rules: %v", err)
+	}
+	return NewClickParser(data)
+}
+
+// Version is the version of the parser's rules.
+func (p *ClickParser) Version() int {
+	return p.rules.Version
+}
+
+// Parse classifies a click by its Referer and User-Agent headers.
+func (p *ClickParser) Parse(referrer, userAgent string) ClickClass {
+	class := ClickClass{
+		Source:  p.source(referrer),
+		Device:  ClassUnknown,
+		Browser: ClassUnknown,
+		OS:      ClassUnknown,
+	}
+
+	agent := strings.ToLower(strings.TrimSpace(userAgent))
+	if agent == "" {
+		return class
+	}
+
+	class.Device = matchClickRule(p.rules.Devices, agent, p.rules.DefaultDevice)
+	class.Browser = matchClickRule(p.rules.Browsers, agent, ClassOther)
+	class.OS = matchClickRule(p.rules.OperatingSystems, agent, ClassOther)
+	return class
+}
+
+// Platform returns the operating system of a user agent as a link rule
+// platform: the lowercased name of the operating system rule it matches, or
+// other when that is not a platform link rules know.
+func (p *ClickParser) Platform(userAgent string) string {
+	agent := strings.ToLower(strings.TrimSpace(userAgent))
+	if agent == "" {
+		return models.PlatformOther
+	}
+
+	platform := strings.ToLower(matchClickRule(p.rules.OperatingSystems, agent, models.PlatformOther))
+	if !knownPlatforms[platform] {
+		return models.PlatformOther
+	}
+	return platform
+}
+
+// BotSignatures returns the user agent patterns of the bot device rule.
+func (p *ClickParser) BotSignatures() []string {
+	var signatures []string
+	for _, rule := range p.rules.Devices {
+		if rule.Name == DeviceBot {
+			signatures = append(signatures, rule.Patterns...)
+		}
+	}
+	return signatures
+}
+
+// source returns the domain a referrer belongs to, without "www.", mapped to
+// its source when a rule covers it.
+func (p *ClickParser) source(referrer string) string {
+	if strings.TrimSpace(referrer) == "" {
+		return SourceDirect
+	}
+
+	host := strings.TrimPrefix(referrerHost(referrer), "www.")
+	if host == "" {
+		return ClassUnknown
+	}
+
+	for domain := host; domain != ""; {
+		if name, ok := p.sources[domain]; ok {
+			return name
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return host
+}
+
+func matchClickRule(rules []ClickRule, agent, fallback string) string {
+	for _, rule := range rules {
+		for _, pattern := range rule.Patterns {
+			if strings.Contains(agent, pattern) {
+				return rule.Name
+			}
+		}
+	}
+	return fallback
+}
+
+// reclassifyBatchSize is how many stored clicks are reclassified per
+// transaction.
+const reclassifyBatchSize = 1000
+
+// ReclassifyClicks classifies the stored clicks that were classified with
+// another version of the rules, or not at all, from their stored referrer and
+// user agent. It returns how many clicks it updated.
+func ReclassifyClicks(db *gorm.DB, parser *ClickParser) (int, error) {
+	updated := 0
+	var lastId uint
+	for {
+		var events []models.ClickEvent
+		err := db.Select("id", "referrer", "user_agent").
+			Where("id > ? AND rules_version <> ?", lastId, parser.Version()).
+			Order("id").Limit(reclassifyBatchSize).Find(&events).Error
+		if err != nil {
+			return updated, fmt.Errorf("failed to load clicks: %v", err)
+		}
+		if len(events) == 0 {
+			return updated, nil
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, event := range events {
+				class := parser.Parse(event.Referrer, event.UserAgent)
+				err := tx.Model(&models.ClickEvent{}).Where("id = ?", event.ID).UpdateColumns(map[string]interface{}{
+					"source":        class.Source,
+					"device":        class.Device,
+					"browser":       class.Browser,
+					"os":            class.OS,
+					"rules_version": parser.Version(),
+				}).Error
+				if err != nil {
+					return fmt.Errorf("failed to save click: %v", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return updated, err
+		}
+
+		updated += len(events)
+		lastId = events[len(events)-1].ID
+	}
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Series intervals.
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+const (
+	clickRollupName = "clicks"
+	// rollupGrace leaves the hour that just ended alone for a while, so
+	// clicks still waiting in the ingestion queue make it into its rollup
+	rollupGrace = 5 * time.Minute
+	// rollupChunk is how much history one rollup transaction aggregates
+	rollupChunk = 24 * time.Hour
+	// maxSeriesPoints bounds the length of a series
+	maxSeriesPoints = 1000
+	// defaultSeriesDays is the range of a series without a start date
+	defaultSeriesDays = 30
+)
+
+// ErrInvalidSeriesQuery is returned for a series with an unknown interval or
+// a date range that is reversed or has too many points.
+var ErrInvalidSeriesQuery = errors.New("invalid series query")
+
+// SeriesQuery selects a click series. From and To are the first and last day
+// included, as dates in Location.
+type SeriesQuery struct {
+	From     time.Time
+	To       time.Time
+	Interval string
+	Location *time.Location
+}
+
+// SeriesPoint is the number of clicks in one bucket of a series.
+type SeriesPoint struct {
+	// Start is when the bucket starts, in the series' timezone
+	Start time.Time `json:"start" example:"2024-01-01T00:00:00+01:00"`
+
+	// Clicks is the number of clicks in the bucket
+	Clicks int64 `json:"clicks" example:"42"`
+}
+
+// ClickSeries is a link's or profile's clicks over time.
+type ClickSeries struct {
+	Interval string    `json:"interval" example:"day"`
+	Timezone string    `json:"timezone" example:"Europe/Berlin"`
+	From     time.Time `json:"from" example:"2024-01-01T00:00:00+01:00"`
+	To       time.Time `json:"to" example:"2024-02-01T00:00:00+01:00"`
+	Total    int64     `json:"total" example:"1234"`
+	// UniqueVisitors counts each visitor once per UTC day they clicked
+	UniqueVisitors int64 `json:"unique_visitors" example:"980"`
+	// IncludesBots is true when the owner chose to see automated clicks
+	IncludesBots bool          `json:"includes_bots" example:"false"`
+	Points       []SeriesPoint `json:"points"`
+}
+
+// GetLinkClickSeries returns the clicks on one of the user's links bucketed
+// by query's interval. Automated clicks are left out unless the user chose
+// to see them.
+func (s *AnalyticsService) GetLinkClickSeries(username string, linkId uint64, query SeriesQuery) (ClickSeries, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return ClickSeries{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return ClickSeries{}, fmt.Errorf("link not found: %v", err)
+	}
+
+	return s.clickSeries(query, user.ShowBotTraffic, func(db *gorm.DB) *gorm.DB {
+		return db.Where("link_id = ?", link.ID)
+	})
+}
+
+// GetProfileClickSeries returns the clicks on all of the user's links
+// bucketed by query's interval, leaving out automated clicks like
+// GetLinkClickSeries.
+func (s *AnalyticsService) GetProfileClickSeries(username string, query SeriesQuery) (ClickSeries, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return ClickSeries{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	return s.clickSeries(query, user.ShowBotTraffic, func(db *gorm.DB) *gorm.DB {
+		return db.Where("link_id IN (?)", s.db.Model(&models.Link{}).Select("id").Where("user_id = ?", user.ID))
+	})
+}
+
+// clickSeries adds up the clicks of the links selected by links.
+func (s *AnalyticsService) clickSeries(query SeriesQuery, includeBots bool, links func(*gorm.DB) *gorm.DB) (ClickSeries, error) {
+	series, err := newClickSeries(query)
+	if err != nil {
+		return series, err
+	}
+	series.IncludesBots = includeBots
+
+	counts, err := s.hourlyClickCounts(series.From, series.To, links)
+	if err != nil {
+		return series, err
+	}
+
+	index := make(map[int64]int, len(series.Points))
+	for i, point := range series.Points {
+		index[point.Start.Unix()] = i
+	}
+	for _, count := range counts {
+		if i, ok := index[bucketStart(count.hour, series.Interval, series.From.Location()).Unix()]; ok {
+			series.Points[i].Clicks += count.total(includeBots)
+			series.Total += count.total(includeBots)
+		}
+	}
+
+	series.UniqueVisitors, err = countUniqueVisitors(clickEvents(s.db, series.From, series.To, includeBots, links))
+	if err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+// hourlyClickCounts counts the clicks on the links selected by links in
+// [from, to) per link and UTC hour. Hours the rollup job has covered are read
+// from the rollups, later ones from the click events themselves.
+func (s *AnalyticsService) hourlyClickCounts(from, to time.Time, links func(*gorm.DB) *gorm.DB) ([]hourCount, error) {
+	var cursor models.RollupCursor
+	if err := s.db.Where("name = ?", clickRollupName).Limit(1).Find(&cursor).Error; err != nil {
+		return nil, fmt.Errorf("failed to load rollup cursor: %v", err)
+	}
+
+	from, to = from.UTC(), to.UTC()
+	var counts []hourCount
+
+	if cursor.RolledUntil.After(from) {
+		rolledTo := to
+		if cursor.RolledUntil.Before(rolledTo) {
+			rolledTo = cursor.RolledUntil
+		}
+
+		var rollups []models.ClickRollup
+		err := s.db.Scopes(links).Where("hour >= ? AND hour < ?", from, rolledTo).Find(&rollups).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rollups: %v", err)
+		}
+		for _, rollup := range rollups {
+			counts = append(counts, hourCount{linkId: rollup.LinkID, hour: rollup.Hour.UTC(), clicks: rollup.Clicks, bots: rollup.BotClicks})
+		}
+	}
+
+	if cursor.RolledUntil.Before(to) {
+		rawFrom := from
+		if cursor.RolledUntil.After(rawFrom) {
+			rawFrom = cursor.RolledUntil
+		}
+
+		raw, err := hourlyClicks(s.db, rawFrom, to, links)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, raw...)
+	}
+
+	return counts, nil
+}
+
+// newClickSeries lays out the empty buckets of a series.
+func newClickSeries(query SeriesQuery) (ClickSeries, error) {
+	if query.Interval == "" {
+		query.Interval = IntervalDay
+	}
+	if query.Interval != IntervalHour && query.Interval != IntervalDay && query.Interval != IntervalWeek {
+		return ClickSeries{}, fmt.Errorf("%w: unknown interval %q", ErrInvalidSeriesQuery, query.Interval)
+	}
+
+	from, to, err := dateRange(query)
+	if err != nil {
+		return ClickSeries{}, err
+	}
+	from = bucketStart(from, query.Interval, from.Location())
+
+	series := ClickSeries{
+		Interval: query.Interval,
+		Timezone: from.Location().String(),
+		From:     from,
+		To:       to,
+		Points:   []SeriesPoint{},
+	}
+	for start := from; start.Before(to); start = nextBucket(start, query.Interval) {
+		if len(series.Points) == maxSeriesPoints {
+			return ClickSeries{}, fmt.Errorf("%w: a series can have at most %d points; use a longer interval", ErrInvalidSeriesQuery, maxSeriesPoints)
+		}
+		series.Points = append(series.Points, SeriesPoint{Start: start})
+	}
+
+	return series, nil
+}
+
+// dateRange turns the first and last day of query into the times the range
+// starts and ends, in the query's timezone.
+func dateRange(query SeriesQuery) (time.Time, time.Time, error) {
+	loc := query.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	to := query.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	to = bucketStart(to, IntervalDay, loc).AddDate(0, 0, 1)
+
+	from := query.From
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultSeriesDays)
+	}
+	from = bucketStart(from, IntervalDay, loc)
+	if !from.Before(to) {
+		return from, to, fmt.Errorf("%w: the start date must not be after the end date", ErrInvalidSeriesQuery)
+	}
+
+	return from, to, nil
+}
+
+// bucketStart returns the start of the bucket containing t, in loc. Weeks
+// start on Monday.
+func bucketStart(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case IntervalHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	}
+}
+
+func nextBucket(start time.Time, interval string) time.Time {
+	switch interval {
+	case IntervalHour:
+		return bucketStart(start.Add(time.Hour), IntervalHour, start.Location())
+	case IntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+type hourCount struct {
+	linkId uint
+	hour   time.Time
+	clicks uint
+	bots   uint
+}
+
+// total is the number of clicks by people, plus the automated ones if
+// includeBots is set.
+func (c hourCount) total(includeBots bool) int64 {
+	if includeBots {
+		return int64(c.clicks + c.bots)
+	}
+	return int64(c.clicks)
+}
+
+// hourlyClicks counts the click events in [from, to) per link and UTC hour,
+// people's and automated ones apart.
+func hourlyClicks(db *gorm.DB, from, to time.Time, scopes ...func(*gorm.DB) *gorm.DB) ([]hourCount, error) {
+	var rows []struct {
+		LinkID uint
+		Hour   string
+		Clicks uint
+		Bots   uint
+	}
+	err := db.Model(&models.ClickEvent{}).Scopes(scopes...).
+		Select("link_id, "+hourExpression(db)+" AS hour, "+
+			"SUM(CASE WHEN bot THEN 0 ELSE 1 END) AS clicks, SUM(CASE WHEN bot THEN 1 ELSE 0 END) AS bots").
+		Where("occurred_at >= ? AND occurred_at < ?", from.UTC(), to.UTC()).
+		Group("link_id, hour").Find(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count clicks: %v", err)
+	}
+
+	counts := make([]hourCount, 0, len(rows))
+	for _, row := range rows {
+		hour, err := time.ParseInLocation("2006-01-02 15:04:05", row.Hour, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse hour %q: %v", row.Hour, err)
+		}
+		counts = append(counts, hourCount{linkId: row.LinkID, hour: hour, clicks: row.Clicks, bots: row.Bots})
+	}
+	return counts, nil
+}
+
+// hourExpression truncates a click's time to its UTC hour as text, which
+// Postgres and SQLite spell differently.
+func hourExpression(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "to_char(date_trunc('hour', occurred_at AT TIME ZONE 'UTC'), 'YYYY-MM-DD HH24:00:00')"
+	}
+	return "strftime('%Y-%m-%d %H:00:00', occurred_at)"
+}
+
+// ClickRollupJob periodically adds up click events per link and UTC hour, so
+// click series do not have to count every event. It also discards the
+// visitor salts of days that are over.
+type ClickRollupJob struct {
+	db       *gorm.DB
+	interval time.Duration
+}
+
+// NewClickRollupJob creates a job running every CLICK_ROLLUP_INTERVAL, five
+// minutes by default.
+func NewClickRollupJob(db *gorm.DB) (*ClickRollupJob, error) {
+	job := &ClickRollupJob{db: db, interval: 5 * time.Minute}
+	if value := os.Getenv("CLICK_ROLLUP_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CLICK_ROLLUP_INTERVAL: %v", err)
+		}
+		job.interval = parsed
+	}
+	return job, nil
+}
+
+// Start rolls up immediately and then every interval until ctx is cancelled.
+func (j *ClickRollupJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := RollupClicks(j.db, time.Now()); err != nil {
+			log.Printf("click rollup failed: %v", err)
+		}
+		if err := DiscardVisitorSalts(j.db, time.Now()); err != nil {
+			log.Printf("visitor salt cleanup failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RollupClicks aggregates the click events of every hour that ended at least
+// a few minutes before now and has not been aggregated yet. The last hour
+// before the cursor is aggregated again to pick up late clicks.
+func RollupClicks(db *gorm.DB, now time.Time) error {
+	until := now.Add(-rollupGrace).UTC().Truncate(time.Hour)
+
+	var cursor models.RollupCursor
+	if err := db.Where("name = ?", clickRollupName).Limit(1).Find(&cursor).Error; err != nil {
+		return fmt.Errorf("failed to load rollup cursor: %v", err)
+	}
+
+	from := cursor.RolledUntil.UTC().Add(-time.Hour)
+	if cursor.RolledUntil.IsZero() {
+		var first models.ClickEvent
+		if err := db.Order("occurred_at").Limit(1).Find(&first).Error; err != nil {
+			return fmt.Errorf("failed to load first click: %v", err)
+		}
+		if first.ID == 0 {
+			from = until
+		} else {
+			from = first.OccurredAt.UTC().Truncate(time.Hour)
+		}
+	}
+
+	for from.Before(until) {
+		end := from.Add(rollupChunk)
+		if end.After(until) {
+			end = until
+		}
+		if err := rollupWindow(db, from, end); err != nil {
+			return err
+		}
+		from = end
+	}
+
+	return nil
+}
+
+// rollupWindow replaces the rollups of [from, to) and moves the cursor to to.
+func rollupWindow(db *gorm.DB, from, to time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		counts, err := hourlyClicks(tx, from, to)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("hour >= ? AND hour < ?", from, to).Delete(&models.ClickRollup{}).Error; err != nil {
+			return fmt.Errorf("failed to delete rollups: %v", err)
+		}
+
+		rollups := make([]models.ClickRollup, 0, len(counts))
+		for _, count := range counts {
+			rollups = append(rollups, models.ClickRollup{LinkID: count.linkId, Hour: count.hour, Clicks: count.clicks, BotClicks: count.bots})
+		}
+		if len(rollups) > 0 {
+			if err := tx.CreateInBatches(&rollups, 500).Error; err != nil {
+				return fmt.Errorf("failed to save rollups: %v", err)
+			}
+		}
+
+		cursor := models.RollupCursor{Name: clickRollupName, RolledUntil: to}
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rolled_until"}),
+		}).Create(&cursor).Error
+		if err != nil {
+			return fmt.Errorf("failed to save rollup cursor: %v", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// GeoLocation is where an IP address is, as far as a geolocation database
+// knows. Fields it does not know are empty.
+type GeoLocation struct {
+	// Country is the ISO 3166-1 country code
+	Country string
+	// Region is the ISO 3166-2 code of the country's subdivision, e.g. DE-BE
+	Region string
+	// City is the English name of the city
+	City string
+}
+
+// GeoLocator looks up where IP addresses are.
+type GeoLocator interface {
+	Locate(ip net.IP) (GeoLocation, error)
+}
+
+// MMDBGeoLocator reads locations from a local MaxMind-format database, such
+// as GeoLite2 Country or City or the DB-IP Lite databases. Lookups never
+// leave the machine.
+type MMDBGeoLocator struct {
+	reader *maxminddb.Reader
+}
+
+// mmdbRecord is the part of a country or city record that is used.
+type mmdbRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// OpenMMDBGeoLocator opens the database file at path.
+func OpenMMDBGeoLocator(path string) (*MMDBGeoLocator, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open geolocation database: %v", err)
+	}
+	return &MMDBGeoLocator{reader: reader}, nil
+}
+
+// Locate looks up ip. Addresses missing from the database have an empty
+// location.
+func (l *MMDBGeoLocator) Locate(ip net.IP) (GeoLocation, error) {
+	var record mmdbRecord
+	if err := l.reader.Lookup(ip, &record); err != nil {
+		return GeoLocation{}, fmt.Errorf("failed to look up location: %v", err)
+	}
+
+	location := GeoLocation{Country: record.Country.ISOCode, City: record.City.Names["en"]}
+	if len(record.Subdivisions) > 0 && record.Subdivisions[0].ISOCode != "" && location.Country != "" {
+		location.Region = location.Country + "-" + record.Subdivisions[0].ISOCode
+	}
+	return location, nil
+}
+
+// Close releases the database file.
+func (l *MMDBGeoLocator) Close() error {
+	return l.reader.Close()
+}
+
+// LoadGeoLocator opens the database at GEOIP_DATABASE_FILE. Without it clicks
+// are located by CDN country headers alone and nil is returned.
+func LoadGeoLocator() (*MMDBGeoLocator, error) {
+	path := os.Getenv("GEOIP_DATABASE_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return OpenMMDBGeoLocator(path)
+}
+
+func newIPHashSalt() ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate IP hash salt: %v", err)
+	}
+	return salt, nil
+}
+
+// hashIP replaces an IP address with a keyed one-way hash, so requests from
+// the same address can be told apart without the address being kept.
+func hashIP(salt []byte, ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ip.To16())
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
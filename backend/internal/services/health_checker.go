@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"linktree-mohamedfadel-backend/internal/models"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HealthCheckerConfig struct {
+	// Interval between full passes over all links
+	Interval time.Duration
+	// Timeout for a single check including redirects
+	Timeout time.Duration
+	// MaxRedirects followed before a check fails
+	MaxRedirects int
+	// Concurrency is how many links are checked at once
+	Concurrency int
+	// PerHostInterval is the minimum gap between requests to the same host
+	PerHostInterval time.Duration
+	// UserAgent sent with every check
+	UserAgent string
+	// AllowPrivateNetworks permits checking loopback, private and link-local
+	// addresses
+	AllowPrivateNetworks bool
+}
+
+func DefaultHealthCheckerConfig() HealthCheckerConfig {
+	return HealthCheckerConfig{
+		Interval:        6 * time.Hour,
+		Timeout:         10 * time.Second,
+		MaxRedirects:    5,
+		Concurrency:     8,
+		PerHostInterval: time.Second,
+		UserAgent:       "LinktreeHealthChecker/1.0",
+	}
+}
+
+// LoadHealthCheckerConfig overrides the defaults with LINK_HEALTH_INTERVAL,
+// LINK_HEALTH_TIMEOUT, LINK_HEALTH_MAX_REDIRECTS, LINK_HEALTH_CONCURRENCY and
+// LINK_HEALTH_PER_HOST_INTERVAL and LINK_HEALTH_ALLOW_PRIVATE_NETWORKS when
+// they are set.
+func LoadHealthCheckerConfig() (HealthCheckerConfig, error) {
+	config := DefaultHealthCheckerConfig()
+
+	durations := map[string]*time.Duration{
+		"LINK_HEALTH_INTERVAL":          &config.Interval,
+		"LINK_HEALTH_TIMEOUT":           &config.Timeout,
+		"LINK_HEALTH_PER_HOST_INTERVAL": &config.PerHostInterval,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	if config.Interval <= 0 {
+		return config, fmt.Errorf("invalid LINK_HEALTH_INTERVAL: must be positive")
+	}
+
+	ints := map[string]*int{
+		"LINK_HEALTH_MAX_REDIRECTS": &config.MaxRedirects,
+		"LINK_HEALTH_CONCURRENCY":   &config.Concurrency,
+	}
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := os.Getenv("LINK_HEALTH_ALLOW_PRIVATE_NETWORKS"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid LINK_HEALTH_ALLOW_PRIVATE_NETWORKS: %v", err)
+		}
+		config.AllowPrivateNetworks = parsed
+	}
+
+	return config, nil
+}
+
+// HealthChecker periodically requests every link URL and records whether it
+// still resolves. Like the metadata fetcher it only connects to public
+// addresses unless the config allows private networks.
+type HealthChecker struct {
+	db     *gorm.DB
+	config HealthCheckerConfig
+	client *http.Client
+	hosts  *hostLimiter
+}
+
+var errTooManyRedirects = errors.New("too many redirects")
+
+func NewHealthChecker(db *gorm.DB, config HealthCheckerConfig) *HealthChecker {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if config.Interval <= 0 {
+		config.Interval = DefaultHealthCheckerConfig().Interval
+	}
+
+	checker := &HealthChecker{
+		db:     db,
+		config: config,
+		hosts:  newHostLimiter(config.PerHostInterval),
+	}
+
+	dialer := publicDialer(config.Timeout, config.AllowPrivateNetworks)
+	checker.client = &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			Proxy:                  nil,
+			DialContext:            dialer.DialContext,
+			TLSHandshakeTimeout:    config.Timeout,
+			ResponseHeaderTimeout:  config.Timeout,
+			MaxResponseHeaderBytes: 64 << 10,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > config.MaxRedirects {
+				return errTooManyRedirects
+			}
+			if err := checkRedirectTarget(req.URL, config.AllowPrivateNetworks); err != nil {
+				return err
+			}
+			return checker.hosts.wait(req.Context(), req.URL.Host)
+		},
+	}
+
+	return checker
+}
+
+// Start runs a check pass immediately and then every Interval until ctx is
+// cancelled.
+func (c *HealthChecker) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := c.CheckAll(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("link health check failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every http and https link, at most Concurrency at a time.
+func (c *HealthChecker) CheckAll(ctx context.Context) error {
+	var links []models.Link
+	if err := c.db.Select("id", "url").Find(&links).Error; err != nil {
+		return fmt.Errorf("failed to load links: %v", err)
+	}
+
+	jobs := make(chan models.Link)
+	errs := make(chan error, len(links))
+
+	var wg sync.WaitGroup
+	for i := 0; i < c.config.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				if _, err := c.CheckLink(ctx, link); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, link := range links {
+		select {
+		case jobs <- link:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	for err := range errs {
+		return err
+	}
+
+	return nil
+}
+
+// CheckLink requests a single link and stores the outcome. Links that are not
+// http or https, such as mailto, are skipped.
+func (c *HealthChecker) CheckLink(ctx context.Context, link models.Link) (models.LinkHealth, error) {
+	parsed, err := url.Parse(link.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return models.LinkHealth{}, nil
+	}
+
+	if err := c.hosts.wait(ctx, parsed.Host); err != nil {
+		return models.LinkHealth{}, err
+	}
+
+	start := time.Now()
+	status, finalURL, checkErr := c.probe(ctx, link.URL)
+
+	health := models.LinkHealth{
+		LinkID:     link.ID,
+		LastStatus: status,
+		LatencyMs:  time.Since(start).Milliseconds(),
+		FinalURL:   finalURL,
+		CheckedAt:  time.Now(),
+		Healthy:    checkErr == nil && status >= 200 && status < 400,
+	}
+
+	if checkErr != nil {
+		if ctx.Err() != nil {
+			return health, ctx.Err()
+		}
+		health.LastError = checkErr.Error()
+	} else if !health.Healthy {
+		health.LastError = http.StatusText(status)
+	}
+
+	var previous models.LinkHealth
+	if err := c.db.Where("link_id = ?", link.ID).First(&previous).Error; err == nil && !health.Healthy {
+		health.FailureStreak = previous.FailureStreak + 1
+	} else if !health.Healthy {
+		health.FailureStreak = 1
+	}
+
+	err = c.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "link_id"}},
+		UpdateAll: true,
+	}).Create(&health).Error
+	if err != nil {
+		return health, fmt.Errorf("failed to save link health: %v", err)
+	}
+
+	return health, nil
+}
+
+// probe sends a HEAD request, retrying with GET when the server rejects HEAD.
+func (c *HealthChecker) probe(ctx context.Context, target string) (int, string, error) {
+	status, finalURL, err := c.request(ctx, http.MethodHead, target)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		return c.request(ctx, http.MethodGet, target)
+	}
+	return status, finalURL, err
+}
+
+func (c *HealthChecker) request(ctx context.Context, method, target string) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && errors.Is(urlErr.Err, errTooManyRedirects) {
+			return 0, urlErr.URL, errTooManyRedirects
+		}
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, resp.Request.URL.String(), nil
+}
+
+// hostLimiter spaces out requests to the same host by a fixed interval.
+type hostLimiter struct {
+	interval time.Duration
+
+	// mu guards the next free slot of each host
+	mu        sync.Mutex
+	next      map[string]time.Time
+	lastSweep time.Time
+}
+
+func newHostLimiter(interval time.Duration) *hostLimiter {
+	return &hostLimiter{interval: interval, next: make(map[string]time.Time)}
+}
+
+// wait blocks until host may be requested again and reserves the slot.
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	// Hosts whose slot has passed would get now anyway, so forgetting them
+	// keeps the map to the hosts requested within the last interval.
+	if now.Sub(l.lastSweep) >= l.interval {
+		for key, next := range l.next {
+			if next.Before(now) {
+				delete(l.next, key)
+			}
+		}
+		l.lastSweep = now
+	}
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
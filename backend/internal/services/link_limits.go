@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LinkLimits caps how many visitors a link lets through and until when.
+// A nil MaxClicks or ExpiresAt leaves that limit off.
+type LinkLimits struct {
+	MaxClicks    *uint
+	ExpiresAt    *time.Time
+	ExpiryAction string
+	FallbackURL  string
+	// ResetClicks starts counting towards MaxClicks from zero again
+	ResetClicks bool
+}
+
+// SetLinkLimits replaces the click cap and expiry date of one of the user's
+// links. The expiry action defaults to showing a sold out page.
+func (s *LinkService) SetLinkLimits(username string, linkId uint64, limits LinkLimits) error {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return err
+	}
+
+	if limits.MaxClicks != nil && *limits.MaxClicks == 0 {
+		limits.MaxClicks = nil
+	}
+
+	if limits.ExpiryAction == "" {
+		limits.ExpiryAction = models.ExpiryActionSoldOut
+	}
+
+	switch limits.ExpiryAction {
+	case models.ExpiryActionHide, models.ExpiryActionSoldOut:
+		limits.FallbackURL = ""
+	case models.ExpiryActionFallback:
+		if limits.FallbackURL == "" {
+			return errors.New("the fallback expiry action needs a fallback URL")
+		}
+		if err := s.urlPolicy.Check(limits.FallbackURL); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown expiry action %q", limits.ExpiryAction)
+	}
+
+	if limits.MaxClicks == nil && limits.ExpiresAt == nil {
+		limits.ExpiryAction = ""
+		limits.FallbackURL = ""
+	}
+
+	link.MaxClicks = limits.MaxClicks
+	link.ExpiresAt = limits.ExpiresAt
+	link.ExpiryAction = limits.ExpiryAction
+	link.FallbackURL = limits.FallbackURL
+	if limits.ResetClicks {
+		link.ClicksUsed = 0
+	}
+
+	err = s.db.Model(&link).
+		Select("max_clicks", "expires_at", "expiry_action", "fallback_url", "clicks_used").
+		Updates(&link).Error
+	if err != nil {
+		return fmt.Errorf("failed to update link: %v", err)
+	}
+
+	return nil
+}
+
+// ClaimClick lets one more visitor through a limited link. The count and the
+// check happen in a single conditional update, so concurrent clicks cannot
+// push a link past its cap. It returns false once the link has expired.
+func (s *LinkService) ClaimClick(link models.Link) (bool, error) {
+	if link.MaxClicks == nil && link.ExpiresAt == nil {
+		return true, nil
+	}
+
+	result := s.db.Model(&models.Link{}).
+		Where("id = ?", link.ID).
+		Where("max_clicks IS NULL OR clicks_used < max_clicks").
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		UpdateColumn("clicks_used", gorm.Expr("clicks_used + 1"))
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim click: %v", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// visibleLinks drops the expired links that are set to disappear.
+func visibleLinks(links []models.Link) []models.Link {
+	visible := links[:0]
+	for _, link := range links {
+		if !link.Hidden() {
+			visible = append(visible, link)
+		}
+	}
+	return visible
+}
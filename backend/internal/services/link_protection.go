@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	minLinkPasswordLength = 4
+	maxUnlockFailures     = 5
+	unlockFailureWindow   = 15 * time.Minute
+	// maxLinkUnlockFailures caps the wrong guesses on one link from all
+	// clients together, for guessers that keep changing address
+	maxLinkUnlockFailures = 20
+)
+
+var (
+	ErrWrongLinkPassword = errors.New("incorrect password")
+	ErrTooManyUnlocks    = errors.New("too many incorrect passwords, try again later")
+)
+
+// SetLinkPassword protects one of the user's links with password, or removes
+// the protection when password is empty.
+func (s *LinkService) SetLinkPassword(username string, linkId uint64, password string) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var hash string
+	if password != "" {
+		if len(password) < minLinkPasswordLength {
+			return fmt.Errorf("password must be at least %d characters", minLinkPasswordLength)
+		}
+
+		hashed, err := HashPassword(password)
+		if err != nil {
+			return err
+		}
+		hash = hashed
+	}
+
+	result := s.db.Model(&models.Link{}).Where("id = ? AND user_id = ?", linkId, user.ID).Update("password_hash", hash)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update link: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("link not found")
+	}
+
+	return nil
+}
+
+// UnlockLink checks password against a protected link. Each client gets a
+// limited number of wrong guesses per link, and the link a limited number
+// from all clients together, before it has to wait; the returned duration
+// says how long.
+func (s *LinkService) UnlockLink(link models.Link, password, client string) (time.Duration, error) {
+	if link.PasswordHash == "" {
+		return 0, nil
+	}
+
+	linkKey := strconv.FormatUint(uint64(link.ID), 10)
+	key := fmt.Sprintf("%d|%s", link.ID, client)
+	if wait := max(s.unlockAttempts.blocked(key), s.linkUnlocks.blocked(linkKey)); wait > 0 {
+		return wait, ErrTooManyUnlocks
+	}
+
+	if err := CheckPassword(link.PasswordHash, password); err != nil {
+		s.unlockAttempts.fail(key)
+		s.linkUnlocks.fail(linkKey)
+		return 0, ErrWrongLinkPassword
+	}
+
+	s.unlockAttempts.reset(key)
+	return 0, nil
+}
+
+// attemptLimiter counts failures per key within a fixed window.
+type attemptLimiter struct {
+	maxFailures int
+	window      time.Duration
+
+	mu       sync.Mutex
+	attempts map[string]*attemptWindow
+}
+
+type attemptWindow struct {
+	failures int
+	resetAt  time.Time
+}
+
+func newAttemptLimiter(maxFailures int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		attempts:    make(map[string]*attemptWindow),
+	}
+}
+
+// blocked returns how long key must wait before trying again, or 0.
+func (l *attemptLimiter) blocked(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempt, ok := l.attempts[key]
+	if !ok {
+		return 0
+	}
+
+	now := time.Now()
+	if now.After(attempt.resetAt) {
+		delete(l.attempts, key)
+		return 0
+	}
+
+	if attempt.failures < l.maxFailures {
+		return 0
+	}
+
+	return attempt.resetAt.Sub(now)
+}
+
+func (l *attemptLimiter) fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	attempt, ok := l.attempts[key]
+	if !ok || now.After(attempt.resetAt) {
+		l.prune(now)
+		attempt = &attemptWindow{resetAt: now.Add(l.window)}
+		l.attempts[key] = attempt
+	}
+	attempt.failures++
+}
+
+func (l *attemptLimiter) reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, key)
+}
+
+// prune drops expired windows once the map gets large so it does not grow
+// without bound. Callers hold the lock.
+func (l *attemptLimiter) prune(now time.Time) {
+	if len(l.attempts) < 1024 {
+		return
+	}
+	for key, attempt := range l.attempts {
+		if now.After(attempt.resetAt) {
+			delete(l.attempts, key)
+		}
+	}
+}
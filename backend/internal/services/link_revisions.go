@@ -0,0 +1,100 @@
+package services
+
+import (
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetLinkRevisions returns the previous versions of one of the user's links,
+// most recently replaced first.
+func (s *LinkService) GetLinkRevisions(username string, linkId uint64) ([]models.LinkRevision, error) {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []models.LinkRevision
+	if err := s.db.Where("link_id = ?", link.ID).Order("valid_to DESC, id DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load revisions: %v", err)
+	}
+
+	return revisions, nil
+}
+
+// RollbackLink restores the title and URL of one of the link's revisions.
+// The version being replaced is kept as a revision itself, so a rollback can
+// be undone.
+func (s *LinkService) RollbackLink(username string, linkId uint64, revisionId uint64) (models.Link, error) {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return link, err
+	}
+
+	var revision models.LinkRevision
+	if err := s.db.Where("id = ? AND link_id = ?", revisionId, link.ID).First(&revision).Error; err != nil {
+		return link, fmt.Errorf("revision not found: %v", err)
+	}
+
+	if revision.Title == link.Title && revision.URL == link.URL {
+		return link, nil
+	}
+
+	if revision.URL != link.URL {
+		if err := s.urlPolicy.Check(revision.URL); err != nil {
+			return link, err
+		}
+
+		var existingLink models.Link
+		if err := s.db.Where("url = ? AND id <> ?", revision.URL, link.ID).First(&existingLink).Error; err == nil {
+			return link, fmt.Errorf("link already exists")
+		}
+	}
+
+	previous := link
+	link.Title = revision.Title
+	link.URL = revision.URL
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := recordRevision(tx, previous, username); err != nil {
+			return err
+		}
+		if err := tx.Model(&link).Select("title", "url").Updates(&link).Error; err != nil {
+			return fmt.Errorf("failed to update link: %v", err)
+		}
+		return nil
+	})
+
+	return link, err
+}
+
+// recordRevision keeps the version of link that is about to be replaced. It
+// went live when the previous revision was replaced, or when the link was
+// created.
+func recordRevision(tx *gorm.DB, link models.Link, actor string) error {
+	validFrom := link.CreatedAt
+
+	var last []models.LinkRevision
+	if err := tx.Where("link_id = ?", link.ID).Order("valid_to DESC").Limit(1).Find(&last).Error; err != nil {
+		return fmt.Errorf("failed to load revisions: %v", err)
+	}
+	if len(last) == 1 {
+		validFrom = last[0].ValidTo
+	}
+
+	revision := models.LinkRevision{
+		LinkID:    link.ID,
+		Title:     link.Title,
+		URL:       link.URL,
+		Actor:     actor,
+		ValidFrom: validFrom,
+		ValidTo:   time.Now(),
+	}
+	if err := tx.Create(&revision).Error; err != nil {
+		return fmt.Errorf("failed to save revision: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"net/url"
+	"time"
+
+	"golang.org/x/text/language"
+)
+
+const maxLinkRules = 20
+
+var knownPlatforms = map[string]bool{
+	models.PlatformIOS:      true,
+	models.PlatformAndroid:  true,
+	models.PlatformWindows:  true,
+	models.PlatformMacOS:    true,
+	models.PlatformLinux:    true,
+	models.PlatformChromeOS: true,
+	models.PlatformOther:    true,
+}
+
+// RuleRequest is the part of a visit that link rules look at.
+type RuleRequest struct {
+	UserAgent      string    `json:"user_agent" example:"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X)"`
+	AcceptLanguage string    `json:"accept_language" example:"de-DE,de;q=0.9,en;q=0.8"`
+	Referrer       string    `json:"referrer" example:"https://www.instagram.com/"`
+	Time           time.Time `json:"time" example:"2024-01-01T12:00:00Z"`
+}
+
+// RuleResult is where a visit ends up. RuleIndex is -1 when no rule matched
+// and the link's own URL is used.
+type RuleResult struct {
+	Target    string `json:"target" example:"https://apps.apple.com/app/id123456789"`
+	RuleIndex int    `json:"rule_index" example:"0"`
+	Platform  string `json:"platform" example:"ios"`
+	Language  string `json:"language" example:"de-DE"`
+}
+
+// SetLinkRules replaces the targeting rules of one of the user's links.
+func (s *LinkService) SetLinkRules(username string, linkId uint64, rules []models.LinkRule) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return fmt.Errorf("link not found: %v", err)
+	}
+
+	if err := s.validateLinkRules(rules); err != nil {
+		return err
+	}
+
+	link.Rules = rules
+	if err := s.db.Model(&link).Select("rules").Updates(&link).Error; err != nil {
+		return fmt.Errorf("failed to update link: %v", err)
+	}
+
+	return nil
+}
+
+// TestLinkRules evaluates the rules of one of the user's links against a
+// simulated visit. When rules is not nil it is validated and used instead of
+// the saved rules, so owners can try rules before saving them.
+func (s *LinkService) TestLinkRules(username string, linkId uint64, rules []models.LinkRule, req RuleRequest) (RuleResult, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return RuleResult{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return RuleResult{}, fmt.Errorf("link not found: %v", err)
+	}
+
+	if rules != nil {
+		if err := s.validateLinkRules(rules); err != nil {
+			return RuleResult{}, err
+		}
+		link.Rules = rules
+	}
+
+	if req.Time.IsZero() {
+		req.Time = time.Now()
+	}
+
+	return s.EvaluateLinkRules(link, req), nil
+}
+
+func (s *LinkService) validateLinkRules(rules []models.LinkRule) error {
+	if len(rules) > maxLinkRules {
+		return fmt.Errorf("a link can have at most %d rules", maxLinkRules)
+	}
+
+	for i, rule := range rules {
+		if err := s.urlPolicy.Check(rule.Target); err != nil {
+			return fmt.Errorf("rule %d: %w", i+1, err)
+		}
+
+		for _, platform := range rule.Platforms {
+			if !knownPlatforms[platform] {
+				return fmt.Errorf("rule %d: unknown platform %q", i+1, platform)
+			}
+		}
+
+		for _, lang := range rule.Languages {
+			if _, err := language.Parse(lang); err != nil {
+				return fmt.Errorf("rule %d: invalid language %q", i+1, lang)
+			}
+		}
+
+		for _, referrer := range rule.Referrers {
+			if _, err := normalizeDomain(referrer); err != nil {
+				return fmt.Errorf("rule %d: invalid referrer domain %q", i+1, referrer)
+			}
+		}
+
+		if (rule.TimeFrom == "") != (rule.TimeTo == "") {
+			return fmt.Errorf("rule %d: time_from and time_to must be set together", i+1)
+		}
+		if rule.TimeFrom != "" {
+			if _, err := parseTimeOfDay(rule.TimeFrom); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+			if _, err := parseTimeOfDay(rule.TimeTo); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+		}
+
+		if rule.Timezone != "" {
+			if _, err := time.LoadLocation(rule.Timezone); err != nil {
+				return fmt.Errorf("rule %d: unknown timezone %q", i+1, rule.Timezone)
+			}
+		}
+	}
+
+	return nil
+}
+
+// EvaluateLinkRules returns the target of the first rule matching req, or the
+// link's URL when none does. The platform comes from the click rules.
+func (s *LinkService) EvaluateLinkRules(link models.Link, req RuleRequest) RuleResult {
+	result := RuleResult{
+		Target:    link.URL,
+		RuleIndex: -1,
+		Platform:  s.parser.Platform(req.UserAgent),
+		Language:  preferredLanguage(req.AcceptLanguage),
+	}
+
+	referrer := referrerHost(req.Referrer)
+	for i, rule := range link.Rules {
+		if ruleMatches(rule, result.Platform, result.Language, referrer, req.Time) {
+			result.Target = rule.Target
+			result.RuleIndex = i
+			break
+		}
+	}
+
+	return result
+}
+
+func ruleMatches(rule models.LinkRule, platform, lang, referrer string, at time.Time) bool {
+	if len(rule.Platforms) > 0 && !containsString(rule.Platforms, platform) {
+		return false
+	}
+
+	if len(rule.Languages) > 0 && !languageMatches(rule.Languages, lang) {
+		return false
+	}
+
+	if len(rule.Referrers) > 0 {
+		if referrer == "" {
+			return false
+		}
+		domains := make(map[string]bool)
+		for _, domain := range rule.Referrers {
+			if normalized, err := normalizeDomain(domain); err == nil {
+				domains[normalized] = true
+			}
+		}
+		if matchingDomain(domains, referrer) == "" {
+			return false
+		}
+	}
+
+	if rule.TimeFrom != "" && !withinTimeOfDay(rule, at) {
+		return false
+	}
+
+	return true
+}
+
+// preferredLanguage returns the highest weighted tag of an Accept-Language
+// header, or "" when there is none.
+func preferredLanguage(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return ""
+	}
+	return tags[0].String()
+}
+
+// languageMatches reports whether lang is one of wanted. A wanted base
+// language like "en" matches every regional variant of it.
+func languageMatches(wanted []string, lang string) bool {
+	if lang == "" {
+		return false
+	}
+
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return false
+	}
+	base, _ := tag.Base()
+
+	for _, w := range wanted {
+		wantedTag, err := language.Parse(w)
+		if err != nil {
+			continue
+		}
+		if wantedTag.String() == tag.String() {
+			return true
+		}
+		if wantedBase, _ := wantedTag.Base(); wantedTag.String() == wantedBase.String() && wantedBase == base {
+			return true
+		}
+	}
+
+	return false
+}
+
+func referrerHost(referrer string) string {
+	parsed, err := url.Parse(referrer)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+
+	host, err := normalizeDomain(parsed.Hostname())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+func withinTimeOfDay(rule models.LinkRule, at time.Time) bool {
+	from, err := parseTimeOfDay(rule.TimeFrom)
+	if err != nil {
+		return false
+	}
+	to, err := parseTimeOfDay(rule.TimeTo)
+	if err != nil {
+		return false
+	}
+
+	location := time.UTC
+	if rule.Timezone != "" {
+		if loaded, err := time.LoadLocation(rule.Timezone); err == nil {
+			location = loaded
+		}
+	}
+
+	local := at.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	if from <= to {
+		return minute >= from && minute < to
+	}
+	// The range wraps past midnight, e.g. 22:00 to 06:00.
+	return minute >= from || minute < to
+}
+
+// parseTimeOfDay turns "HH:MM" into minutes after midnight.
+func parseTimeOfDay(value string) (int, error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
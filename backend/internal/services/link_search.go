@@ -0,0 +1,218 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	maxLinkTags     = 20
+	maxTagLength    = 32
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// LinkQuery selects a page of a user's links. Links must carry every tag in
+// Tags and, when Text is set, match it in their title, URL, description or
+// tags.
+type LinkQuery struct {
+	Text    string
+	Tags    []string
+	Page    int
+	PerPage int
+}
+
+// LinkPage is one page of links with the total number of matches.
+type LinkPage struct {
+	Links   []models.Link `json:"links"`
+	Total   int64         `json:"total" example:"42"`
+	Page    int           `json:"page" example:"1"`
+	PerPage int           `json:"per_page" example:"20"`
+}
+
+// SetLinkTags replaces the tags of one of the user's links. Tags are
+// lowercased and duplicates are dropped.
+func (s *LinkService) SetLinkTags(username string, linkId uint64, tags []string) ([]string, error) {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		return saveLinkTags(tx, link.ID, normalized)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return normalized, nil
+}
+
+// saveLinkTags replaces the tags of a link with names, which must already be
+// normalized.
+func saveLinkTags(tx *gorm.DB, linkId uint, names []string) error {
+	if err := tx.Where("link_id = ?", linkId).Delete(&models.LinkTag{}).Error; err != nil {
+		return fmt.Errorf("failed to delete tags: %v", err)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	rows := make([]models.LinkTag, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, models.LinkTag{LinkID: linkId, Name: name})
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to save tags: %v", err)
+	}
+	return nil
+}
+
+// SearchLinks returns a page of the user's links matching query. Postgres
+// ranks text matches with full-text search; other databases fall back to
+// requiring every word of the text somewhere in the link.
+func (s *LinkService) SearchLinks(username string, query LinkQuery) (LinkPage, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return LinkPage{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	page := LinkPage{Page: query.Page, PerPage: query.PerPage, Links: []models.Link{}}
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.PerPage < 1 {
+		page.PerPage = defaultPageSize
+	}
+	if page.PerPage > maxPageSize {
+		page.PerPage = maxPageSize
+	}
+
+	tags, err := normalizeTags(query.Tags)
+	if err != nil {
+		return page, err
+	}
+
+	text := strings.TrimSpace(query.Text)
+	postgres := s.db.Dialector.Name() == "postgres"
+	matching := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("links.user_id = ?", user.ID)
+		if len(tags) > 0 {
+			tagged := s.db.Model(&models.LinkTag{}).Select("link_id").Where("name IN ?", tags).
+				Group("link_id").Having("COUNT(DISTINCT name) = ?", len(tags))
+			db = db.Where("links.id IN (?)", tagged)
+		}
+
+		if text == "" {
+			return db
+		}
+		if postgres {
+			return db.Where(linkDocument+" @@ websearch_to_tsquery('simple', ?) OR links.url ILIKE ?",
+				text, "%"+escapeLike(text)+"%")
+		}
+		for _, word := range strings.Fields(text) {
+			db = db.Where(linkWordMatch, sql.Named("pattern", "%"+escapeLike(strings.ToLower(word))+"%"))
+		}
+		return db
+	}
+
+	if err := s.db.Model(&models.Link{}).Scopes(matching).Count(&page.Total).Error; err != nil {
+		return page, fmt.Errorf("failed to count links: %v", err)
+	}
+
+	find := s.db.Scopes(matching)
+	if text != "" && postgres {
+		find = find.Order(gorm.Expr("ts_rank("+linkDocument+", websearch_to_tsquery('simple', ?)) DESC", text))
+	}
+	err = find.Order("links.id").Limit(page.PerPage).Offset((page.Page - 1) * page.PerPage).Find(&page.Links).Error
+	if err != nil {
+		return page, fmt.Errorf("failed to load links: %v", err)
+	}
+
+	if err := s.loadLinkTags(page.Links); err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+// linkWordMatch is the portable fallback for full-text search: one word
+// appearing anywhere in the link's title, URL, description or tags.
+const linkWordMatch = `LOWER(links.title) LIKE @pattern ESCAPE '\' OR LOWER(links.url) LIKE @pattern ESCAPE '\' OR ` +
+	`LOWER(links.description) LIKE @pattern ESCAPE '\' OR ` +
+	`links.id IN (SELECT link_id FROM link_tags WHERE name LIKE @pattern ESCAPE '\')`
+
+// linkDocument is the text Postgres full-text search looks at: the link's
+// title, URL, description and tags.
+const linkDocument = `to_tsvector('simple', coalesce(links.title, '') || ' ' || coalesce(links.url, '') || ' ' || ` +
+	`coalesce(links.description, '') || ' ' || ` +
+	`coalesce((SELECT string_agg(link_tags.name, ' ') FROM link_tags WHERE link_tags.link_id = links.id), ''))`
+
+func (s *LinkService) loadLinkTags(links []models.Link) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+
+	var tags []models.LinkTag
+	if err := s.db.Where("link_id IN ?", ids).Order("name").Find(&tags).Error; err != nil {
+		return fmt.Errorf("failed to load tags: %v", err)
+	}
+
+	byLink := make(map[uint][]string)
+	for _, tag := range tags {
+		byLink[tag.LinkID] = append(byLink[tag.LinkID], tag.Name)
+	}
+	for i := range links {
+		links[i].Tags = byLink[links[i].ID]
+	}
+
+	return nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool)
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name := strings.ToLower(strings.TrimSpace(tag))
+		if name == "" || seen[name] {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", name, maxTagLength)
+		}
+		if strings.Contains(name, ",") {
+			return nil, fmt.Errorf("tag %q must not contain commas", name)
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+
+	if len(normalized) > maxLinkTags {
+		return nil, fmt.Errorf("a link can have at most %d tags", maxLinkTags)
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// escapeLike makes the LIKE wildcards in value match literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
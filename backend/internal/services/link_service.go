@@ -1,29 +1,65 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"linktree-mohamedfadel-backend/internal/models"
-	"net/url"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type LinkService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	metadata       *MetadataFetcher
+	urlPolicy      *URLPolicy
+	parser         *ClickParser
+	unlockAttempts *attemptLimiter
+	linkUnlocks    *attemptLimiter
 }
 
 func NewLinkService(db *gorm.DB) *LinkService {
-	return &LinkService{db: db}
+	return &LinkService{
+		db:             db,
+		metadata:       NewMetadataFetcher(DefaultMetadataFetcherConfig()),
+		urlPolicy:      NewURLPolicy(),
+		parser:         DefaultClickParser(),
+		unlockAttempts: newAttemptLimiter(maxUnlockFailures, unlockFailureWindow),
+		linkUnlocks:    newAttemptLimiter(maxLinkUnlockFailures, unlockFailureWindow),
+	}
 }
 
-func (s *LinkService) CreateLink(username string, link models.Link) error {
-	if link.Title == "" || link.URL == "" {
-		return errors.New("required fields are missing")
+// SetURLPolicy replaces the policy that link URLs are checked against.
+func (s *LinkService) SetURLPolicy(policy *URLPolicy) {
+	s.urlPolicy = policy
+}
+
+// SetClickParser replaces the built-in click rules that link rules read
+// visitors' platforms with.
+func (s *LinkService) SetClickParser(parser *ClickParser) {
+	s.parser = parser
+}
+
+// SetMetadataFetcher replaces the fetcher used to fill in link previews.
+func (s *LinkService) SetMetadataFetcher(fetcher *MetadataFetcher) {
+	s.metadata = fetcher
+}
+
+// CreateLink adds a link to the user's profile. A link without a title gets
+// the title and preview of the linked page, fetched within ctx.
+func (s *LinkService) CreateLink(ctx context.Context, username string, link models.Link) error {
+	if err := s.validateNewLink(link); err != nil {
+		return err
 	}
 
-	if _, err := url.ParseRequestURI(link.URL); err != nil {
-		return fmt.Errorf("invalid url")
+	if link.Title == "" {
+		if err := s.FillLinkMetadata(ctx, &link); err != nil {
+			return fmt.Errorf("title is missing and could not be fetched: %w", err)
+		}
+		if link.Title == "" {
+			return errors.New("title is missing and the page has none")
+		}
 	}
 
 	var user models.User
@@ -36,13 +72,69 @@ func (s *LinkService) CreateLink(username string, link models.Link) error {
 		return fmt.Errorf("link already exists")
 	}
 
+	if link.SectionID != nil {
+		if err := s.checkSectionOwner(*link.SectionID, user.ID); err != nil {
+			return err
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		_, err := insertLink(tx, user.ID, link)
+		return err
+	})
+}
+
+func (s *LinkService) validateNewLink(link models.Link) error {
+	if link.URL == "" {
+		return errors.New("required fields are missing")
+	}
+
+	return s.urlPolicy.Check(link.URL)
+}
+
+// CheckRedirectURL applies the current URL policy to a destination a visitor
+// is about to be sent to. URLs are also checked when saved, but links saved
+// before a domain was blocked only stop redirecting through this check.
+func (s *LinkService) CheckRedirectURL(rawURL string) error {
+	return s.urlPolicy.Check(rawURL)
+}
+
+// insertLink appends link to the end of its section (or the ungrouped links),
+// assigns its slug and creates its link block. Callers validate the link and
+// section ownership beforehand.
+func insertLink(tx *gorm.DB, userId uint, link models.Link) (models.Link, error) {
+	position, err := nextLinkPosition(tx, userId, link.SectionID)
+	if err != nil {
+		return models.Link{}, err
+	}
+
+	slug, err := resolveSlug(tx, link.Slug, 0)
+	if err != nil {
+		return models.Link{}, err
+	}
+
 	newLink := models.Link{
-		Title:  link.Title,
-		URL:    link.URL,
-		UserID: user.ID,
+		Title:       link.Title,
+		URL:         link.URL,
+		Description: link.Description,
+		ImageURL:    link.ImageURL,
+		FaviconURL:  link.FaviconURL,
+		Slug:        slug,
+		UserID:      userId,
+		SectionID:   link.SectionID,
+		Position:    position,
+		Sensitive:   link.Sensitive,
+	}
+
+	if err := tx.Create(&newLink).Error; err != nil {
+		return models.Link{}, err
+	}
+
+	if err := CreateLinkBlock(tx, newLink); err != nil {
+		return models.Link{}, err
 	}
 
-	return s.db.Create(&newLink).Error
+	return newLink, nil
 }
 
 func (s *LinkService) UpdateLink(username string, linkId uint64, updatedLink models.Link) error {
@@ -56,27 +148,158 @@ func (s *LinkService) UpdateLink(username string, linkId uint64, updatedLink mod
 		return fmt.Errorf("link not found: %v", err)
 	}
 
+	previous := link
 	if updatedLink.Title != "" {
 		link.Title = updatedLink.Title
 	}
 
 	if updatedLink.URL != "" {
-		if _, err := url.ParseRequestURI(updatedLink.URL); err != nil {
-			return fmt.Errorf("invalid url")
+		if err := s.urlPolicy.Check(updatedLink.URL); err != nil {
+			return err
 		}
 		link.URL = updatedLink.URL
 	}
 
-	return s.db.Save(&link).Error
+	// A section_id of 0 moves the link out of its section.
+	if updatedLink.SectionID != nil {
+		var sectionId *uint
+		if *updatedLink.SectionID != 0 {
+			if err := s.checkSectionOwner(*updatedLink.SectionID, user.ID); err != nil {
+				return err
+			}
+			sectionId = updatedLink.SectionID
+		}
+
+		if !sameSection(link.SectionID, sectionId) {
+			position, err := nextLinkPosition(s.db, user.ID, sectionId)
+			if err != nil {
+				return err
+			}
+			link.SectionID = sectionId
+			link.Position = position
+		}
+	}
+
+	oldSlug := link.Slug
+	if updatedLink.Slug != "" && updatedLink.Slug != link.Slug {
+		slug, err := resolveSlug(s.db, updatedLink.Slug, link.ID)
+		if err != nil {
+			return err
+		}
+		link.Slug = slug
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if link.Slug != oldSlug {
+			if err := retireSlug(tx, link.ID, oldSlug, link.Slug); err != nil {
+				return err
+			}
+		}
+		if link.Title != previous.Title || link.URL != previous.URL {
+			if err := recordRevision(tx, previous, username); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&link).Error
+	})
 }
 
-func (s *LinkService) DeleteLink(username string, linkId uint64) error {
+// FillLinkMetadata fetches the page behind link.URL and sets its preview
+// description, image and favicon, and its title when link has none. URLs the
+// URL policy rejects are never fetched.
+func (s *LinkService) FillLinkMetadata(ctx context.Context, link *models.Link) error {
+	if err := s.urlPolicy.Check(link.URL); err != nil {
+		return err
+	}
+
+	metadata, err := s.metadata.Fetch(ctx, link.URL)
+	if err != nil {
+		return err
+	}
+
+	applyLinkMetadata(link, metadata)
+	return nil
+}
+
+// RefreshLinkMetadata re-fetches the preview of one of the user's links,
+// bypassing the cache. The title is only replaced when it is empty.
+func (s *LinkService) RefreshLinkMetadata(ctx context.Context, username string, linkId uint64) (models.Link, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return models.Link{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return link, fmt.Errorf("link not found: %v", err)
+	}
+
+	if err := s.urlPolicy.Check(link.URL); err != nil {
+		return link, err
+	}
+
+	metadata, err := s.metadata.Refresh(ctx, link.URL)
+	if err != nil {
+		return link, fmt.Errorf("failed to fetch metadata: %v", err)
+	}
+
+	applyLinkMetadata(&link, metadata)
+	err = s.db.Model(&link).Select("title", "description", "image_url", "favicon_url").Updates(&link).Error
+	if err != nil {
+		return link, fmt.Errorf("failed to update link: %v", err)
+	}
+
+	return link, nil
+}
+
+func applyLinkMetadata(link *models.Link, metadata LinkMetadata) {
+	if link.Title == "" {
+		link.Title = metadata.Title
+	}
+	link.Description = metadata.Description
+	link.ImageURL = metadata.ImageURL
+	link.FaviconURL = metadata.FaviconURL
+}
+
+func (s *LinkService) GetLink(linkId uint64) (models.Link, error) {
+	var link models.Link
+	if err := s.db.Where("id = ?", linkId).First(&link).Error; err != nil {
+		return link, fmt.Errorf("link not found: %v", err)
+	}
+
+	return link, nil
+}
+
+// GetLinkBySlug resolves a slug to its link, falling back to slugs the link
+// used before that are still within their grace period.
+func (s *LinkService) GetLinkBySlug(slug string) (models.Link, error) {
+	var link models.Link
+	err := s.db.Where("slug = ?", slug).First(&link).Error
+	if err == nil {
+		return link, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return link, fmt.Errorf("failed to look up slug: %v", err)
+	}
+
+	var alias models.SlugAlias
+	if err := s.db.Where("slug = ? AND expires_at > ?", slug, time.Now()).First(&alias).Error; err != nil {
+		return link, fmt.Errorf("link not found: %v", err)
+	}
+
+	return s.GetLink(uint64(alias.LinkID))
+}
+
+func (s *LinkService) SetLinkSensitive(username string, linkId uint64, sensitive bool) error {
 	var user models.User
 	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
 		return fmt.Errorf("user not found: %v", err)
 	}
 
-	result := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).Delete(&models.Link{})
+	result := s.db.Model(&models.Link{}).Where("id = ? AND user_id = ?", linkId, user.ID).Update("sensitive", sensitive)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update link: %v", result.Error)
+	}
 
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("link not found")
@@ -84,3 +307,82 @@ func (s *LinkService) DeleteLink(username string, linkId uint64) error {
 
 	return nil
 }
+
+// GetLinksHealth returns the user's links with their latest check result.
+// With brokenOnly only links whose last check failed are returned.
+func (s *LinkService) GetLinksHealth(username string, brokenOnly bool) ([]models.Link, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %v", err)
+	}
+
+	query := s.db.Preload("Health").Where("links.user_id = ?", user.ID)
+	if brokenOnly {
+		query = query.Joins("JOIN link_healths ON link_healths.link_id = links.id").
+			Where("link_healths.healthy = ?", false)
+	}
+
+	var links []models.Link
+	if err := query.Order("links.id").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to load links: %v", err)
+	}
+
+	return links, nil
+}
+
+func (s *LinkService) DeleteLink(username string, linkId uint64) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", linkId, user.ID).Delete(&models.Link{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete link: %v", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("link not found")
+		}
+
+		return deleteLinkDependents(tx, []uint64{linkId})
+	})
+}
+
+// deleteLinkDependents removes the rows hanging off deleted links so they do
+// not rely on database cascades, which SQLite leaves disabled.
+func deleteLinkDependents(tx *gorm.DB, linkIds interface{}) error {
+	for _, dependent := range []interface{}{&models.Analytics{}, &models.Block{}, &models.SlugAlias{}, &models.LinkHealth{}, &models.LinkVariant{}, &models.LinkTag{}, &models.LinkRevision{}, &models.ClickEvent{}, &models.ClickRollup{}} {
+		if err := tx.Where("link_id IN ?", linkIds).Delete(dependent).Error; err != nil {
+			return fmt.Errorf("failed to delete link data: %v", err)
+		}
+	}
+	return nil
+}
+
+func (s *LinkService) checkSectionOwner(sectionId uint, userId uint) error {
+	var section models.Section
+	if err := s.db.Where("id = ? AND user_id = ?", sectionId, userId).First(&section).Error; err != nil {
+		return fmt.Errorf("section not found: %v", err)
+	}
+	return nil
+}
+
+func nextLinkPosition(db *gorm.DB, userId uint, sectionId *uint) (int, error) {
+	query := db.Model(&models.Link{}).Where("user_id = ?", userId)
+	if sectionId == nil {
+		query = query.Where("section_id IS NULL")
+	} else {
+		query = query.Where("section_id = ?", *sectionId)
+	}
+
+	return nextPosition(query)
+}
+
+func sameSection(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,508 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"linktree-mohamedfadel-backend/internal/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gorm.io/gorm"
+)
+
+const (
+	ImportStatusOK        = "ok"
+	ImportStatusDuplicate = "duplicate"
+	ImportStatusInvalid   = "invalid"
+)
+
+var linkRecordCSVHeader = []string{"title", "url", "description", "image_url", "slug", "section", "tags", "position", "sensitive", "created_at"}
+
+// LinkRecord is the portable form of a link used for import and export
+type LinkRecord struct {
+	Title       string     `json:"title" example:"My GitHub"`
+	URL         string     `json:"url" example:"https://github.com/johndoe"`
+	Description string     `json:"description,omitempty" example:"Open source projects by John Doe"`
+	ImageURL    string     `json:"image_url,omitempty" example:"https://github.com/johndoe.png"`
+	Slug        string     `json:"slug,omitempty" example:"johndoe-github"`
+	Section     string     `json:"section,omitempty" example:"Code"`
+	Tags        []string   `json:"tags,omitempty" example:"code,personal"`
+	Position    int        `json:"position" example:"0"`
+	Sensitive   bool       `json:"sensitive" example:"false"`
+	CreatedAt   *time.Time `json:"created_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
+
+// ImportRowResult reports what happened, or would happen, to one imported row
+type ImportRowResult struct {
+	Row    int    `json:"row" example:"1"`
+	Title  string `json:"title" example:"My GitHub"`
+	URL    string `json:"url" example:"https://github.com/johndoe"`
+	Status string `json:"status" example:"ok" enums:"ok,duplicate,invalid"`
+	Error  string `json:"error,omitempty" example:"invalid url"`
+}
+
+// ImportReport summarizes an import. Rows are numbered from 1 in file order.
+type ImportReport struct {
+	DryRun     bool              `json:"dry_run" example:"true"`
+	Valid      int               `json:"valid" example:"10"`
+	Duplicates int               `json:"duplicates" example:"1"`
+	Invalid    int               `json:"invalid" example:"0"`
+	Imported   int               `json:"imported" example:"0"`
+	Rows       []ImportRowResult `json:"rows"`
+}
+
+var ErrImportHasInvalidRows = errors.New("import contains invalid rows")
+
+var errDuplicateLink = errors.New("link already exists")
+
+// ExportLinks returns the user's links in profile order: ungrouped links
+// first, then each section's links.
+func (s *LinkService) ExportLinks(username string) ([]LinkRecord, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %v", err)
+	}
+
+	var ungrouped []models.Link
+	if err := s.db.Where("user_id = ? AND section_id IS NULL", user.ID).Order("position, id").Find(&ungrouped).Error; err != nil {
+		return nil, fmt.Errorf("failed to load links: %v", err)
+	}
+
+	var sections []models.Section
+	err := s.db.Preload("Links", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Where("user_id = ?", user.ID).Order("position, id").Find(&sections).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sections: %v", err)
+	}
+
+	if err := s.loadLinkTags(ungrouped); err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if err := s.loadLinkTags(section.Links); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]LinkRecord, 0, len(ungrouped))
+	for _, link := range ungrouped {
+		records = append(records, linkToRecord(link, ""))
+	}
+	for _, section := range sections {
+		for _, link := range section.Links {
+			records = append(records, linkToRecord(link, section.Title))
+		}
+	}
+
+	return records, nil
+}
+
+// ImportLinks validates records and, unless dryRun is set, creates them in a
+// single transaction. Duplicates of existing links or of earlier rows are
+// skipped; any invalid row aborts the whole import with
+// ErrImportHasInvalidRows. Sections are matched by title and created when
+// missing. Imported links go after the links their section already has,
+// ordered by position; rows with the same position keep their file order.
+func (s *LinkService) ImportLinks(username string, records []LinkRecord, dryRun bool) (ImportReport, error) {
+	report := ImportReport{DryRun: dryRun, Rows: make([]ImportRowResult, 0, len(records))}
+
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return report, fmt.Errorf("user not found: %v", err)
+	}
+
+	seenURLs := make(map[string]bool, len(records))
+	seenSlugs := make(map[string]bool, len(records))
+	var accepted []LinkRecord
+
+	for i, record := range records {
+		record.Title = strings.TrimSpace(record.Title)
+		record.URL = strings.TrimSpace(record.URL)
+		record.Slug = strings.ToLower(strings.TrimSpace(record.Slug))
+		record.Section = strings.TrimSpace(record.Section)
+		record.Description = strings.TrimSpace(record.Description)
+		record.ImageURL = strings.TrimSpace(record.ImageURL)
+
+		result := ImportRowResult{Row: i + 1, Title: record.Title, URL: record.URL, Status: ImportStatusOK}
+
+		err := s.checkImportRecord(record, seenURLs, seenSlugs)
+		if err == nil {
+			record.Tags, err = normalizeTags(record.Tags)
+		}
+		if err != nil {
+			if errors.Is(err, errDuplicateLink) {
+				result.Status = ImportStatusDuplicate
+				report.Duplicates++
+			} else {
+				result.Status = ImportStatusInvalid
+				report.Invalid++
+			}
+			result.Error = err.Error()
+		} else {
+			report.Valid++
+			accepted = append(accepted, record)
+		}
+
+		seenURLs[record.URL] = true
+		if record.Slug != "" {
+			seenSlugs[record.Slug] = true
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	if report.Invalid > 0 {
+		return report, ErrImportHasInvalidRows
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Sections are created in file order. Links are then appended to
+		// their section one by one, so inserting them by position keeps the
+		// imported order within each section.
+		sectionIds := make(map[string]*uint)
+		for _, record := range accepted {
+			if _, err := importSection(tx, user.ID, record.Section, sectionIds); err != nil {
+				return err
+			}
+		}
+		sort.SliceStable(accepted, func(i, j int) bool {
+			return accepted[i].Position < accepted[j].Position
+		})
+
+		for _, record := range accepted {
+			sectionId, err := importSection(tx, user.ID, record.Section, sectionIds)
+			if err != nil {
+				return err
+			}
+
+			link := models.Link{
+				Title:       record.Title,
+				URL:         record.URL,
+				Description: record.Description,
+				ImageURL:    record.ImageURL,
+				Slug:        record.Slug,
+				SectionID:   sectionId,
+				Sensitive:   record.Sensitive,
+			}
+			created, err := insertLink(tx, user.ID, link)
+			if err != nil {
+				return fmt.Errorf("failed to import %s: %v", record.URL, err)
+			}
+			if err := saveLinkTags(tx, created.ID, record.Tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	report.Imported = len(accepted)
+	return report, nil
+}
+
+func (s *LinkService) checkImportRecord(record LinkRecord, seenURLs, seenSlugs map[string]bool) error {
+	if record.Title == "" {
+		return errors.New("required fields are missing")
+	}
+
+	if err := s.validateNewLink(models.Link{Title: record.Title, URL: record.URL}); err != nil {
+		return err
+	}
+
+	// Preview images are shown on the profile, so they get the same policy
+	// as block images.
+	if record.ImageURL != "" {
+		if err := s.urlPolicy.Check(record.ImageURL); err != nil {
+			return fmt.Errorf("image_url: %w", err)
+		}
+		if scheme := strings.ToLower(strings.SplitN(record.ImageURL, ":", 2)[0]); scheme != "http" && scheme != "https" {
+			return errors.New("image_url must be an http or https URL")
+		}
+	}
+
+	if seenURLs[record.URL] {
+		return fmt.Errorf("%w earlier in the import", errDuplicateLink)
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Link{}).Where("url = ?", record.URL).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check url: %v", err)
+	}
+	if count > 0 {
+		return errDuplicateLink
+	}
+
+	if record.Slug != "" {
+		if err := ValidateSlug(record.Slug); err != nil {
+			return err
+		}
+		if seenSlugs[record.Slug] {
+			return fmt.Errorf("slug %q is used earlier in the import", record.Slug)
+		}
+		available, err := slugAvailable(s.db, record.Slug, 0)
+		if err != nil {
+			return err
+		}
+		if !available {
+			return ErrSlugTaken
+		}
+	}
+
+	return nil
+}
+
+func importSection(tx *gorm.DB, userId uint, title string, cache map[string]*uint) (*uint, error) {
+	if title == "" {
+		return nil, nil
+	}
+	if id, ok := cache[title]; ok {
+		return id, nil
+	}
+
+	var section models.Section
+	err := tx.Where("user_id = ? AND title = ?", userId, title).Order("position, id").First(&section).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		position, err := nextPosition(tx.Model(&models.Section{}).Where("user_id = ?", userId))
+		if err != nil {
+			return nil, err
+		}
+		section = models.Section{Title: title, Position: position, UserID: userId}
+		if err := tx.Create(&section).Error; err != nil {
+			return nil, fmt.Errorf("failed to create section: %v", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load section: %v", err)
+	}
+
+	cache[title] = &section.ID
+	return &section.ID, nil
+}
+
+func linkToRecord(link models.Link, section string) LinkRecord {
+	createdAt := link.CreatedAt
+	return LinkRecord{
+		Title:       link.Title,
+		URL:         link.URL,
+		Description: link.Description,
+		ImageURL:    link.ImageURL,
+		Slug:        link.Slug,
+		Section:     section,
+		Tags:        link.Tags,
+		Position:    link.Position,
+		Sensitive:   link.Sensitive,
+		CreatedAt:   &createdAt,
+	}
+}
+
+// ParseLinkRecordsJSON reads a JSON array of link records
+func ParseLinkRecordsJSON(r io.Reader) ([]LinkRecord, error) {
+	var records []LinkRecord
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return records, nil
+}
+
+// ParseLinkRecordsCSV reads link records from CSV with a header row. The
+// title and url columns are required; the other export columns are optional
+// and unknown columns are ignored. Tags are separated by commas within their
+// cell. Cells escaped against formula injection on export are read back as
+// they were.
+func ParseLinkRecordsCSV(r io.Reader) ([]LinkRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV header: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, errors.New("CSV header must include a title column")
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, errors.New("CSV header must include a url column")
+	}
+
+	field := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+
+	var records []LinkRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+
+		record := LinkRecord{
+			Title:       unescapeFormula(field(row, "title")),
+			URL:         unescapeFormula(field(row, "url")),
+			Description: unescapeFormula(field(row, "description")),
+			ImageURL:    unescapeFormula(field(row, "image_url")),
+			Slug:        unescapeFormula(field(row, "slug")),
+			Section:     unescapeFormula(field(row, "section")),
+		}
+		if tags := unescapeFormula(field(row, "tags")); tags != "" {
+			record.Tags = strings.Split(tags, ",")
+		}
+		record.Position, _ = strconv.Atoi(field(row, "position"))
+		record.Sensitive, _ = strconv.ParseBool(field(row, "sensitive"))
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// ParseBookmarksHTML reads links from a Netscape bookmarks file as exported
+// by browsers. The innermost folder of each bookmark becomes its section.
+func ParseBookmarksHTML(r io.Reader) ([]LinkRecord, error) {
+	tokenizer := html.NewTokenizer(r)
+
+	var records []LinkRecord
+	var folders []string
+	var pendingFolder string
+	var current *LinkRecord
+	var text strings.Builder
+	inFolderTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return nil, fmt.Errorf("invalid bookmarks file: %v", err)
+			}
+			return records, nil
+		case html.StartTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.H3:
+				inFolderTitle = true
+				text.Reset()
+			case atom.Dl:
+				folders = append(folders, pendingFolder)
+				pendingFolder = ""
+			case atom.A:
+				current = &LinkRecord{}
+				for _, attr := range token.Attr {
+					if strings.EqualFold(attr.Key, "href") {
+						current.URL = attr.Val
+					}
+				}
+				text.Reset()
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.H3:
+				inFolderTitle = false
+				pendingFolder = strings.TrimSpace(text.String())
+			case atom.Dl:
+				if len(folders) > 0 {
+					folders = folders[:len(folders)-1]
+				}
+			case atom.A:
+				if current != nil {
+					current.Title = strings.TrimSpace(text.String())
+					if current.Title == "" {
+						current.Title = current.URL
+					}
+					current.Section = innermostFolder(folders)
+					records = append(records, *current)
+					current = nil
+				}
+			}
+		case html.TextToken:
+			if inFolderTitle || current != nil {
+				text.Write(tokenizer.Text())
+			}
+		}
+	}
+}
+
+func innermostFolder(folders []string) string {
+	for i := len(folders) - 1; i >= 0; i-- {
+		if folders[i] != "" {
+			return folders[i]
+		}
+	}
+	return ""
+}
+
+// WriteLinkRecordsCSV writes records as CSV with a header row. Text cells
+// that a spreadsheet would run as a formula are escaped.
+func WriteLinkRecordsCSV(w io.Writer, records []LinkRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(linkRecordCSVHeader); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		createdAt := ""
+		if record.CreatedAt != nil {
+			createdAt = record.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		row := []string{
+			escapeFormula(record.Title),
+			escapeFormula(record.URL),
+			escapeFormula(record.Description),
+			escapeFormula(record.ImageURL),
+			escapeFormula(record.Slug),
+			escapeFormula(record.Section),
+			escapeFormula(strings.Join(record.Tags, ",")),
+			strconv.Itoa(record.Position),
+			strconv.FormatBool(record.Sensitive),
+			createdAt,
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formulaPrefixes start cells that spreadsheets evaluate as formulas.
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula prefixes a cell that a spreadsheet would run as a formula
+// with a single quote, which makes it text.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaPrefixes, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeFormula undoes escapeFormula.
+func unescapeFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
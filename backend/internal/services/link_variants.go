@@ -0,0 +1,236 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"linktree-mohamedfadel-backend/internal/models"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+const maxLinkVariants = 10
+
+// AddLinkVariant starts or extends an A/B test on one of the user's links.
+// A variant needs a title, a URL or both; a weight of 0 counts as 1.
+func (s *LinkService) AddLinkVariant(username string, linkId uint64, variant models.LinkVariant) (models.LinkVariant, error) {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return models.LinkVariant{}, err
+	}
+
+	if variant.Title == "" && variant.URL == "" {
+		return models.LinkVariant{}, errors.New("a variant needs a title or a URL")
+	}
+
+	if variant.URL != "" {
+		if err := s.urlPolicy.Check(variant.URL); err != nil {
+			return models.LinkVariant{}, err
+		}
+	}
+
+	var count int64
+	if err := s.db.Model(&models.LinkVariant{}).Where("link_id = ?", link.ID).Count(&count).Error; err != nil {
+		return models.LinkVariant{}, fmt.Errorf("failed to count variants: %v", err)
+	}
+	if count >= maxLinkVariants {
+		return models.LinkVariant{}, fmt.Errorf("a link can have at most %d variants", maxLinkVariants)
+	}
+
+	newVariant := models.LinkVariant{
+		LinkID: link.ID,
+		Title:  variant.Title,
+		URL:    variant.URL,
+		Weight: variant.Weight,
+	}
+	if newVariant.Weight == 0 {
+		newVariant.Weight = 1
+	}
+
+	if err := s.db.Create(&newVariant).Error; err != nil {
+		return models.LinkVariant{}, fmt.Errorf("failed to create variant: %v", err)
+	}
+
+	return newVariant, nil
+}
+
+// DeleteLinkVariant removes a variant, and its statistics, from a test.
+func (s *LinkService) DeleteLinkVariant(username string, linkId uint64, variantId uint64) error {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return err
+	}
+
+	result := s.db.Where("id = ? AND link_id = ?", variantId, link.ID).Delete(&models.LinkVariant{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete variant: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("variant not found")
+	}
+
+	return nil
+}
+
+// PromoteLinkVariant ends the test on a link by copying the winning variant's
+// title and URL onto the link and removing all of its variants.
+func (s *LinkService) PromoteLinkVariant(username string, linkId uint64, variantId uint64) (models.Link, error) {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return link, err
+	}
+
+	var variant models.LinkVariant
+	if err := s.db.Where("id = ? AND link_id = ?", variantId, link.ID).First(&variant).Error; err != nil {
+		return link, fmt.Errorf("variant not found: %v", err)
+	}
+
+	previous := link
+	if variant.Title != "" {
+		link.Title = variant.Title
+	}
+
+	if variant.URL != "" && variant.URL != link.URL {
+		var existingLink models.Link
+		if err := s.db.Where("url = ?", variant.URL).First(&existingLink).Error; err == nil {
+			return link, fmt.Errorf("link already exists")
+		}
+		link.URL = variant.URL
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if link.Title != previous.Title || link.URL != previous.URL {
+			if err := recordRevision(tx, previous, username); err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&link).Select("title", "url").Updates(&link).Error; err != nil {
+			return fmt.Errorf("failed to update link: %v", err)
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&models.LinkVariant{}).Error; err != nil {
+			return fmt.Errorf("failed to delete variants: %v", err)
+		}
+		return nil
+	})
+
+	return link, err
+}
+
+// ResolveLinkVariant returns the variant a click on link belongs to: the one
+// named by requested if it is one of the link's variants, otherwise the one
+// the visitor is assigned to. It returns nil when the link is not under test.
+func (s *LinkService) ResolveLinkVariant(link models.Link, requested string, visitorId string) (*models.LinkVariant, error) {
+	var variants []models.LinkVariant
+	if err := s.db.Where("link_id = ?", link.ID).Order("id").Find(&variants).Error; err != nil {
+		return nil, fmt.Errorf("failed to load variants: %v", err)
+	}
+
+	if id, err := strconv.ParseUint(requested, 10, 64); err == nil {
+		for i := range variants {
+			if uint64(variants[i].ID) == id {
+				return &variants[i], nil
+			}
+		}
+	}
+
+	return PickLinkVariant(variants, link.ID, visitorId), nil
+}
+
+// PickLinkVariant assigns a visitor to one of a link's variants in proportion
+// to their weights. The choice only depends on the visitor and the link, so
+// a returning visitor keeps seeing the same variant.
+func PickLinkVariant(variants []models.LinkVariant, linkId uint, visitorId string) *models.LinkVariant {
+	var total uint64
+	for _, variant := range variants {
+		total += uint64(variant.Weight)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	hash := fnv.New64a()
+	fmt.Fprintf(hash, "%d|%s", linkId, visitorId)
+	point := hash.Sum64() % total
+
+	for i := range variants {
+		if point < uint64(variants[i].Weight) {
+			return &variants[i]
+		}
+		point -= uint64(variants[i].Weight)
+	}
+
+	return nil
+}
+
+// applyLinkVariants shows each link under test as the visitor's variant,
+// pointing its tracked URL at that variant so clicks are attributed to it,
+// and counts the impressions.
+func applyLinkVariants(db *gorm.DB, links []*models.Link, visitorId string) error {
+	if len(links) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(links))
+	for _, link := range links {
+		ids = append(ids, link.ID)
+	}
+
+	var variants []models.LinkVariant
+	if err := db.Where("link_id IN ?", ids).Order("id").Find(&variants).Error; err != nil {
+		return fmt.Errorf("failed to load variants: %v", err)
+	}
+	if len(variants) == 0 {
+		return nil
+	}
+
+	byLink := make(map[uint][]models.LinkVariant)
+	for _, variant := range variants {
+		byLink[variant.LinkID] = append(byLink[variant.LinkID], variant)
+	}
+
+	var shown []uint
+	for _, link := range links {
+		variant := PickLinkVariant(byLink[link.ID], link.ID, visitorId)
+		if variant == nil {
+			continue
+		}
+
+		if variant.Title != "" {
+			link.Title = variant.Title
+		}
+		if variant.URL != "" {
+			link.URL = variant.URL
+		}
+		link.VariantID = &variant.ID
+		link.TrackedURL = fmt.Sprintf("%s?v=%d", link.TrackedURL, variant.ID)
+		shown = append(shown, variant.ID)
+	}
+
+	if len(shown) == 0 {
+		return nil
+	}
+
+	err := db.Model(&models.LinkVariant{}).Where("id IN ?", shown).
+		UpdateColumn("impressions", gorm.Expr("impressions + 1")).Error
+	if err != nil {
+		return fmt.Errorf("failed to track impressions: %v", err)
+	}
+
+	return nil
+}
+
+func (s *LinkService) ownedLink(username string, linkId uint64) (models.Link, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return models.Link{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	var link models.Link
+	if err := s.db.Where("id = ? AND user_id = ?", linkId, user.ID).First(&link).Error; err != nil {
+		return link, fmt.Errorf("link not found: %v", err)
+	}
+
+	return link, nil
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// LinkMetadata is the preview information extracted from a page.
+type LinkMetadata struct {
+	Title       string `json:"title" example:"johndoe - Overview"`
+	Description string `json:"description" example:"Open source projects by John Doe"`
+	ImageURL    string `json:"image_url" example:"https://github.com/johndoe.png"`
+	FaviconURL  string `json:"favicon_url" example:"https://github.com/favicon.ico"`
+}
+
+type MetadataFetcherConfig struct {
+	// Timeout for fetching a page including redirects
+	Timeout time.Duration
+	// MaxBytes of the page body that is read and parsed
+	MaxBytes int64
+	// MaxRedirects followed before a fetch fails
+	MaxRedirects int
+	// CacheTTL is how long fetched metadata is reused for the same URL
+	CacheTTL time.Duration
+	// CacheSize is the maximum number of cached URLs
+	CacheSize int
+	// AllowPrivateNetworks permits loopback, private and link-local addresses
+	AllowPrivateNetworks bool
+	// UserAgent sent with every fetch
+	UserAgent string
+}
+
+func DefaultMetadataFetcherConfig() MetadataFetcherConfig {
+	return MetadataFetcherConfig{
+		Timeout:      5 * time.Second,
+		MaxBytes:     512 << 10,
+		MaxRedirects: 5,
+		CacheTTL:     time.Hour,
+		CacheSize:    1000,
+		UserAgent:    "LinktreeMetadataFetcher/1.0",
+	}
+}
+
+// LoadMetadataFetcherConfig overrides the defaults with METADATA_TIMEOUT,
+// METADATA_MAX_BYTES, METADATA_CACHE_TTL and METADATA_ALLOW_PRIVATE_NETWORKS
+// when they are set.
+func LoadMetadataFetcherConfig() (MetadataFetcherConfig, error) {
+	config := DefaultMetadataFetcherConfig()
+
+	durations := map[string]*time.Duration{
+		"METADATA_TIMEOUT":   &config.Timeout,
+		"METADATA_CACHE_TTL": &config.CacheTTL,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				return config, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			*target = parsed
+		}
+	}
+
+	if value := os.Getenv("METADATA_MAX_BYTES"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return config, fmt.Errorf("invalid METADATA_MAX_BYTES: %v", err)
+		}
+		config.MaxBytes = parsed
+	}
+
+	if value := os.Getenv("METADATA_ALLOW_PRIVATE_NETWORKS"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("invalid METADATA_ALLOW_PRIVATE_NETWORKS: %v", err)
+		}
+		config.AllowPrivateNetworks = parsed
+	}
+
+	return config, nil
+}
+
+var (
+	ErrBlockedAddress = errors.New("address is not allowed")
+	errNotHTML        = errors.New("page is not html")
+)
+
+// Ranges that are not covered by the net.IP helpers but must not be reached
+// from the server either.
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"100.64.0.0/10",
+	"192.0.0.0/24",
+	"198.18.0.0/15",
+	"240.0.0.0/4",
+	"64:ff9b::/96",
+)
+
+// MetadataFetcher downloads pages to read their OpenGraph, Twitter card and
+// HTML metadata. Connections are only made to public addresses unless the
+// config allows private networks, and results are cached per URL.
+type MetadataFetcher struct {
+	config MetadataFetcherConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]metadataCacheEntry
+}
+
+type metadataCacheEntry struct {
+	metadata  LinkMetadata
+	expiresAt time.Time
+}
+
+func NewMetadataFetcher(config MetadataFetcherConfig) *MetadataFetcher {
+	dialer := publicDialer(config.Timeout, config.AllowPrivateNetworks)
+
+	return &MetadataFetcher{
+		config: config,
+		cache:  make(map[string]metadataCacheEntry),
+		client: &http.Client{
+			Timeout: config.Timeout,
+			Transport: &http.Transport{
+				Proxy:                  nil,
+				DialContext:            dialer.DialContext,
+				TLSHandshakeTimeout:    config.Timeout,
+				ResponseHeaderTimeout:  config.Timeout,
+				MaxResponseHeaderBytes: 64 << 10,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > config.MaxRedirects {
+					return errTooManyRedirects
+				}
+				return checkRedirectTarget(req.URL, config.AllowPrivateNetworks)
+			},
+		},
+	}
+}
+
+// publicDialer returns a dialer that refuses to connect to anything but
+// public addresses unless allowPrivate is set.
+func publicDialer(timeout time.Duration, allowPrivate bool) *net.Dialer {
+	return &net.Dialer{
+		Timeout: timeout,
+		// Control runs after DNS resolution, so it sees the address actually
+		// dialled and cannot be bypassed by a hostname or a redirect.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if allowPrivate {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicIP(net.ParseIP(host)) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		},
+	}
+}
+
+// checkRedirectTarget rejects redirects to other schemes than http and https
+// and to literal private addresses before anything is dialled.
+func checkRedirectTarget(target *url.URL, allowPrivate bool) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("%w: redirect to %s", ErrBlockedAddress, target.Scheme)
+	}
+	if ip := net.ParseIP(target.Hostname()); ip != nil && !allowPrivate && !publicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
+
+// Fetch returns the metadata of the page at target, using the cache when a
+// fresh entry exists.
+func (f *MetadataFetcher) Fetch(ctx context.Context, target string) (LinkMetadata, error) {
+	f.mu.Lock()
+	entry, ok := f.cache[target]
+	f.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.metadata, nil
+	}
+
+	metadata, err := f.fetch(ctx, target)
+	if err != nil {
+		return metadata, err
+	}
+
+	f.store(target, metadata)
+	return metadata, nil
+}
+
+// Refresh is Fetch without reading the cache.
+func (f *MetadataFetcher) Refresh(ctx context.Context, target string) (LinkMetadata, error) {
+	f.mu.Lock()
+	delete(f.cache, target)
+	f.mu.Unlock()
+
+	return f.Fetch(ctx, target)
+}
+
+func (f *MetadataFetcher) store(target string, metadata LinkMetadata) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if len(f.cache) >= f.config.CacheSize {
+		for key, entry := range f.cache {
+			if now.After(entry.expiresAt) {
+				delete(f.cache, key)
+			}
+		}
+	}
+	// Still full of live entries: drop an arbitrary one.
+	for key := range f.cache {
+		if len(f.cache) < f.config.CacheSize {
+			break
+		}
+		delete(f.cache, key)
+	}
+
+	f.cache[target] = metadataCacheEntry{metadata: metadata, expiresAt: now.Add(f.config.CacheTTL)}
+}
+
+func (f *MetadataFetcher) fetch(ctx context.Context, target string) (LinkMetadata, error) {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return LinkMetadata{}, fmt.Errorf("can only fetch http and https urls")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return LinkMetadata{}, err
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return LinkMetadata{}, fmt.Errorf("failed to fetch page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return LinkMetadata{}, fmt.Errorf("failed to fetch page: status %d", resp.StatusCode)
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return LinkMetadata{}, errNotHTML
+	}
+
+	return ParseLinkMetadata(io.LimitReader(resp.Body, f.config.MaxBytes), resp.Request.URL), nil
+}
+
+// ParseLinkMetadata reads the head of an HTML page. OpenGraph tags win over
+// Twitter card tags, which win over <title> and the description meta tag.
+// Relative image and icon URLs are resolved against base, and the favicon
+// falls back to /favicon.ico.
+func ParseLinkMetadata(r io.Reader, base *url.URL) LinkMetadata {
+	var og, twitter, plain LinkMetadata
+	var favicon string
+
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+
+parse:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			break parse
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.DataAtom {
+			case atom.Body:
+				break parse
+			case atom.Title:
+				inTitle = plain.Title == ""
+			case atom.Meta:
+				key := strings.ToLower(attr(token, "property"))
+				if key == "" {
+					key = strings.ToLower(attr(token, "name"))
+				}
+				content := strings.TrimSpace(attr(token, "content"))
+				switch key {
+				case "og:title":
+					og.Title = content
+				case "og:description":
+					og.Description = content
+				case "og:image", "og:image:url":
+					if og.ImageURL == "" {
+						og.ImageURL = content
+					}
+				case "twitter:title":
+					twitter.Title = content
+				case "twitter:description":
+					twitter.Description = content
+				case "twitter:image", "twitter:image:src":
+					if twitter.ImageURL == "" {
+						twitter.ImageURL = content
+					}
+				case "description":
+					plain.Description = content
+				}
+			case atom.Link:
+				for _, rel := range strings.Fields(strings.ToLower(attr(token, "rel"))) {
+					if rel == "icon" && favicon == "" {
+						favicon = attr(token, "href")
+					}
+				}
+			}
+		case html.EndTagToken:
+			token := tokenizer.Token()
+			if token.DataAtom == atom.Head {
+				break parse
+			}
+			if token.DataAtom == atom.Title {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				plain.Title += string(tokenizer.Text())
+			}
+		}
+	}
+
+	metadata := LinkMetadata{
+		Title:       firstNonEmpty(og.Title, twitter.Title, strings.TrimSpace(plain.Title)),
+		Description: firstNonEmpty(og.Description, twitter.Description, plain.Description),
+		ImageURL:    resolveReference(base, firstNonEmpty(og.ImageURL, twitter.ImageURL)),
+		FaviconURL:  resolveReference(base, firstNonEmpty(favicon, "/favicon.ico")),
+	}
+
+	return metadata
+}
+
+func attr(token html.Token, name string) string {
+	for _, a := range token.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// resolveReference makes ref absolute against base, dropping anything that is
+// not an http or https URL.
+func resolveReference(base *url.URL, ref string) string {
+	if ref == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		parsed = base.ResolveReference(parsed)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return ""
+	}
+
+	return parsed.String()
+}
+
+// publicIP reports whether ip is a globally routable unicast address.
+func publicIP(ip net.IP) bool {
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() ||
+		ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
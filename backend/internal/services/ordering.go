@@ -0,0 +1,45 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// nextPosition returns the position after the highest one matched by query,
+// or 0 when it matches nothing.
+func nextPosition(query *gorm.DB) (int, error) {
+	var highest sql.NullInt64
+	if err := query.Select("MAX(position)").Scan(&highest).Error; err != nil {
+		return 0, fmt.Errorf("failed to compute position: %v", err)
+	}
+
+	if !highest.Valid {
+		return 0, nil
+	}
+
+	return int(highest.Int64) + 1, nil
+}
+
+// samePermutation reports whether ordered lists every id in owned exactly once.
+func samePermutation(owned, ordered []uint) bool {
+	if len(owned) != len(ordered) {
+		return false
+	}
+
+	seen := make(map[uint]bool, len(owned))
+	for _, id := range owned {
+		seen[id] = false
+	}
+
+	for _, id := range ordered {
+		used, ok := seen[id]
+		if !ok || used {
+			return false
+		}
+		seen[id] = true
+	}
+
+	return true
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LinkCTR is how often visitors of a profile clicked one of its links.
+type LinkCTR struct {
+	// LinkID is the link's ID
+	LinkID uint `json:"link_id" example:"1"`
+
+	// Title is the link's title
+	Title string `json:"title" example:"My Website"`
+
+	// Clicks is the number of clicks on the link in the period
+	Clicks int64 `json:"clicks" example:"42"`
+
+	// UniqueVisitors counts each visitor who clicked the link once per UTC
+	// day
+	UniqueVisitors int64 `json:"unique_visitors" example:"30"`
+
+	// CTR is clicks per profile view; 0 without views
+	CTR float64 `json:"ctr" example:"0.21"`
+}
+
+// CTRReport is the click-through rate of each of a profile's links.
+type CTRReport struct {
+	Timezone     string    `json:"timezone" example:"Europe/Berlin"`
+	From         time.Time `json:"from" example:"2024-01-01T00:00:00+01:00"`
+	To           time.Time `json:"to" example:"2024-02-01T00:00:00+01:00"`
+	ProfileViews int64     `json:"profile_views" example:"200"`
+	// UniqueViewers counts each visitor of the profile once per UTC day
+	UniqueViewers int64 `json:"unique_viewers" example:"150"`
+	// UniqueVisitors counts each visitor who clicked any of the links once
+	// per UTC day
+	UniqueVisitors int64 `json:"unique_visitors" example:"90"`
+	// IncludesBots is true when the owner chose to see automated clicks
+	IncludesBots bool      `json:"includes_bots" example:"false"`
+	Links        []LinkCTR `json:"links"`
+}
+
+// TrackProfileView records a visit to a user's profile. Owners looking at
+// their own profile and visits the bot classifier takes for automated are
+// not counted.
+func (s *AnalyticsService) TrackProfileView(username string, viewerUsername string, details ClickDetails) error {
+	if username == viewerUsername {
+		return nil
+	}
+	if !details.classified {
+		details = s.ClassifyClick(details)
+	}
+	if details.BotReason != "" {
+		return nil
+	}
+	now := time.Now()
+
+	var user models.User
+	if err := s.db.Select("id").Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	visitorHash, err := s.salts.hash(details.IP, details.UserAgent, now)
+	if err != nil {
+		return err
+	}
+
+	view := models.ProfileView{
+		UserID:      user.ID,
+		ViewedAt:    now.UTC(),
+		Referrer:    truncateRunes(details.Referrer, maxClickFieldLength),
+		VisitorHash: visitorHash,
+	}
+	if err := s.db.Create(&view).Error; err != nil {
+		return fmt.Errorf("failed to save profile view: %v", err)
+	}
+
+	return nil
+}
+
+// GetCTRReport returns the profile views of the user in the days selected by
+// query and, for each of their links, the clicks and the click-through rate.
+func (s *AnalyticsService) GetCTRReport(username string, query SeriesQuery) (CTRReport, error) {
+	from, to, err := dateRange(query)
+	if err != nil {
+		return CTRReport{}, err
+	}
+
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return CTRReport{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	report := CTRReport{Timezone: from.Location().String(), From: from, To: to, IncludesBots: user.ShowBotTraffic, Links: []LinkCTR{}}
+
+	views := func() *gorm.DB {
+		return s.db.Model(&models.ProfileView{}).
+			Where("user_id = ? AND viewed_at >= ? AND viewed_at < ?", user.ID, from.UTC(), to.UTC())
+	}
+	if err := views().Count(&report.ProfileViews).Error; err != nil {
+		return report, fmt.Errorf("failed to count profile views: %v", err)
+	}
+	report.UniqueViewers, err = countUniqueVisitors(views())
+	if err != nil {
+		return report, err
+	}
+
+	var links []models.Link
+	if err := s.db.Select("id", "title").Where("user_id = ?", user.ID).Order("id").Find(&links).Error; err != nil {
+		return report, fmt.Errorf("failed to load links: %v", err)
+	}
+
+	userLinks := func(db *gorm.DB) *gorm.DB {
+		return db.Where("link_id IN (?)", s.db.Model(&models.Link{}).Select("id").Where("user_id = ?", user.ID))
+	}
+	counts, err := s.hourlyClickCounts(from, to, userLinks)
+	if err != nil {
+		return report, err
+	}
+	clicks := make(map[uint]int64)
+	for _, count := range counts {
+		clicks[count.linkId] += count.total(user.ShowBotTraffic)
+	}
+
+	report.UniqueVisitors, err = countUniqueVisitors(clickEvents(s.db, from, to, user.ShowBotTraffic, userLinks))
+	if err != nil {
+		return report, err
+	}
+
+	var visitorRows []struct {
+		LinkID   uint
+		Visitors int64
+	}
+	err = clickEvents(s.db, from, to, user.ShowBotTraffic, userLinks).
+		Select("link_id, " + uniqueVisitorsExpression + " AS visitors").Group("link_id").Scan(&visitorRows).Error
+	if err != nil {
+		return report, fmt.Errorf("failed to count unique visitors: %v", err)
+	}
+	visitors := make(map[uint]int64, len(visitorRows))
+	for _, row := range visitorRows {
+		visitors[row.LinkID] = row.Visitors
+	}
+
+	for _, link := range links {
+		ctr := LinkCTR{LinkID: link.ID, Title: link.Title, Clicks: clicks[link.ID], UniqueVisitors: visitors[link.ID]}
+		if report.ProfileViews > 0 {
+			ctr.CTR = float64(ctr.Clicks) / float64(report.ProfileViews)
+		}
+		report.Links = append(report.Links, ctr)
+	}
+
+	return report, nil
+}
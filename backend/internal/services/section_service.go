@@ -0,0 +1,238 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	SectionDeleteMoveLinks = "move"
+	SectionDeleteLinks     = "delete"
+)
+
+type SectionService struct {
+	db *gorm.DB
+}
+
+func NewSectionService(db *gorm.DB) *SectionService {
+	return &SectionService{db: db}
+}
+
+func (s *SectionService) CreateSection(username string, section models.Section) (models.Section, error) {
+	if section.Title == "" {
+		return models.Section{}, errors.New("required fields are missing")
+	}
+
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return models.Section{}, fmt.Errorf("user not found: %v", err)
+	}
+
+	position, err := nextPosition(s.db.Model(&models.Section{}).Where("user_id = ?", user.ID))
+	if err != nil {
+		return models.Section{}, err
+	}
+
+	newSection := models.Section{
+		Title:     section.Title,
+		Collapsed: section.Collapsed,
+		Position:  position,
+		UserID:    user.ID,
+	}
+
+	if err := s.db.Create(&newSection).Error; err != nil {
+		return models.Section{}, err
+	}
+
+	return newSection, nil
+}
+
+func (s *SectionService) GetSections(username string) ([]models.Section, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %v", err)
+	}
+
+	var sections []models.Section
+	err := s.db.Preload("Links", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Where("user_id = ?", user.ID).Order("position, id").Find(&sections).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sections: %v", err)
+	}
+
+	return sections, nil
+}
+
+func (s *SectionService) UpdateSection(username string, sectionId uint64, title string, collapsed *bool) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var section models.Section
+	if err := s.db.Where("id = ? AND user_id = ?", sectionId, user.ID).First(&section).Error; err != nil {
+		return fmt.Errorf("section not found: %v", err)
+	}
+
+	if title != "" {
+		section.Title = title
+	}
+
+	if collapsed != nil {
+		section.Collapsed = *collapsed
+	}
+
+	return s.db.Save(&section).Error
+}
+
+// ReorderSections sets section positions to match sectionIds, which must list
+// every section owned by the user exactly once.
+func (s *SectionService) ReorderSections(username string, sectionIds []uint) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var ownedIds []uint
+	if err := s.db.Model(&models.Section{}).Where("user_id = ?", user.ID).Pluck("id", &ownedIds).Error; err != nil {
+		return fmt.Errorf("failed to load sections: %v", err)
+	}
+
+	if !samePermutation(ownedIds, sectionIds) {
+		return errors.New("section order must list every section exactly once")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range sectionIds {
+			if err := tx.Model(&models.Section{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return fmt.Errorf("failed to reorder sections: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ReorderSectionLinks sets link positions inside a section to match linkIds,
+// which must list every link in the section exactly once.
+func (s *SectionService) ReorderSectionLinks(username string, sectionId uint64, linkIds []uint) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var section models.Section
+	if err := s.db.Where("id = ? AND user_id = ?", sectionId, user.ID).First(&section).Error; err != nil {
+		return fmt.Errorf("section not found: %v", err)
+	}
+
+	var ownedIds []uint
+	if err := s.db.Model(&models.Link{}).Where("section_id = ?", section.ID).Pluck("id", &ownedIds).Error; err != nil {
+		return fmt.Errorf("failed to load links: %v", err)
+	}
+
+	if !samePermutation(ownedIds, linkIds) {
+		return errors.New("link order must list every link in the section exactly once")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range linkIds {
+			if err := tx.Model(&models.Link{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return fmt.Errorf("failed to reorder links: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ReorderUngroupedLinks sets the positions of the user's links outside any
+// section to match linkIds, which must list every one of them exactly once.
+func (s *SectionService) ReorderUngroupedLinks(username string, linkIds []uint) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var ownedIds []uint
+	if err := s.db.Model(&models.Link{}).Where("user_id = ? AND section_id IS NULL", user.ID).Pluck("id", &ownedIds).Error; err != nil {
+		return fmt.Errorf("failed to load links: %v", err)
+	}
+
+	if !samePermutation(ownedIds, linkIds) {
+		return errors.New("link order must list every ungrouped link exactly once")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for position, id := range linkIds {
+			if err := tx.Model(&models.Link{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return fmt.Errorf("failed to reorder links: %v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteSection removes a section. With SectionDeleteMoveLinks its links are
+// appended to the user's ungrouped links; with SectionDeleteLinks they are
+// deleted along with it.
+func (s *SectionService) DeleteSection(username string, sectionId uint64, mode string) error {
+	if mode != SectionDeleteMoveLinks && mode != SectionDeleteLinks {
+		return fmt.Errorf("links must be either %q or %q", SectionDeleteMoveLinks, SectionDeleteLinks)
+	}
+
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found: %v", err)
+	}
+
+	var section models.Section
+	if err := s.db.Where("id = ? AND user_id = ?", sectionId, user.ID).First(&section).Error; err != nil {
+		return fmt.Errorf("section not found: %v", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if mode == SectionDeleteLinks {
+			var linkIds []uint
+			if err := tx.Model(&models.Link{}).Where("section_id = ?", section.ID).Pluck("id", &linkIds).Error; err != nil {
+				return fmt.Errorf("failed to load links: %v", err)
+			}
+			if len(linkIds) > 0 {
+				if err := deleteLinkDependents(tx, linkIds); err != nil {
+					return err
+				}
+				if err := tx.Where("id IN ?", linkIds).Delete(&models.Link{}).Error; err != nil {
+					return fmt.Errorf("failed to delete links: %v", err)
+				}
+			}
+		} else {
+			position, err := nextPosition(tx.Model(&models.Link{}).Where("user_id = ? AND section_id IS NULL", user.ID))
+			if err != nil {
+				return err
+			}
+
+			var links []models.Link
+			if err := tx.Where("section_id = ?", section.ID).Order("position, id").Find(&links).Error; err != nil {
+				return fmt.Errorf("failed to load links: %v", err)
+			}
+
+			for i, link := range links {
+				err := tx.Model(&models.Link{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+					"section_id": nil,
+					"position":   position + i,
+				}).Error
+				if err != nil {
+					return fmt.Errorf("failed to move links: %v", err)
+				}
+			}
+		}
+
+		if err := tx.Delete(&section).Error; err != nil {
+			return fmt.Errorf("failed to delete section: %v", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	generatedSlugLength   = 7
+	generatedSlugAttempts = 10
+	slugAlphabet          = "abcdefghijkmnopqrstuvwxyz23456789"
+)
+
+// SlugGracePeriod is how long a replaced slug keeps redirecting to its link.
+var SlugGracePeriod = 30 * 24 * time.Hour
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{1,62}[a-z0-9])$`)
+
+var reservedSlugs = map[string]bool{
+	"admin":     true,
+	"analytics": true,
+	"api":       true,
+	"app":       true,
+	"help":      true,
+	"links":     true,
+	"login":     true,
+	"logout":    true,
+	"settings":  true,
+	"signup":    true,
+	"static":    true,
+	"support":   true,
+	"swagger":   true,
+	"users":     true,
+	"www":       true,
+}
+
+var ErrSlugTaken = errors.New("slug is already taken")
+
+// ValidateSlug checks that slug is 3-64 lowercase letters, digits or inner
+// hyphens and is not a reserved word.
+func ValidateSlug(slug string) error {
+	if !slugPattern.MatchString(slug) {
+		return errors.New("slug must be 3-64 lowercase letters, digits or hyphens and cannot start or end with a hyphen")
+	}
+
+	if reservedSlugs[slug] {
+		return fmt.Errorf("slug %q is reserved", slug)
+	}
+
+	return nil
+}
+
+// slugAvailable reports whether slug can be given to linkId: no other link
+// uses it and no other link still redirects from it during a grace period.
+func slugAvailable(db *gorm.DB, slug string, linkId uint) (bool, error) {
+	var count int64
+	if err := db.Model(&models.Link{}).Where("slug = ? AND id <> ?", slug, linkId).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check slug: %v", err)
+	}
+	if count > 0 {
+		return false, nil
+	}
+
+	err := db.Model(&models.SlugAlias{}).
+		Where("slug = ? AND link_id <> ? AND expires_at > ?", slug, linkId, time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check slug: %v", err)
+	}
+
+	return count == 0, nil
+}
+
+// GenerateSlug returns a random slug that is not in use.
+func GenerateSlug(db *gorm.DB) (string, error) {
+	for attempt := 0; attempt < generatedSlugAttempts; attempt++ {
+		var b strings.Builder
+		for i := 0; i < generatedSlugLength; i++ {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(slugAlphabet))))
+			if err != nil {
+				return "", fmt.Errorf("failed to generate slug: %v", err)
+			}
+			b.WriteByte(slugAlphabet[n.Int64()])
+		}
+
+		slug := b.String()
+		available, err := slugAvailable(db, slug, 0)
+		if err != nil {
+			return "", err
+		}
+		if available {
+			return slug, nil
+		}
+	}
+
+	return "", errors.New("failed to generate a unique slug")
+}
+
+// resolveSlug picks the slug for a link: the requested one after validation
+// and collision checks, or a generated one when none is requested.
+func resolveSlug(db *gorm.DB, requested string, linkId uint) (string, error) {
+	if requested == "" {
+		return GenerateSlug(db)
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(requested))
+	if err := ValidateSlug(slug); err != nil {
+		return "", err
+	}
+
+	available, err := slugAvailable(db, slug, linkId)
+	if err != nil {
+		return "", err
+	}
+	if !available {
+		return "", ErrSlugTaken
+	}
+
+	return slug, nil
+}
+
+// retireSlug keeps oldSlug redirecting to linkId for SlugGracePeriod and drops
+// any alias the link held for newSlug.
+func retireSlug(tx *gorm.DB, linkId uint, oldSlug, newSlug string) error {
+	if err := tx.Where("slug = ?", newSlug).Delete(&models.SlugAlias{}).Error; err != nil {
+		return fmt.Errorf("failed to update slug aliases: %v", err)
+	}
+
+	if oldSlug == "" {
+		return nil
+	}
+
+	if err := tx.Where("slug = ?", oldSlug).Delete(&models.SlugAlias{}).Error; err != nil {
+		return fmt.Errorf("failed to update slug aliases: %v", err)
+	}
+
+	alias := models.SlugAlias{
+		Slug:      oldSlug,
+		LinkID:    linkId,
+		ExpiresAt: time.Now().Add(SlugGracePeriod),
+	}
+	if err := tx.Create(&alias).Error; err != nil {
+		return fmt.Errorf("failed to keep old slug: %v", err)
+	}
+
+	return nil
+}
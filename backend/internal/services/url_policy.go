@@ -0,0 +1,526 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+const defaultMaxURLLength = 2048
+
+var (
+	// ErrURLRejected wraps every URL policy violation.
+	ErrURLRejected = errors.New("url rejected")
+
+	defaultAllowedSchemes = []string{"http", "https", "mailto", "tel"}
+
+	telPattern   = regexp.MustCompile(`^\+?[0-9][0-9 ().-]{2,30}$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+	numericLabelPattern = regexp.MustCompile(`(?i)^(0x[0-9a-f]*|[0-9]+)$`)
+)
+
+// Cyrillic and Greek letters that render like Latin ones. A label written
+// only with these is treated as impersonating a Latin domain.
+var latinLookalikes = map[rune]bool{
+	'а': true, 'в': true, 'е': true, 'к': true, 'м': true, 'н': true, 'о': true,
+	'р': true, 'с': true, 'т': true, 'у': true, 'х': true, 'ѕ': true, 'і': true,
+	'ј': true, 'ԁ': true, 'һ': true, 'ӏ': true, 'ԛ': true, 'ԝ': true, 'ь': true,
+	'α': true, 'ο': true, 'ν': true, 'ρ': true, 'τ': true, 'υ': true, 'κ': true,
+	'ι': true, 'ε': true,
+}
+
+// Scripts that may share a label, as in Japanese and Korean domains.
+var compatibleScripts = map[string]map[string]bool{
+	"Han":      {"Hiragana": true, "Katakana": true, "Hangul": true},
+	"Hiragana": {"Han": true, "Katakana": true},
+	"Katakana": {"Han": true, "Hiragana": true},
+	"Hangul":   {"Han": true},
+}
+
+var homographScripts = []string{
+	"Latin", "Cyrillic", "Greek", "Armenian", "Hebrew", "Arabic", "Han",
+	"Hiragana", "Katakana", "Hangul", "Thai", "Devanagari", "Georgian", "Cherokee",
+}
+
+// URLPolicy decides which user-submitted link URLs are accepted. The domain
+// blocklist can be changed while the server runs; when it was loaded from a
+// file, changes are written back to that file.
+type URLPolicy struct {
+	mu             sync.RWMutex
+	allowedSchemes map[string]bool
+	blocked        map[string]bool
+	allowed        map[string]bool
+	maxLength      int
+	blocklistFile  string
+	allowPrivate   bool
+}
+
+// NewURLPolicy returns a policy allowing http, https, mailto and tel URLs of
+// up to 2048 characters, with empty domain lists.
+func NewURLPolicy() *URLPolicy {
+	policy := &URLPolicy{
+		allowedSchemes: make(map[string]bool),
+		blocked:        make(map[string]bool),
+		allowed:        make(map[string]bool),
+		maxLength:      defaultMaxURLLength,
+	}
+	for _, scheme := range defaultAllowedSchemes {
+		policy.allowedSchemes[scheme] = true
+	}
+	return policy
+}
+
+// LoadURLPolicy builds a policy from URL_POLICY_SCHEMES (comma separated),
+// URL_POLICY_MAX_LENGTH, URL_POLICY_BLOCKLIST_FILE, URL_POLICY_ALLOWLIST_FILE
+// and URL_POLICY_ALLOW_PRIVATE_NETWORKS when they are set.
+func LoadURLPolicy() (*URLPolicy, error) {
+	policy := NewURLPolicy()
+
+	if value := os.Getenv("URL_POLICY_SCHEMES"); value != "" {
+		policy.SetAllowedSchemes(strings.Split(value, ","))
+	}
+
+	if value := os.Getenv("URL_POLICY_MAX_LENGTH"); value != "" {
+		maxLength, err := strconv.Atoi(value)
+		if err != nil || maxLength <= 0 {
+			return nil, fmt.Errorf("invalid URL_POLICY_MAX_LENGTH: %q", value)
+		}
+		policy.maxLength = maxLength
+	}
+
+	if value := os.Getenv("URL_POLICY_ALLOW_PRIVATE_NETWORKS"); value != "" {
+		allow, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL_POLICY_ALLOW_PRIVATE_NETWORKS: %v", err)
+		}
+		policy.allowPrivate = allow
+	}
+
+	if path := os.Getenv("URL_POLICY_BLOCKLIST_FILE"); path != "" {
+		if err := policy.LoadBlocklistFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if path := os.Getenv("URL_POLICY_ALLOWLIST_FILE"); path != "" {
+		domains, err := readDomainFile(path)
+		if err != nil {
+			return nil, err
+		}
+		policy.SetAllowedDomains(domains)
+	}
+
+	return policy, nil
+}
+
+// SetAllowedSchemes replaces the accepted URL schemes.
+func (p *URLPolicy) SetAllowedSchemes(schemes []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.allowedSchemes = make(map[string]bool)
+	for _, scheme := range schemes {
+		if scheme = strings.ToLower(strings.TrimSpace(scheme)); scheme != "" {
+			p.allowedSchemes[scheme] = true
+		}
+	}
+}
+
+// SetAllowPrivateNetworks permits private and loopback addresses and local
+// host names such as localhost, for development and tests.
+func (p *URLPolicy) SetAllowPrivateNetworks(allow bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.allowPrivate = allow
+}
+
+// SetMaxLength sets the longest accepted URL in bytes.
+func (p *URLPolicy) SetMaxLength(maxLength int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxLength = maxLength
+}
+
+// SetAllowedDomains replaces the trusted domains. Trusted domains and their
+// subdomains skip the blocklist and homograph checks, for example for a
+// legitimate internationalised domain.
+func (p *URLPolicy) SetAllowedDomains(domains []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.allowed = make(map[string]bool)
+	for _, domain := range domains {
+		if normalized, err := normalizeDomain(domain); err == nil {
+			p.allowed[normalized] = true
+		}
+	}
+}
+
+// LoadBlocklistFile replaces the blocklist with the domains in path, one per
+// line with # comments, and remembers path for saving runtime changes.
+func (p *URLPolicy) LoadBlocklistFile(path string) error {
+	domains, err := readDomainFile(path)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.blocked = make(map[string]bool)
+	for _, domain := range domains {
+		if normalized, err := normalizeDomain(domain); err == nil {
+			p.blocked[normalized] = true
+		}
+	}
+	p.blocklistFile = path
+
+	return nil
+}
+
+// BlockedDomains returns the blocklist in alphabetical order.
+func (p *URLPolicy) BlockedDomains() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	domains := make([]string, 0, len(p.blocked))
+	for domain := range p.blocked {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	return domains
+}
+
+// BlockDomains adds domains, and with them all their subdomains, to the
+// blocklist.
+func (p *URLPolicy) BlockDomains(domains []string) error {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		n, err := normalizeDomain(domain)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, n)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, domain := range normalized {
+		p.blocked[domain] = true
+	}
+
+	return p.saveBlocklist()
+}
+
+// UnblockDomain removes a domain from the blocklist.
+func (p *URLPolicy) UnblockDomain(domain string) error {
+	normalized, err := normalizeDomain(domain)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.blocked[normalized] {
+		return fmt.Errorf("domain %q is not blocked", normalized)
+	}
+	delete(p.blocked, normalized)
+
+	return p.saveBlocklist()
+}
+
+// saveBlocklist writes the blocklist back to the file it was loaded from.
+// Callers hold the write lock.
+func (p *URLPolicy) saveBlocklist() error {
+	if p.blocklistFile == "" {
+		return nil
+	}
+
+	domains := make([]string, 0, len(p.blocked))
+	for domain := range p.blocked {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+
+	tmp := p.blocklistFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(domains, "\n")+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to save blocklist: %v", err)
+	}
+	if err := os.Rename(tmp, p.blocklistFile); err != nil {
+		return fmt.Errorf("failed to save blocklist: %v", err)
+	}
+
+	return nil
+}
+
+// Check returns an error wrapping ErrURLRejected when rawURL may not be used
+// as a link.
+func (p *URLPolicy) Check(rawURL string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if rawURL == "" {
+		return fmt.Errorf("%w: url is empty", ErrURLRejected)
+	}
+
+	if len(rawURL) > p.maxLength {
+		return fmt.Errorf("%w: url is longer than %d characters", ErrURLRejected, p.maxLength)
+	}
+
+	for _, r := range rawURL {
+		if unicode.IsControl(r) || unicode.IsSpace(r) {
+			return fmt.Errorf("%w: url contains whitespace or control characters", ErrURLRejected)
+		}
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" {
+		return fmt.Errorf("%w: invalid url", ErrURLRejected)
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	if !p.allowedSchemes[scheme] {
+		return fmt.Errorf("%w: scheme %q is not allowed", ErrURLRejected, scheme)
+	}
+
+	switch scheme {
+	case "mailto":
+		address := parsed.Opaque
+		if address == "" {
+			address = parsed.Path
+		}
+		if !emailPattern.MatchString(address) {
+			return fmt.Errorf("%w: invalid email address", ErrURLRejected)
+		}
+		return p.checkDomain(address[strings.LastIndex(address, "@")+1:])
+	case "tel":
+		number := parsed.Opaque
+		if number == "" {
+			number = parsed.Path
+		}
+		if !telPattern.MatchString(number) {
+			return fmt.Errorf("%w: invalid phone number", ErrURLRejected)
+		}
+		return nil
+	case "http", "https":
+		if parsed.User != nil {
+			return fmt.Errorf("%w: urls with credentials are not allowed", ErrURLRejected)
+		}
+		return p.checkHost(parsed.Hostname())
+	}
+
+	// Other schemes enabled by configuration carry no host to check.
+	return nil
+}
+
+func (p *URLPolicy) checkHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("%w: url has no host", ErrURLRejected)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		if !p.allowPrivate && !publicIP(ip) {
+			return fmt.Errorf("%w: private and loopback addresses are not allowed", ErrURLRejected)
+		}
+		return nil
+	}
+
+	// Browsers read hosts like 2130706433, 0x7f.1 or 0177.0.0.1 as IPv4
+	// addresses, so anything that only looks numeric is refused outright.
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if p.allowPrivate {
+		return p.checkDomain(host)
+	}
+
+	if numericHost(labels) {
+		return fmt.Errorf("%w: numeric hosts are not allowed", ErrURLRejected)
+	}
+
+	if len(labels) < 2 {
+		return fmt.Errorf("%w: host %q is not a public domain", ErrURLRejected, host)
+	}
+
+	switch labels[len(labels)-1] {
+	case "localhost", "local", "internal", "lan", "home", "corp", "intranet":
+		return fmt.Errorf("%w: host %q is not a public domain", ErrURLRejected, host)
+	}
+
+	return p.checkDomain(host)
+}
+
+// checkDomain applies the allowlist, blocklist and homograph checks. The
+// allowlist wins: an allowlisted domain is accepted even when blocked.
+func (p *URLPolicy) checkDomain(host string) error {
+	ascii, err := normalizeDomain(host)
+	if err != nil {
+		return fmt.Errorf("%w: invalid domain %q", ErrURLRejected, host)
+	}
+
+	if matchingDomain(p.allowed, ascii) != "" {
+		return nil
+	}
+
+	if domain := matchingDomain(p.blocked, ascii); domain != "" {
+		return fmt.Errorf("%w: domain %q is blocked", ErrURLRejected, domain)
+	}
+
+	unicodeHost, err := idna.Lookup.ToUnicode(ascii)
+	if err != nil {
+		return fmt.Errorf("%w: invalid domain %q", ErrURLRejected, host)
+	}
+	labels := strings.Split(unicodeHost, ".")
+	latinTLD := isASCII(labels[len(labels)-1])
+	for _, label := range labels {
+		if reason := homographReason(label, latinTLD); reason != "" {
+			return fmt.Errorf("%w: domain %q %s", ErrURLRejected, unicodeHost, reason)
+		}
+	}
+
+	return nil
+}
+
+// homographReason explains why a domain label looks like a spoof of another
+// domain, or returns "" when it does not. Labels spelled only with Latin
+// lookalikes are fine under their own script's TLDs, such as .рф, but not
+// under a Latin one like .com.
+func homographReason(label string, latinTLD bool) string {
+	scripts := make(map[string]bool)
+	lookalikesOnly := true
+	letters := 0
+
+	for _, r := range label {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		script := runeScript(r)
+		if script != "" {
+			scripts[script] = true
+		}
+		if !latinLookalikes[r] {
+			lookalikesOnly = false
+		}
+	}
+
+	names := make([]string, 0, len(scripts))
+	for script := range scripts {
+		names = append(names, script)
+	}
+	sort.Strings(names)
+
+	for i, a := range names {
+		for _, b := range names[i+1:] {
+			if !compatibleScripts[a][b] {
+				return fmt.Sprintf("mixes %s and %s characters", a, b)
+			}
+		}
+	}
+
+	if latinTLD && letters > 0 && lookalikesOnly && !scripts["Latin"] {
+		return "only uses characters that look like Latin letters"
+	}
+
+	return ""
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+func runeScript(r rune) string {
+	for _, name := range homographScripts {
+		if unicode.Is(unicode.Scripts[name], r) {
+			return name
+		}
+	}
+	return "Other"
+}
+
+func numericHost(labels []string) bool {
+	for _, label := range labels {
+		if label != "" && !numericLabelPattern.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeDomain lowercases domain and converts it to its ASCII (punycode)
+// form so that blocklist entries match however a URL spells the domain.
+func normalizeDomain(domain string) (string, error) {
+	domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+	if domain == "" {
+		return "", errors.New("domain is empty")
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil {
+		return "", fmt.Errorf("invalid domain %q: %v", domain, err)
+	}
+
+	return ascii, nil
+}
+
+// matchingDomain returns the entry of domains that host equals or is a
+// subdomain of.
+func matchingDomain(domains map[string]bool, host string) string {
+	for {
+		if domains[host] {
+			return host
+		}
+		dot := strings.IndexByte(host, '.')
+		if dot < 0 {
+			return ""
+		}
+		host = host[dot+1:]
+	}
+}
+
+func readDomainFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open domain list: %v", err)
+	}
+	defer file.Close()
+
+	return readDomains(file)
+}
+
+func readDomains(r io.Reader) ([]string, error) {
+	var domains []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			domains = append(domains, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain list: %v", err)
+	}
+	return domains, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"linktree-mohamedfadel-backend/internal/models"
 	"linktree-mohamedfadel-backend/internal/utils"
@@ -71,14 +72,67 @@ func (s *UserService) Login(username, password string) (string, error) {
 	return token, nil
 }
 
+// GetUserProfileInfo returns the profile as its owner sees it, with the
+// links as saved and their full analytics.
 func (s *UserService) GetUserProfileInfo(username string) (models.User, error) {
+	return s.GetUserProfileForVisitor(username, "", username)
+}
+
+// GetUserProfileForVisitor is GetUserProfileInfo for a visitor: links under
+// A/B test are shown as the variant visitorId is assigned to, and the
+// impressions are counted. Without a visitor the links are shown as saved.
+// Unless viewerUsername is the owner, link analytics are left out or, if the
+// owner made them public, cut down to counts.
+func (s *UserService) GetUserProfileForVisitor(username string, visitorId string, viewerUsername string) (models.User, error) {
 	var user models.User
 
-	if err := s.db.Preload("Links").Preload("Links.Analytics").Where("username = ?", username).First(&user).Error; err != nil {
+	err := s.db.
+		Preload("Links", func(db *gorm.DB) *gorm.DB {
+			return db.Where("section_id IS NULL").Order("position, id")
+		}).
+		Preload("Links.Analytics").
+		Preload("Sections", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		}).
+		Preload("Sections.Links", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		}).
+		Preload("Sections.Links.Analytics").
+		Where("username = ?", username).First(&user).Error
+	if err != nil {
 		return user, fmt.Errorf("user not found: %v", err)
 	}
 
 	user.PasswordHash = ""
+
+	user.Links = visibleLinks(user.Links)
+	for i := range user.Sections {
+		user.Sections[i].Links = visibleLinks(user.Sections[i].Links)
+	}
+
+	var links []*models.Link
+	for i := range user.Links {
+		links = append(links, &user.Links[i])
+	}
+	for i := range user.Sections {
+		for j := range user.Sections[i].Links {
+			links = append(links, &user.Sections[i].Links[j])
+		}
+	}
+
+	if visitorId != "" {
+		if err := applyLinkVariants(s.db, links, visitorId); err != nil {
+			return user, err
+		}
+	}
+
+	owner := viewerUsername == user.Username
+	for _, link := range links {
+		if !owner {
+			link.PublicAnalytics(user.PublicClickCounts)
+		}
+		link.HideProtectedURL()
+	}
 	return user, nil
 }
 
@@ -108,16 +162,114 @@ func (s *UserService) UpdateUser(username string, updatedUser models.User) error
 	return s.db.Save(&user).Error
 }
 
-func (s *UserService) DeleteUser(username string) error {
-	result := s.db.Where("username = ?", username).Delete(&models.User{})
-
+// SetShowBotTraffic sets whether the user's click reports include automated
+// clicks.
+func (s *UserService) SetShowBotTraffic(username string, show bool) error {
+	result := s.db.Model(&models.User{}).Where("username = ?", username).UpdateColumn("show_bot_traffic", show)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user: %v", result.Error)
+	}
 	if result.RowsAffected == 0 {
 		return fmt.Errorf("user not found")
 	}
+	return nil
+}
 
+// SetAnalyticsPrivacy sets whether the user's public profile shows click
+// counts.
+func (s *UserService) SetAnalyticsPrivacy(username string, publicClickCounts bool) error {
+	result := s.db.Model(&models.User{}).Where("username = ?", username).UpdateColumn("public_click_counts", publicClickCounts)
 	if result.Error != nil {
-		return fmt.Errorf("failed to delete user: %v", result.Error)
+		return fmt.Errorf("failed to update user: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
 	}
-
 	return nil
 }
+
+// SetAdmins makes the users named in usernames admins and takes admin rights
+// from everyone else. It returns the names that have no account; signing up
+// with one of them later does not make an admin until SetAdmins runs again.
+func (s *UserService) SetAdmins(usernames []string) ([]string, error) {
+	var missing []string
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		revoke := tx.Model(&models.User{}).Where("admin = ?", true)
+		if len(usernames) > 0 {
+			revoke = revoke.Where("username NOT IN ?", usernames)
+		}
+		if err := revoke.UpdateColumn("admin", false).Error; err != nil {
+			return err
+		}
+		if len(usernames) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.User{}).Where("username IN ?", usernames).UpdateColumn("admin", true).Error; err != nil {
+			return err
+		}
+
+		var found []string
+		if err := tx.Model(&models.User{}).Where("username IN ?", usernames).Pluck("username", &found).Error; err != nil {
+			return err
+		}
+		existing := make(map[string]bool, len(found))
+		for _, username := range found {
+			existing[username] = true
+		}
+		for _, username := range usernames {
+			if !existing[username] {
+				missing = append(missing, username)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set admins: %v", err)
+	}
+	return missing, nil
+}
+
+// IsAdmin reports whether the user may use the admin endpoints.
+func (s *UserService) IsAdmin(username string) (bool, error) {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to load user: %v", err)
+	}
+	return user.Admin, nil
+}
+
+// DeleteUser deletes the user together with everything they own: links and
+// the data hanging off them, sections, blocks and profile views.
+func (s *UserService) DeleteUser(username string) error {
+	var user models.User
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		return fmt.Errorf("user not found")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var linkIds []uint
+		if err := tx.Model(&models.Link{}).Where("user_id = ?", user.ID).Pluck("id", &linkIds).Error; err != nil {
+			return fmt.Errorf("failed to load links: %v", err)
+		}
+		if len(linkIds) > 0 {
+			if err := deleteLinkDependents(tx, linkIds); err != nil {
+				return err
+			}
+		}
+
+		for _, owned := range []interface{}{&models.Link{}, &models.Block{}, &models.Section{}, &models.ProfileView{}} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(owned).Error; err != nil {
+				return fmt.Errorf("failed to delete user data: %v", err)
+			}
+		}
+
+		if err := tx.Delete(&user).Error; err != nil {
+			return fmt.Errorf("failed to delete user: %v", err)
+		}
+		return nil
+	})
+}
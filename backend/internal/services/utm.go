@@ -0,0 +1,183 @@
+package services
+
+import (
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxUTMValueLength = 200
+
+var utmPlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+var utmPlaceholders = map[string]bool{
+	"{username}": true,
+	"{link_id}":  true,
+	"{slug}":     true,
+}
+
+// SetProfileUTM sets the UTM template added to all of the user's outgoing
+// links. An empty template turns tagging off.
+func (s *UserService) SetProfileUTM(username string, template models.UTMTemplate) error {
+	if err := validateUTMTemplate(template); err != nil {
+		return err
+	}
+
+	var value *models.UTMTemplate
+	if template != (models.UTMTemplate{}) {
+		value = &template
+	}
+
+	result := s.db.Model(&models.User{}).Where("username = ?", username).Select("utm").Updates(&models.User{UTM: value})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update user: %v", result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetLinkUTM sets the UTM fields of one of the user's links that override the
+// profile template. An empty template falls back to the profile's.
+func (s *LinkService) SetLinkUTM(username string, linkId uint64, template models.UTMTemplate) error {
+	link, err := s.ownedLink(username, linkId)
+	if err != nil {
+		return err
+	}
+
+	if err := validateUTMTemplate(template); err != nil {
+		return err
+	}
+
+	link.UTM = nil
+	if template != (models.UTMTemplate{}) {
+		link.UTM = &template
+	}
+
+	if err := s.db.Model(&link).Select("utm").Updates(&link).Error; err != nil {
+		return fmt.Errorf("failed to update link: %v", err)
+	}
+
+	return nil
+}
+
+// TagOutgoingURL adds the UTM parameters of link and its owner's profile to
+// target, the URL a visitor is about to be sent to. The stored link URL is
+// left alone.
+func (s *LinkService) TagOutgoingURL(link models.Link, target string) (string, error) {
+	var owner models.User
+	if err := s.db.Select("id", "username", "utm").Where("id = ?", link.UserID).First(&owner).Error; err != nil {
+		return target, fmt.Errorf("user not found: %v", err)
+	}
+
+	template := mergeUTMTemplates(owner.UTM, link.UTM)
+	if template == (models.UTMTemplate{}) {
+		return target, nil
+	}
+
+	return AppendUTM(target, template, map[string]string{
+		"{username}": owner.Username,
+		"{link_id}":  strconv.FormatUint(uint64(link.ID), 10),
+		"{slug}":     link.Slug,
+	}), nil
+}
+
+// AppendUTM adds the parameters of template to an http(s) target, filling in
+// placeholders from values. Parameters the target already has are kept as
+// they are, and the rest of its query is not re-encoded.
+func AppendUTM(target string, template models.UTMTemplate, values map[string]string) string {
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return target
+	}
+
+	existing := parsed.Query()
+	added := url.Values{}
+	for _, param := range []struct{ key, value string }{
+		{"utm_source", template.Source},
+		{"utm_medium", template.Medium},
+		{"utm_campaign", template.Campaign},
+		{"utm_content", template.Content},
+		{"utm_term", template.Term},
+	} {
+		if param.value == "" || existing.Has(param.key) {
+			continue
+		}
+		value := utmPlaceholder.ReplaceAllStringFunc(param.value, func(placeholder string) string {
+			return values[placeholder]
+		})
+		added.Set(param.key, value)
+	}
+
+	if len(added) == 0 {
+		return target
+	}
+
+	if parsed.RawQuery == "" {
+		parsed.RawQuery = added.Encode()
+	} else {
+		parsed.RawQuery += "&" + added.Encode()
+	}
+	return parsed.String()
+}
+
+// mergeUTMTemplates overlays the non-empty fields of the link's template on
+// the profile's.
+func mergeUTMTemplates(profile, link *models.UTMTemplate) models.UTMTemplate {
+	var merged models.UTMTemplate
+	if profile != nil {
+		merged = *profile
+	}
+	if link == nil {
+		return merged
+	}
+
+	if link.Source != "" {
+		merged.Source = link.Source
+	}
+	if link.Medium != "" {
+		merged.Medium = link.Medium
+	}
+	if link.Campaign != "" {
+		merged.Campaign = link.Campaign
+	}
+	if link.Content != "" {
+		merged.Content = link.Content
+	}
+	if link.Term != "" {
+		merged.Term = link.Term
+	}
+	return merged
+}
+
+func validateUTMTemplate(template models.UTMTemplate) error {
+	for name, value := range map[string]string{
+		"source":   template.Source,
+		"medium":   template.Medium,
+		"campaign": template.Campaign,
+		"content":  template.Content,
+		"term":     template.Term,
+	} {
+		if len(value) > maxUTMValueLength {
+			return fmt.Errorf("utm %s must be at most %d characters", name, maxUTMValueLength)
+		}
+
+		for _, placeholder := range utmPlaceholder.FindAllString(value, -1) {
+			if !utmPlaceholders[placeholder] {
+				return fmt.Errorf("utm %s has unknown placeholder %s", name, placeholder)
+			}
+		}
+
+		if strings.ContainsAny(utmPlaceholder.ReplaceAllString(value, ""), "{}") {
+			return fmt.Errorf("utm %s has an unclosed placeholder", name)
+		}
+	}
+
+	return nil
+}
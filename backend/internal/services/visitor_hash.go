@@ -0,0 +1,126 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/models"
+	"net"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// uniqueVisitorsExpression counts the distinct visitors among click events
+// or profile views. Rows without a visitor hash cannot be told apart, so
+// each of them counts as a visitor of its own.
+const uniqueVisitorsExpression = "COUNT(DISTINCT NULLIF(visitor_hash, '')) + " +
+	"COALESCE(SUM(CASE WHEN visitor_hash = '' THEN 1 ELSE 0 END), 0)"
+
+// clickEvents selects the click events of the links selected by links in
+// [from, to), leaving out automated ones unless includeBots is set.
+func clickEvents(db *gorm.DB, from, to time.Time, includeBots bool, links func(*gorm.DB) *gorm.DB) *gorm.DB {
+	db = db.Model(&models.ClickEvent{}).Scopes(links).
+		Where("occurred_at >= ? AND occurred_at < ?", from.UTC(), to.UTC())
+	if !includeBots {
+		db = db.Where("bot = ?", false)
+	}
+	return db
+}
+
+// countUniqueVisitors counts the distinct visitors among the rows query
+// selects.
+func countUniqueVisitors(query *gorm.DB) (int64, error) {
+	var count int64
+	if err := query.Select(uniqueVisitorsExpression).Row().Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unique visitors: %v", err)
+	}
+	return count, nil
+}
+
+// visitorSalts keys visitor hashes with a random salt per UTC day. The salt
+// is shared through the database so every server hashes alike, and deleted
+// when the day is over.
+type visitorSalts struct {
+	db *gorm.DB
+
+	// mu guards the cached salt of the current day
+	mu   sync.Mutex
+	day  string
+	salt []byte
+}
+
+func newVisitorSalts(db *gorm.DB) *visitorSalts {
+	return &visitorSalts{db: db}
+}
+
+// hash returns a hash of a visitor's IP address and user agent that is the
+// same for every request they make on at's UTC day and unrelated to their
+// hashes of other days. Without an address there is nothing to hash.
+func (v *visitorSalts) hash(ip net.IP, userAgent string, at time.Time) (string, error) {
+	if ip == nil {
+		return "", nil
+	}
+
+	salt, err := v.saltFor(at.UTC().Format("2006-01-02"))
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ip.To16())
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16]), nil
+}
+
+// saltFor returns the salt of day, creating it on the first request of the
+// day and discarding the salts of the days before.
+func (v *visitorSalts) saltFor(day string) ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.day == day {
+		return v.salt, nil
+	}
+
+	fresh, err := newIPHashSalt()
+	if err != nil {
+		return nil, err
+	}
+
+	// Another server may have created the day's salt first; theirs wins.
+	err = v.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.VisitorSalt{Day: day, Salt: hex.EncodeToString(fresh)}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save visitor salt: %v", err)
+	}
+
+	var stored models.VisitorSalt
+	if err := v.db.Where("day = ?", day).First(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load visitor salt: %v", err)
+	}
+	salt, err := hex.DecodeString(stored.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid visitor salt: %v", err)
+	}
+
+	if err := v.db.Where("day < ?", day).Delete(&models.VisitorSalt{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to discard visitor salts: %v", err)
+	}
+
+	v.day, v.salt = day, salt
+	return salt, nil
+}
+
+// DiscardVisitorSalts deletes the salts of the UTC days before now's, for
+// when no click has rotated them yet.
+func DiscardVisitorSalts(db *gorm.DB, now time.Time) error {
+	if err := db.Where("day < ?", now.UTC().Format("2006-01-02")).Delete(&models.VisitorSalt{}).Error; err != nil {
+		return fmt.Errorf("failed to discard visitor salts: %v", err)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -67,3 +68,30 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// LoadAdminUsernames returns the usernames listed in the comma separated
+// ADMIN_USERNAMES environment variable. It is read once at startup to grant
+// the matching accounts admin rights.
+func LoadAdminUsernames() []string {
+	var usernames []string
+	for _, username := range strings.Split(os.Getenv("ADMIN_USERNAMES"), ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+	return usernames
+}
+
+// LoadTrustedProxies returns the addresses and CIDR ranges listed in the
+// comma separated TRUSTED_PROXIES environment variable. Only requests from
+// these may set the client address with X-Forwarded-For or X-Real-IP;
+// without any, the client address is always the connection's.
+func LoadTrustedProxies() []string {
+	var proxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
@@ -2,16 +2,21 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"linktree-mohamedfadel-backend/internal/api/handlers"
 	"linktree-mohamedfadel-backend/internal/api/middleware"
 	"linktree-mohamedfadel-backend/internal/models"
 	"linktree-mohamedfadel-backend/internal/services"
+	"linktree-mohamedfadel-backend/internal/utils"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -27,6 +32,10 @@ type HandlerTestSuite struct {
 	userHandler *handlers.UserHandler
 	linkHandler *handlers.LinkHandler
 	analytics   *handlers.AnalyticsHandler
+	sections    *handlers.SectionHandler
+	blocks      *handlers.BlockHandler
+	redirects   *handlers.RedirectHandler
+	admin       *handlers.AdminHandler
 }
 
 func (s *HandlerTestSuite) SetupSuite() {
@@ -40,41 +49,121 @@ func (s *HandlerTestSuite) SetupSuite() {
 
 	os.Setenv("JWT_SECRET", "test-secret-key")
 
-	s.db.AutoMigrate(&models.User{}, &models.Link{}, &models.Analytics{})
+	s.db.AutoMigrate(&models.User{}, &models.Section{}, &models.Link{}, &models.Analytics{}, &models.Block{}, &models.SlugAlias{}, &models.LinkHealth{}, &models.LinkVariant{}, &models.LinkTag{}, &models.LinkRevision{}, &models.ClickEvent{}, &models.ClickRollup{}, &models.RollupCursor{}, &models.ProfileView{}, &models.VisitorSalt{})
 
 	userService := services.NewUserService(s.db)
 	linkService := services.NewLinkService(s.db)
+	metadataConfig := services.DefaultMetadataFetcherConfig()
+	metadataConfig.AllowPrivateNetworks = true
+	linkService.SetMetadataFetcher(services.NewMetadataFetcher(metadataConfig))
+	urlPolicy := localURLPolicy()
+	linkService.SetURLPolicy(urlPolicy)
 	analyticsService := services.NewAnalyticsService(s.db)
+	sectionService := services.NewSectionService(s.db)
+	blockService := services.NewBlockService(s.db)
+	blockService.SetURLPolicy(urlPolicy)
 
-	s.userHandler = handlers.NewUserHandler(userService)
+	s.userHandler = handlers.NewUserHandler(userService, analyticsService)
 	s.linkHandler = handlers.NewLinkHandler(linkService)
 	s.analytics = handlers.NewAnalyticsHandler(analyticsService)
+	s.sections = handlers.NewSectionHandler(sectionService)
+	s.blocks = handlers.NewBlockHandler(blockService, analyticsService)
+	s.redirects = handlers.NewRedirectHandler(linkService, analyticsService)
+	s.admin = handlers.NewAdminHandler(urlPolicy)
 
 	s.router = gin.New()
+	s.router.SetTrustedProxies(utils.LoadTrustedProxies())
 	s.setupRoutes()
 }
 
 func (s *HandlerTestSuite) setupRoutes() {
 	s.router.POST("/users/signup", s.userHandler.SignUpHandler)
 	s.router.POST("/users/login", s.userHandler.LoginHandler)
-	s.router.GET("/users/:username", s.userHandler.GetUserProfileInfoHandler)
+	s.router.GET("/users/:username", middleware.OptionalJWTFromContext(), s.userHandler.GetUserProfileInfoHandler)
+	s.router.GET("/users/:username/blocks", s.blocks.GetBlocksHandler)
 
 	protected := s.router.Group("")
 	protected.Use(middleware.ValidateJWTFromContext())
 	{
 		protected.PUT("/users", s.userHandler.UpdateUserHandler)
 		protected.DELETE("/users", s.userHandler.DeleteUserHandler)
+		protected.PUT("/users/utm", s.userHandler.SetProfileUTMHandler)
+		protected.PUT("/users/privacy", s.userHandler.SetAnalyticsPrivacyHandler)
+		protected.PUT("/users/bot-traffic", s.userHandler.SetBotTrafficHandler)
+		protected.GET("/links", s.linkHandler.ListLinksHandler)
 		protected.POST("/links", s.linkHandler.CreateLinkHandler)
+		protected.GET("/links/search", s.linkHandler.SearchLinksHandler)
+		protected.GET("/links/health", s.linkHandler.GetLinksHealthHandler)
+		protected.GET("/links/export", s.linkHandler.ExportLinksHandler)
+		protected.POST("/links/import", s.linkHandler.ImportLinksHandler)
+		protected.PUT("/links/order", s.sections.ReorderUngroupedLinksHandler)
 		protected.PUT("/links/:id", s.linkHandler.UpdateLinkHandler)
+		protected.POST("/links/:id/metadata", s.linkHandler.RefreshLinkMetadataHandler)
+		protected.PUT("/links/:id/protection", s.linkHandler.SetLinkProtectionHandler)
+		protected.PUT("/links/:id/rules", s.linkHandler.SetLinkRulesHandler)
+		protected.POST("/links/:id/rules/test", s.linkHandler.TestLinkRulesHandler)
+		protected.PUT("/links/:id/utm", s.linkHandler.SetLinkUTMHandler)
+		protected.PUT("/links/:id/limits", s.linkHandler.SetLinkLimitsHandler)
+		protected.PUT("/links/:id/tags", s.linkHandler.SetLinkTagsHandler)
+		protected.GET("/links/:id/revisions", s.linkHandler.GetLinkRevisionsHandler)
+		protected.POST("/links/:id/revisions/:revisionId/rollback", s.linkHandler.RollbackLinkHandler)
+		protected.GET("/links/:id/variants", s.analytics.GetVariantReportHandler)
+		protected.POST("/links/:id/variants", s.linkHandler.AddLinkVariantHandler)
+		protected.DELETE("/links/:id/variants/:variantId", s.linkHandler.DeleteLinkVariantHandler)
+		protected.POST("/links/:id/variants/:variantId/promote", s.linkHandler.PromoteLinkVariantHandler)
 		protected.DELETE("/links/:id", s.linkHandler.DeleteLinkHandler)
+		protected.GET("/sections", s.sections.GetSectionsHandler)
+		protected.POST("/sections", s.sections.CreateSectionHandler)
+		protected.PUT("/sections/order", s.sections.ReorderSectionsHandler)
+		protected.PUT("/sections/:id", s.sections.UpdateSectionHandler)
+		protected.PUT("/sections/:id/links/order", s.sections.ReorderSectionLinksHandler)
+		protected.DELETE("/sections/:id", s.sections.DeleteSectionHandler)
+		protected.POST("/blocks", s.blocks.CreateBlockHandler)
+		protected.PUT("/blocks/order", s.blocks.ReorderBlocksHandler)
+		protected.PUT("/blocks/:id", s.blocks.UpdateBlockHandler)
+		protected.DELETE("/blocks/:id", s.blocks.DeleteBlockHandler)
+		protected.GET("/analytics/links/:id", s.analytics.GetLinkClickSeriesHandler)
+		protected.GET("/analytics/profile", s.analytics.GetProfileClickSeriesHandler)
+		protected.GET("/analytics/links/:id/breakdown", s.analytics.GetLinkClickBreakdownHandler)
+		protected.GET("/analytics/profile/breakdown", s.analytics.GetProfileClickBreakdownHandler)
+		protected.GET("/analytics/ctr", s.analytics.GetCTRReportHandler)
 	}
 
-	s.router.POST("/analytics/:id/click", s.analytics.TrackLinkClickHandler)
+	admin := s.router.Group("/admin")
+	admin.Use(middleware.ValidateJWTFromContext(), middleware.RequireAdminFromContext(s.userHandler.UserService.IsAdmin))
+	{
+		admin.GET("/blocklist", s.admin.GetBlocklistHandler)
+		admin.POST("/blocklist", s.admin.BlockDomainsHandler)
+		admin.DELETE("/blocklist/:domain", s.admin.UnblockDomainHandler)
+		admin.GET("/analytics/ingestion", s.analytics.GetIngestionStatsHandler)
+	}
+
+	redirects := s.router.Group("")
+	redirects.Use(middleware.OptionalJWTFromContext())
+	{
+		redirects.POST("/analytics/:id/click", s.analytics.TrackLinkClickHandler)
+		redirects.GET("/l/:id", s.redirects.RedirectLinkHandler)
+		redirects.GET("/r/:slug", s.redirects.RedirectSlugHandler)
+		redirects.POST("/l/:id", s.redirects.UnlockLinkHandler)
+		redirects.POST("/r/:slug", s.redirects.UnlockSlugHandler)
+	}
 }
 
 func (s *HandlerTestSuite) SetupTest() {
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.SlugAlias{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkHealth{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkVariant{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkTag{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkRevision{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ClickEvent{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ClickRollup{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.RollupCursor{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ProfileView{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.VisitorSalt{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Block{})
 	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Analytics{})
 	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Link{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Section{})
 	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.User{})
 }
 
@@ -108,6 +197,19 @@ func (s *HandlerTestSuite) makeRequest(method, url string, body interface{}, hea
 	return w
 }
 
+func (s *HandlerTestSuite) makeRawRequest(method, url, body, contentType string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
 func (s *HandlerTestSuite) TestSignUpHandler() {
 	testCases := []struct {
 		name       string
@@ -418,9 +520,9 @@ func (s *HandlerTestSuite) TestCreateLinkHandler() {
 			wantStatus: http.StatusCreated,
 		},
 		{
-			name: "Missing Title",
+			name: "Missing URL",
 			payload: handlers.CreateLinkRequest{
-				URL: "https://example.com",
+				Title: "Test Link",
 			},
 			token:      token,
 			wantStatus: http.StatusBadRequest,
@@ -434,6 +536,15 @@ func (s *HandlerTestSuite) TestCreateLinkHandler() {
 			token:      token,
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Disallowed Scheme",
+			payload: handlers.CreateLinkRequest{
+				Title: "Test Link",
+				URL:   "javascript:alert(document.cookie)",
+			},
+			token:      token,
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name: "No Authentication",
 			payload: handlers.CreateLinkRequest{
@@ -685,4 +796,1838 @@ func (s *HandlerTestSuite) TestTrackLinkClickHandler() {
 			}
 		})
 	}
+
+	s.Run("Click Event Details", func() {
+		s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ClickEvent{})
+
+		w := s.makeRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click", link.ID), nil, map[string]string{
+			"Referer":      "https://www.instagram.com/",
+			"User-Agent":   "Mozilla/5.0 (iPhone)",
+			"CF-IPCountry": "fr",
+		})
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+
+		var event models.ClickEvent
+		assert.NoError(s.T(), s.db.Where("link_id = ?", link.ID).First(&event).Error)
+		assert.Equal(s.T(), "https://www.instagram.com/", event.Referrer)
+		assert.Equal(s.T(), "Mozilla/5.0 (iPhone)", event.UserAgent)
+		assert.Equal(s.T(), "FR", event.Country)
+	})
+}
+
+func (s *HandlerTestSuite) TestSectionHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	w = s.makeRequest(http.MethodPost, "/sections", handlers.CreateSectionRequest{Title: "Podcasts"}, auth)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+
+	var section models.Section
+	json.Unmarshal(w.Body.Bytes(), &section)
+	assert.NotZero(s.T(), section.ID)
+
+	w = s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{
+		Title:     "Episode 1",
+		URL:       "https://example.com/1",
+		SectionID: &section.ID,
+	}, auth)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	var profile models.User
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Len(s.T(), profile.Links, 0)
+	assert.Len(s.T(), profile.Sections, 1)
+	assert.Len(s.T(), profile.Sections[0].Links, 1)
+
+	sectionPath := fmt.Sprintf("/sections/%d", section.ID)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		payload    interface{}
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "Create Without Title",
+			method:     http.MethodPost,
+			url:        "/sections",
+			payload:    handlers.CreateSectionRequest{},
+			headers:    auth,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "List Without Authentication",
+			method:     http.MethodGet,
+			url:        "/sections",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Rename Section",
+			method:     http.MethodPut,
+			url:        sectionPath,
+			payload:    handlers.UpdateSectionRequest{Title: "Shows"},
+			headers:    auth,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Reorder With Unknown Section",
+			method:     http.MethodPut,
+			url:        "/sections/order",
+			payload:    handlers.ReorderRequest{IDs: []uint{9999}},
+			headers:    auth,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Reorder Sections",
+			method:     http.MethodPut,
+			url:        "/sections/order",
+			payload:    handlers.ReorderRequest{IDs: []uint{section.ID}},
+			headers:    auth,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Delete Without Links Choice",
+			method:     http.MethodDelete,
+			url:        sectionPath,
+			headers:    auth,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Delete Moving Links",
+			method:     http.MethodDelete,
+			url:        sectionPath + "?links=move",
+			headers:    auth,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Delete Missing Section",
+			method:     http.MethodDelete,
+			url:        sectionPath + "?links=move",
+			headers:    auth,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(tc.method, tc.url, tc.payload, tc.headers)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Len(s.T(), profile.Links, 1)
+	assert.Len(s.T(), profile.Sections, 0)
+
+	s.Run("Reorder Ungrouped Links", func() {
+		w := s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Episode 2", URL: "https://example.com/2"}, auth)
+		assert.Equal(s.T(), http.StatusCreated, w.Code)
+
+		w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+		var profile models.User
+		json.Unmarshal(w.Body.Bytes(), &profile)
+		if !assert.Len(s.T(), profile.Links, 2) {
+			return
+		}
+		first, second := profile.Links[0].ID, profile.Links[1].ID
+
+		w = s.makeRequest(http.MethodPut, "/links/order", handlers.ReorderRequest{IDs: []uint{second}}, auth)
+		assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+		w = s.makeRequest(http.MethodPut, "/links/order", handlers.ReorderRequest{IDs: []uint{second, first}}, auth)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+
+		w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+		json.Unmarshal(w.Body.Bytes(), &profile)
+		assert.Equal(s.T(), "Episode 2", profile.Links[0].Title)
+		assert.Equal(s.T(), "Episode 1", profile.Links[1].Title)
+	})
+}
+
+func (s *HandlerTestSuite) TestBlockHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{
+		Title: "Test Link",
+		URL:   "https://example.com",
+	}, auth)
+
+	testCases := []struct {
+		name       string
+		payload    handlers.CreateBlockRequest
+		token      map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "Text Block",
+			payload:    handlers.CreateBlockRequest{Type: "text", Payload: json.RawMessage(`{"text":"About me"}`)},
+			token:      auth,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Divider Block",
+			payload:    handlers.CreateBlockRequest{Type: "divider"},
+			token:      auth,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "Invalid Payload",
+			payload:    handlers.CreateBlockRequest{Type: "image", Payload: json.RawMessage(`{"url":"file:///etc/passwd"}`)},
+			token:      auth,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Link Block",
+			payload:    handlers.CreateBlockRequest{Type: "link"},
+			token:      auth,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "No Authentication",
+			payload:    handlers.CreateBlockRequest{Type: "divider"},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPost, "/blocks", tc.payload, tc.token)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser/blocks", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var blocks []models.Block
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &blocks))
+	assert.Len(s.T(), blocks, 3)
+	assert.Equal(s.T(), models.BlockTypeLink, blocks[0].Type)
+	assert.NotNil(s.T(), blocks[0].Link)
+	assert.Equal(s.T(), models.BlockTypeText, blocks[1].Type)
+	assert.JSONEq(s.T(), `{"text":"About me"}`, string(blocks[1].Payload))
+	assert.Equal(s.T(), models.BlockTypeDivider, blocks[2].Type)
+
+	w = s.makeRequest(http.MethodGet, "/users/nonexistent/blocks", nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+func (s *HandlerTestSuite) TestRedirectLinkHandler() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{
+		Title: "Plain Link",
+		URL:   "https://example.com",
+		Slug:  "plain-link",
+	}, auth)
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{
+		Title:     "Sensitive Link",
+		URL:       "https://sensitive.example.com",
+		Sensitive: true,
+	}, auth)
+
+	var plain, sensitive models.Link
+	s.db.Where("url = ?", "https://example.com").First(&plain)
+	s.db.Where("url = ?", "https://sensitive.example.com").First(&sensitive)
+	assert.True(s.T(), sensitive.Sensitive)
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	var profile models.User
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Equal(s.T(), "/r/plain-link", profile.Links[0].TrackedURL)
+
+	testCases := []struct {
+		name         string
+		url          string
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:         "Redirects And Tracks",
+			url:          fmt.Sprintf("/l/%d", plain.ID),
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com",
+		},
+		{
+			name:         "Redirects By Slug",
+			url:          "/r/plain-link",
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://example.com",
+		},
+		{
+			name:       "Unknown Slug",
+			url:        "/r/unknown-slug",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Sensitive Link Shows Interstitial",
+			url:        fmt.Sprintf("/l/%d", sensitive.ID),
+			wantStatus: http.StatusOK,
+			wantBody:   "confirm=true",
+		},
+		{
+			name:         "Sensitive Link Confirmed",
+			url:          fmt.Sprintf("/l/%d?confirm=true", sensitive.ID),
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://sensitive.example.com",
+		},
+		{
+			name:       "Invalid Link ID",
+			url:        "/l/invalid",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Non-existent Link",
+			url:        "/l/999999",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodGet, tc.url, nil, nil)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+			if tc.wantLocation != "" {
+				assert.Equal(s.T(), tc.wantLocation, w.Header().Get("Location"))
+			}
+			if tc.wantBody != "" {
+				assert.True(s.T(), strings.Contains(w.Body.String(), tc.wantBody))
+			}
+		})
+	}
+
+	var analytics models.Analytics
+	s.db.Where("link_id = ?", plain.ID).First(&analytics)
+	assert.Equal(s.T(), uint(2), analytics.ClickCount)
+
+	var sensitiveClicks int64
+	s.db.Model(&models.Analytics{}).Where("link_id = ? AND click_count = 1", sensitive.ID).Count(&sensitiveClicks)
+	assert.Equal(s.T(), int64(1), sensitiveClicks)
+
+	unflag := false
+	w = s.makeRequest(http.MethodPut, fmt.Sprintf("/links/%d", sensitive.ID), handlers.UpdateLinkRequest{Sensitive: &unflag}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.makeRequest(http.MethodGet, fmt.Sprintf("/l/%d", sensitive.ID), nil, nil)
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+}
+
+func (s *HandlerTestSuite) TestImportExportHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	bookmarks := `<DL><p><DT><H3>Reading</H3><DL><p><DT><A HREF="https://blog.example.com">Blog</A></DL><p></DL>`
+	csvBody := "title,url\nDocs,https://docs.example.com\nBroken,not-a-url\n"
+
+	testCases := []struct {
+		name        string
+		url         string
+		body        string
+		contentType string
+		wantStatus  int
+		wantValid   int
+	}{
+		{
+			name:        "Unsupported Format",
+			url:         "/links/import?format=xml",
+			body:        "<links/>",
+			contentType: "application/xml",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:        "CSV With Invalid Row",
+			url:         "/links/import?format=csv",
+			body:        csvBody,
+			contentType: "text/csv",
+			wantStatus:  http.StatusBadRequest,
+			wantValid:   1,
+		},
+		{
+			name:        "Bookmarks Dry Run",
+			url:         "/links/import?format=html&dry_run=true",
+			body:        bookmarks,
+			contentType: "text/html",
+			wantStatus:  http.StatusOK,
+			wantValid:   1,
+		},
+		{
+			name:        "Bookmarks",
+			url:         "/links/import?format=html",
+			body:        bookmarks,
+			contentType: "text/html",
+			wantStatus:  http.StatusOK,
+			wantValid:   1,
+		},
+		{
+			name:        "JSON",
+			url:         "/links/import?format=json",
+			body:        `[{"title":"Shop","url":"https://shop.example.com"}]`,
+			contentType: "application/json",
+			wantStatus:  http.StatusOK,
+			wantValid:   1,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRawRequest(http.MethodPost, tc.url, tc.body, tc.contentType, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+
+			if tc.wantValid > 0 {
+				var report services.ImportReport
+				assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+				assert.Equal(s.T(), tc.wantValid, report.Valid)
+			}
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/links/export?format=csv", nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Header().Get("Content-Type"), "text/csv")
+	assert.Contains(s.T(), w.Body.String(), "Blog,https://blog.example.com")
+	assert.Contains(s.T(), w.Body.String(), "Reading")
+
+	w = s.makeRequest(http.MethodGet, "/links/export", nil, auth)
+	var records []services.LinkRecord
+	json.Unmarshal(w.Body.Bytes(), &records)
+	assert.Len(s.T(), records, 2)
+	assert.Equal(s.T(), "Shop", records[0].Title)
+
+	w = s.makeRequest(http.MethodGet, "/links/export", nil, nil)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *HandlerTestSuite) TestGetLinksHealthHandler() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Up", URL: "https://up.example.com"}, auth)
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Down", URL: "https://down.example.com"}, auth)
+
+	var up, down models.Link
+	s.db.Where("title = ?", "Up").First(&up)
+	s.db.Where("title = ?", "Down").First(&down)
+	s.db.Create(&models.LinkHealth{LinkID: up.ID, Healthy: true, LastStatus: http.StatusOK, CheckedAt: time.Now()})
+	s.db.Create(&models.LinkHealth{LinkID: down.ID, LastStatus: http.StatusNotFound, FailureStreak: 3, CheckedAt: time.Now()})
+
+	testCases := []struct {
+		name       string
+		url        string
+		headers    map[string]string
+		wantStatus int
+		wantLinks  int
+	}{
+		{
+			name:       "Unauthorized",
+			url:        "/links/health",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "All Links",
+			url:        "/links/health",
+			headers:    auth,
+			wantStatus: http.StatusOK,
+			wantLinks:  2,
+		},
+		{
+			name:       "Broken Only",
+			url:        "/links/health?broken=true",
+			headers:    auth,
+			wantStatus: http.StatusOK,
+			wantLinks:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodGet, tc.url, nil, tc.headers)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+
+			if tc.wantStatus == http.StatusOK {
+				var links []models.Link
+				assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &links))
+				assert.Len(s.T(), links, tc.wantLinks)
+				for _, link := range links {
+					assert.NotNil(s.T(), link.Health)
+				}
+			}
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/links/health?broken=true", nil, auth)
+	var broken []models.Link
+	json.Unmarshal(w.Body.Bytes(), &broken)
+	if assert.Len(s.T(), broken, 1) {
+		assert.Equal(s.T(), "Down", broken[0].Title)
+		assert.Equal(s.T(), 3, broken[0].Health.FailureStreak)
+	}
+}
+
+func (s *HandlerTestSuite) TestLinkMetadataHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	var blockedFetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/blocked" {
+			atomic.AddInt32(&blockedFetches, 1)
+		}
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/slow" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(3 * time.Second):
+			}
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<head><meta property="og:title" content="Page %s"><meta property="og:image" content="/og.png"></head>`, r.URL.Path)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		payload    handlers.CreateLinkRequest
+		wantStatus int
+		wantTitle  string
+	}{
+		{
+			name:       "Title From Page",
+			payload:    handlers.CreateLinkRequest{URL: server.URL + "/auto"},
+			wantStatus: http.StatusCreated,
+			wantTitle:  "Page /auto",
+		},
+		{
+			name:       "Keep Given Title",
+			payload:    handlers.CreateLinkRequest{Title: "Mine", URL: server.URL + "/mine", FetchMetadata: true},
+			wantStatus: http.StatusCreated,
+			wantTitle:  "Mine",
+		},
+		{
+			name:       "Fetch Failure",
+			payload:    handlers.CreateLinkRequest{URL: server.URL + "/gone"},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPost, "/links", tc.payload, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+
+			if tc.wantStatus == http.StatusCreated {
+				var link models.Link
+				s.db.Where("url = ?", tc.payload.URL).First(&link)
+				assert.Equal(s.T(), tc.wantTitle, link.Title)
+				assert.Equal(s.T(), server.URL+"/og.png", link.ImageURL)
+			}
+		})
+	}
+
+	s.Run("Cancelled Request Stops The Fetch", func() {
+		body, _ := json.Marshal(handlers.CreateLinkRequest{URL: server.URL + "/slow"})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		req := httptest.NewRequest(http.MethodPost, "/links", bytes.NewReader(body)).WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", auth["Authorization"])
+
+		started := time.Now()
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+		assert.Contains(s.T(), w.Body.String(), "context canceled")
+		assert.Less(s.T(), time.Since(started), time.Second)
+	})
+
+	s.Run("Blocked URL Is Not Fetched", func() {
+		assert.NoError(s.T(), s.admin.URLPolicy.BlockDomains([]string{"localhost"}))
+		defer s.admin.URLPolicy.UnblockDomain("localhost")
+
+		blockedURL := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/blocked"
+		for _, payload := range []handlers.CreateLinkRequest{
+			{URL: blockedURL},
+			{Title: "Blocked", URL: blockedURL, FetchMetadata: true},
+		} {
+			w := s.makeRequest(http.MethodPost, "/links", payload, auth)
+			assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+			assert.Contains(s.T(), w.Body.String(), "url rejected")
+		}
+		assert.Equal(s.T(), int32(0), atomic.LoadInt32(&blockedFetches))
+	})
+
+	var link models.Link
+	s.db.Where("url = ?", server.URL+"/mine").First(&link)
+	s.db.Model(&link).Update("image_url", "")
+
+	w = s.makeRequest(http.MethodPost, fmt.Sprintf("/links/%d/metadata", link.ID), nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var refreshed models.Link
+	json.Unmarshal(w.Body.Bytes(), &refreshed)
+	assert.Equal(s.T(), "Mine", refreshed.Title)
+	assert.Equal(s.T(), server.URL+"/og.png", refreshed.ImageURL)
+
+	w = s.makeRequest(http.MethodPost, "/links/9999/metadata", nil, auth)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+func (s *HandlerTestSuite) TestBlocklistHandlers() {
+	os.Setenv("ADMIN_USERNAMES", "admin, moderator")
+	defer os.Unsetenv("ADMIN_USERNAMES")
+
+	tokens := map[string]string{}
+	signUp := func(username string) {
+		s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+			FullName: "Test User",
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		var loginResponse map[string]string
+		json.Unmarshal(w.Body.Bytes(), &loginResponse)
+		tokens[username] = loginResponse["token"]
+	}
+	signUp("admin")
+	signUp("testuser")
+
+	missing, err := s.userHandler.UserService.SetAdmins(utils.LoadAdminUsernames())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"moderator"}, missing)
+
+	// A listed name registered after startup is not an admin.
+	signUp("moderator")
+	defer s.admin.URLPolicy.UnblockDomain("phishing.example")
+
+	testCases := []struct {
+		name        string
+		method      string
+		url         string
+		body        interface{}
+		token       string
+		wantStatus  int
+		wantDomains []string
+	}{
+		{
+			name:       "Not Admin",
+			method:     http.MethodGet,
+			url:        "/admin/blocklist",
+			token:      tokens["testuser"],
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "Listed Name Claimed Later",
+			method:     http.MethodGet,
+			url:        "/admin/blocklist",
+			token:      tokens["moderator"],
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:        "Block",
+			method:      http.MethodPost,
+			url:         "/admin/blocklist",
+			body:        handlers.BlockDomainsRequest{Domains: []string{"phishing.example"}},
+			token:       tokens["admin"],
+			wantStatus:  http.StatusOK,
+			wantDomains: []string{"phishing.example"},
+		},
+		{
+			name:       "Invalid Domain",
+			method:     http.MethodPost,
+			url:        "/admin/blocklist",
+			body:       handlers.BlockDomainsRequest{Domains: []string{"bad domain"}},
+			token:      tokens["admin"],
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "List",
+			method:      http.MethodGet,
+			url:         "/admin/blocklist",
+			token:       tokens["admin"],
+			wantStatus:  http.StatusOK,
+			wantDomains: []string{"phishing.example"},
+		},
+		{
+			name:       "Unblock Unknown",
+			method:     http.MethodDelete,
+			url:        "/admin/blocklist/unknown.example",
+			token:      tokens["admin"],
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(tc.method, tc.url, tc.body, map[string]string{"Authorization": "Bearer " + tc.token})
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+
+			if tc.wantDomains != nil {
+				var response handlers.BlocklistResponse
+				assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(s.T(), tc.wantDomains, response.Domains)
+			}
+		})
+	}
+
+	user := map[string]string{"Authorization": "Bearer " + tokens["testuser"]}
+	w := s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Prize", URL: "https://win.phishing.example"}, user)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "is blocked")
+
+	w = s.makeRequest(http.MethodDelete, "/admin/blocklist/phishing.example", nil, map[string]string{"Authorization": "Bearer " + tokens["admin"]})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Prize", URL: "https://win.phishing.example"}, user)
+	assert.Equal(s.T(), http.StatusCreated, w.Code)
+
+	s.Run("Blocked After Saving", func() {
+		var link models.Link
+		s.db.Where("url = ?", "https://win.phishing.example").First(&link)
+		redirectURL := fmt.Sprintf("/l/%d", link.ID)
+
+		w := s.makeRequest(http.MethodPost, "/admin/blocklist", handlers.BlockDomainsRequest{Domains: []string{"phishing.example"}}, map[string]string{"Authorization": "Bearer " + tokens["admin"]})
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+
+		w = s.makeRequest(http.MethodGet, redirectURL, nil, nil)
+		assert.Equal(s.T(), http.StatusForbidden, w.Code)
+		assert.Empty(s.T(), w.Header().Get("Location"))
+
+		var analytics models.Analytics
+		s.db.Where("link_id = ?", link.ID).First(&analytics)
+		assert.Zero(s.T(), analytics.ClickCount)
+
+		s.admin.URLPolicy.UnblockDomain("phishing.example")
+		w = s.makeRequest(http.MethodGet, redirectURL, nil, nil)
+		assert.Equal(s.T(), http.StatusFound, w.Code)
+		assert.Equal(s.T(), "https://win.phishing.example", w.Header().Get("Location"))
+	})
+}
+
+func (s *HandlerTestSuite) TestLinkProtectionHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Secret", URL: "https://secret.example.com", Slug: "secret"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "secret").First(&link)
+	protectionURL := fmt.Sprintf("/links/%d/protection", link.ID)
+
+	password := "open-sesame"
+	short := "abc"
+	testCases := []struct {
+		name       string
+		body       handlers.LinkProtectionRequest
+		wantStatus int
+	}{
+		{name: "Empty Settings", body: handlers.LinkProtectionRequest{}, wantStatus: http.StatusBadRequest},
+		{name: "Short Password", body: handlers.LinkProtectionRequest{Password: &short}, wantStatus: http.StatusBadRequest},
+		{name: "Set Password", body: handlers.LinkProtectionRequest{Password: &password}, wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPut, protectionURL, tc.body, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.NotContains(s.T(), w.Body.String(), "secret.example.com")
+	assert.Contains(s.T(), w.Body.String(), `"password_protected":true`)
+
+	w = s.makeRequest(http.MethodGet, "/r/secret", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Contains(s.T(), w.Body.String(), `type="password"`)
+	assert.NotContains(s.T(), w.Body.String(), "secret.example.com")
+
+	unlockFrom := func(password, ip, forwardedFor string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/r/secret", strings.NewReader("password="+password))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.RemoteAddr = ip + ":1234"
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	unlock := func(password, ip string) *httptest.ResponseRecorder {
+		return unlockFrom(password, ip, "")
+	}
+
+	w = unlock("wrong", "192.0.2.1")
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "incorrect password")
+
+	w = unlock(password, "192.0.2.1")
+	assert.Equal(s.T(), http.StatusSeeOther, w.Code)
+	assert.Equal(s.T(), "https://secret.example.com", w.Header().Get("Location"))
+
+	for i := 0; i < 5; i++ {
+		unlock("wrong", "192.0.2.2")
+	}
+	w = unlock(password, "192.0.2.2")
+	assert.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(s.T(), w.Header().Get("Retry-After"))
+
+	s.Run("Rotating Forwarded For", func() {
+		for i := 0; i < 5; i++ {
+			w := unlockFrom("wrong", "192.0.2.3", fmt.Sprintf("198.51.100.%d", i+1))
+			assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+		}
+		w := unlockFrom(password, "192.0.2.3", "198.51.100.99")
+		assert.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	})
+
+	s.Run("Guesses From Many Clients", func() {
+		// 192.0.2.1, .2 and .3 have already failed 11 times between them.
+		for i := 0; i < 9; i++ {
+			w := unlock("wrong", fmt.Sprintf("192.0.2.%d", 10+i))
+			assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+		}
+		w := unlock(password, "192.0.2.50")
+		assert.Equal(s.T(), http.StatusTooManyRequests, w.Code)
+	})
+
+	sensitive := true
+	empty := ""
+	w = s.makeRequest(http.MethodPut, protectionURL, handlers.LinkProtectionRequest{Password: &empty, Sensitive: &sensitive}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/r/secret", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	w = s.makeRequest(http.MethodGet, "/r/secret?confirm=true", nil, nil)
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+
+	var analytics models.Analytics
+	s.db.Where("link_id = ?", link.ID).First(&analytics)
+	assert.Equal(s.T(), uint(2), analytics.UnlockCount)
+	assert.Equal(s.T(), uint(2), analytics.ClickCount)
+}
+
+func (s *HandlerTestSuite) TestLinkRulesHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "App", URL: "https://app.example.com", Slug: "my-app"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "my-app").First(&link)
+	rulesURL := fmt.Sprintf("/links/%d/rules", link.ID)
+
+	iosRule := []models.LinkRule{{Platforms: []string{models.PlatformIOS}, Target: "https://apps.apple.com/app/id1"}}
+	testCases := []struct {
+		name       string
+		body       handlers.LinkRulesRequest
+		wantStatus int
+	}{
+		{name: "Invalid Rule", body: handlers.LinkRulesRequest{Rules: []models.LinkRule{{Platforms: []string{"beos"}, Target: "https://a.example.com"}}}, wantStatus: http.StatusBadRequest},
+		{name: "Set Rules", body: handlers.LinkRulesRequest{Rules: iosRule}, wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPut, rulesURL, tc.body, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	iPhone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Mobile/15E148"
+	w = s.makeRequest(http.MethodPost, rulesURL+"/test", handlers.TestLinkRulesRequest{UserAgent: iPhone}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var result services.RuleResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(s.T(), 0, result.RuleIndex)
+	assert.Equal(s.T(), models.PlatformIOS, result.Platform)
+	assert.Equal(s.T(), "https://apps.apple.com/app/id1", result.Target)
+
+	w = s.makeRequest(http.MethodGet, "/r/my-app", nil, map[string]string{"User-Agent": iPhone})
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+	assert.Equal(s.T(), "https://apps.apple.com/app/id1", w.Header().Get("Location"))
+
+	w = s.makeRequest(http.MethodGet, "/r/my-app", nil, map[string]string{"User-Agent": "Mozilla/5.0 (Windows NT 10.0; Win64; x64)"})
+	assert.Equal(s.T(), "https://app.example.com", w.Header().Get("Location"))
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.NotContains(s.T(), w.Body.String(), "apps.apple.com")
+}
+
+func (s *HandlerTestSuite) TestLinkVariantHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Podcast", URL: "https://podcast.example.com", Slug: "podcast"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "podcast").First(&link)
+	variantsURL := fmt.Sprintf("/links/%d/variants", link.ID)
+
+	testCases := []struct {
+		name       string
+		body       handlers.LinkVariantRequest
+		wantStatus int
+	}{
+		{name: "Empty Variant", body: handlers.LinkVariantRequest{Weight: 2}, wantStatus: http.StatusBadRequest},
+		{name: "Blocked URL", body: handlers.LinkVariantRequest{URL: "javascript:alert(1)"}, wantStatus: http.StatusBadRequest},
+		{name: "Title Variant", body: handlers.LinkVariantRequest{Title: "Podcast"}, wantStatus: http.StatusCreated},
+		{name: "URL Variant", body: handlers.LinkVariantRequest{Title: "Latest episode", URL: "https://podcast.example.com/latest"}, wantStatus: http.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPost, variantsURL, tc.body, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	cookies := w.Result().Cookies()
+	assert.Len(s.T(), cookies, 1)
+	visitor := map[string]string{"Cookie": cookies[0].Name + "=" + cookies[0].Value}
+
+	var profile models.User
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.NotNil(s.T(), profile.Links[0].VariantID)
+	variantId := *profile.Links[0].VariantID
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, visitor)
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	assert.Equal(s.T(), variantId, *profile.Links[0].VariantID)
+	assert.Empty(s.T(), w.Result().Cookies())
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser/blocks", nil, visitor)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var blocks []models.Block
+	json.Unmarshal(w.Body.Bytes(), &blocks)
+	assert.Len(s.T(), blocks, 1)
+	assert.Equal(s.T(), variantId, *blocks[0].Link.VariantID)
+	assert.Equal(s.T(), profile.Links[0].TrackedURL, blocks[0].Link.TrackedURL)
+
+	w = s.makeRequest(http.MethodGet, profile.Links[0].TrackedURL, nil, nil)
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+	assert.Equal(s.T(), profile.Links[0].URL, w.Header().Get("Location"))
+
+	w = s.makeRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click?variant=%d", link.ID, variantId), nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	s.Run("Bots Are Not Impressions", func() {
+		s.analytics.AnalyticsService.SetBotClassifier(services.NewBotClassifier(services.DefaultBotClassifierConfig()))
+		defer s.analytics.AnalyticsService.SetBotClassifier(nil)
+		crawler := map[string]string{"User-Agent": "Googlebot/2.1 (+http://www.google.com/bot.html)", "Cookie": visitor["Cookie"]}
+
+		w := s.makeRequest(http.MethodGet, "/users/testuser", nil, crawler)
+		var seen models.User
+		json.Unmarshal(w.Body.Bytes(), &seen)
+		assert.Nil(s.T(), seen.Links[0].VariantID)
+
+		w = s.makeRequest(http.MethodGet, "/users/testuser/blocks", nil, crawler)
+		var seenBlocks []models.Block
+		json.Unmarshal(w.Body.Bytes(), &seenBlocks)
+		assert.Nil(s.T(), seenBlocks[0].Link.VariantID)
+
+		w = s.makeRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click?variant=%d", link.ID, variantId), nil, crawler)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+		w = s.makeRequest(http.MethodGet, fmt.Sprintf("/r/%s?v=%d", link.Slug, variantId), nil, crawler)
+		assert.Equal(s.T(), http.StatusFound, w.Code)
+	})
+
+	w = s.makeRequest(http.MethodGet, variantsURL, nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var report []services.VariantStats
+	json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Len(s.T(), report, 2)
+	for _, stats := range report {
+		if stats.ID == variantId {
+			assert.Equal(s.T(), uint(3), stats.Impressions)
+			assert.Equal(s.T(), uint(2), stats.Clicks)
+			assert.InDelta(s.T(), 2.0/3.0, stats.CTR, 0.0001)
+		} else {
+			assert.Equal(s.T(), uint(0), stats.Impressions)
+		}
+	}
+
+	w = s.makeRequest(http.MethodPost, fmt.Sprintf("%s/%d/promote", variantsURL, report[1].ID), nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var promoted models.User
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, visitor)
+	json.Unmarshal(w.Body.Bytes(), &promoted)
+	assert.Nil(s.T(), promoted.Links[0].VariantID)
+	assert.Equal(s.T(), "Latest episode", promoted.Links[0].Title)
+
+	w = s.makeRequest(http.MethodDelete, fmt.Sprintf("%s/%d", variantsURL, report[0].ID), nil, auth)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+}
+
+func (s *HandlerTestSuite) TestUTMHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com/?ref=bio", Slug: "shop"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "shop").First(&link)
+
+	testCases := []struct {
+		name       string
+		url        string
+		body       models.UTMTemplate
+		wantStatus int
+	}{
+		{name: "Unknown Placeholder", url: "/users/utm", body: models.UTMTemplate{Source: "{email}"}, wantStatus: http.StatusBadRequest},
+		{name: "Profile Template", url: "/users/utm", body: models.UTMTemplate{Source: "linktree", Campaign: "{username}"}, wantStatus: http.StatusOK},
+		{name: "Link Template", url: fmt.Sprintf("/links/%d/utm", link.ID), body: models.UTMTemplate{Content: "link-{link_id}"}, wantStatus: http.StatusOK},
+		{name: "Other Link", url: fmt.Sprintf("/links/%d/utm", link.ID+100), body: models.UTMTemplate{Content: "x"}, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPut, tc.url, tc.body, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/r/shop", nil, nil)
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+	want := fmt.Sprintf("https://shop.example.com/?ref=bio&utm_campaign=testuser&utm_content=link-%d&utm_source=linktree", link.ID)
+	assert.Equal(s.T(), want, w.Header().Get("Location"))
+
+	s.db.First(&link, link.ID)
+	assert.Equal(s.T(), "https://shop.example.com/?ref=bio", link.URL)
+}
+
+func (s *HandlerTestSuite) TestLinkLimitsHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Giveaway", URL: "https://giveaway.example.com", Slug: "giveaway"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "giveaway").First(&link)
+	limitsURL := fmt.Sprintf("/links/%d/limits", link.ID)
+
+	one := uint(1)
+	testCases := []struct {
+		name       string
+		body       handlers.LinkLimitsRequest
+		wantStatus int
+	}{
+		{name: "Unknown Action", body: handlers.LinkLimitsRequest{MaxClicks: &one, ExpiryAction: "explode"}, wantStatus: http.StatusBadRequest},
+		{name: "Sold Out After One", body: handlers.LinkLimitsRequest{MaxClicks: &one}, wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPut, limitsURL, tc.body, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/r/giveaway", nil, nil)
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/r/giveaway", nil, nil)
+	assert.Equal(s.T(), http.StatusGone, w.Code)
+	assert.Contains(s.T(), w.Body.String(), "Sold out")
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.Contains(s.T(), w.Body.String(), `"expired":true`)
+	assert.NotContains(s.T(), w.Body.String(), `"url":"https://giveaway.example.com"`)
+
+	w = s.makeRequest(http.MethodPut, limitsURL, handlers.LinkLimitsRequest{
+		MaxClicks:    &one,
+		ExpiryAction: models.ExpiryActionFallback,
+		FallbackURL:  "https://giveaway.example.com/ended",
+	}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/r/giveaway", nil, nil)
+	assert.Equal(s.T(), http.StatusFound, w.Code)
+	assert.Equal(s.T(), "https://giveaway.example.com/ended", w.Header().Get("Location"))
+
+	s.makeRequest(http.MethodPut, limitsURL, handlers.LinkLimitsRequest{MaxClicks: &one, ExpiryAction: models.ExpiryActionHide}, auth)
+	w = s.makeRequest(http.MethodGet, "/r/giveaway", nil, nil)
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.NotContains(s.T(), w.Body.String(), "Giveaway")
+
+	var analytics models.Analytics
+	s.db.Where("link_id = ?", link.ID).First(&analytics)
+	assert.Equal(s.T(), uint(1), analytics.ClickCount)
+}
+
+func (s *HandlerTestSuite) TestLinkSearchHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "My Podcast", URL: "https://podcast.example.com", Slug: "podcast"}, auth)
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "GitHub", URL: "https://github.com/johndoe", Slug: "github"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "podcast").First(&link)
+
+	w = s.makeRequest(http.MethodPut, fmt.Sprintf("/links/%d/tags", link.ID), handlers.LinkTagsRequest{Tags: []string{"Audio", "weekly"}}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.JSONEq(s.T(), `{"tags":["audio","weekly"]}`, w.Body.String())
+
+	testCases := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantTotal  int64
+	}{
+		{name: "List", url: "/links", wantStatus: http.StatusOK, wantTotal: 2},
+		{name: "Filter By Tag", url: "/links?tag=audio", wantStatus: http.StatusOK, wantTotal: 1},
+		{name: "Filter By Missing Tag", url: "/links?tag=audio&tag=code", wantStatus: http.StatusOK, wantTotal: 0},
+		{name: "Search", url: "/links/search?q=github", wantStatus: http.StatusOK, wantTotal: 1},
+		{name: "Search Tags", url: "/links/search?q=weekly", wantStatus: http.StatusOK, wantTotal: 1},
+		{name: "Search Without Text", url: "/links/search?q=", wantStatus: http.StatusBadRequest},
+		{name: "Invalid Page", url: "/links?page=two", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodGet, tc.url, nil, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			var page services.LinkPage
+			json.Unmarshal(w.Body.Bytes(), &page)
+			assert.Equal(s.T(), tc.wantTotal, page.Total)
+			assert.Len(s.T(), page.Links, int(tc.wantTotal))
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/links?per_page=1&page=2", nil, auth)
+	var page services.LinkPage
+	json.Unmarshal(w.Body.Bytes(), &page)
+	assert.Equal(s.T(), 2, page.Page)
+	assert.Equal(s.T(), 1, page.PerPage)
+	assert.Equal(s.T(), "github", page.Links[0].Slug)
+}
+
+func (s *HandlerTestSuite) TestLinkRevisionHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com/v1", Slug: "shop"}, auth)
+
+	var link models.Link
+	s.db.Where("slug = ?", "shop").First(&link)
+	revisionsURL := fmt.Sprintf("/links/%d/revisions", link.ID)
+	s.makeRequest(http.MethodGet, "/r/shop", nil, nil)
+
+	w = s.makeRequest(http.MethodPut, fmt.Sprintf("/links/%d", link.ID), handlers.UpdateLinkRequest{URL: "https://shop.example.com/v2"}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	s.makeRequest(http.MethodGet, "/r/shop", nil, nil)
+
+	w = s.makeRequest(http.MethodGet, revisionsURL, nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var revisions []models.LinkRevision
+	json.Unmarshal(w.Body.Bytes(), &revisions)
+	assert.Len(s.T(), revisions, 1)
+	assert.Equal(s.T(), "https://shop.example.com/v1", revisions[0].URL)
+	assert.Equal(s.T(), "testuser", revisions[0].Actor)
+
+	testCases := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{name: "Invalid Revision ID", url: revisionsURL + "/abc/rollback", wantStatus: http.StatusBadRequest},
+		{name: "Unknown Revision", url: fmt.Sprintf("%s/%d/rollback", revisionsURL, revisions[0].ID+100), wantStatus: http.StatusBadRequest},
+		{name: "Rollback", url: fmt.Sprintf("%s/%d/rollback", revisionsURL, revisions[0].ID), wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			w := s.makeRequest(http.MethodPost, tc.url, nil, auth)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+		})
+	}
+
+	w = s.makeRequest(http.MethodGet, "/r/shop", nil, nil)
+	assert.Equal(s.T(), "https://shop.example.com/v1", w.Header().Get("Location"))
+
+	w = s.makeRequest(http.MethodGet, revisionsURL, nil, auth)
+	json.Unmarshal(w.Body.Bytes(), &revisions)
+	assert.Len(s.T(), revisions, 2)
+
+	s.Run("Clicks By Destination", func() {
+		w := s.makeRequest(http.MethodGet, fmt.Sprintf("/analytics/links/%d/breakdown", link.ID), nil, auth)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+
+		var breakdown services.ClickBreakdown
+		json.Unmarshal(w.Body.Bytes(), &breakdown)
+		assert.Equal(s.T(), int64(3), breakdown.Total)
+		if assert.Len(s.T(), breakdown.Destinations, 2) {
+			assert.Equal(s.T(), "https://shop.example.com/v1", breakdown.Destinations[0].Value)
+			assert.Equal(s.T(), int64(2), breakdown.Destinations[0].Clicks)
+			assert.Equal(s.T(), "https://shop.example.com/v2", breakdown.Destinations[1].Value)
+			assert.Equal(s.T(), int64(1), breakdown.Destinations[1].Clicks)
+		}
+	})
+}
+
+func (s *HandlerTestSuite) TestIngestionStatsHandler() {
+	os.Setenv("ADMIN_USERNAMES", "admin")
+	defer os.Unsetenv("ADMIN_USERNAMES")
+
+	tokens := map[string]string{}
+	for _, username := range []string{"admin", "testuser"} {
+		s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+			FullName: "Test User",
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		var loginResponse map[string]string
+		json.Unmarshal(w.Body.Bytes(), &loginResponse)
+		tokens[username] = loginResponse["token"]
+	}
+	s.userHandler.UserService.SetAdmins(utils.LoadAdminUsernames())
+
+	w := s.makeRequest(http.MethodGet, "/admin/analytics/ingestion", nil, map[string]string{"Authorization": "Bearer " + tokens["testuser"]})
+	assert.Equal(s.T(), http.StatusForbidden, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/admin/analytics/ingestion", nil, map[string]string{"Authorization": "Bearer " + tokens["admin"]})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var stats services.ClickIngesterStats
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &stats))
+	assert.False(s.T(), stats.Enabled)
+}
+
+func (s *HandlerTestSuite) TestClickSeriesHandlers() {
+	tokens := map[string]string{}
+	for _, username := range []string{"testuser", "other"} {
+		s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+			FullName: "Test User",
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		var loginResponse map[string]string
+		json.Unmarshal(w.Body.Bytes(), &loginResponse)
+		tokens[username] = loginResponse["token"]
+	}
+	auth := map[string]string{"Authorization": "Bearer " + tokens["testuser"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, auth)
+
+	var link models.Link
+	s.db.First(&link)
+	occurred, _ := time.Parse(time.RFC3339, "2024-03-04T23:30:00Z")
+	s.db.Create(&models.ClickEvent{LinkID: link.ID, OccurredAt: occurred})
+
+	linkURL := fmt.Sprintf("/analytics/links/%d", link.ID)
+	testCases := []struct {
+		name       string
+		url        string
+		token      string
+		wantStatus int
+		wantClicks []int64
+	}{
+		{name: "Daily UTC", url: linkURL + "?from=2024-03-04&to=2024-03-05", token: tokens["testuser"], wantStatus: http.StatusOK, wantClicks: []int64{1, 0}},
+		{name: "Daily Berlin", url: linkURL + "?from=2024-03-04&to=2024-03-05&tz=Europe/Berlin", token: tokens["testuser"], wantStatus: http.StatusOK, wantClicks: []int64{0, 1}},
+		{name: "Profile Weekly", url: "/analytics/profile?from=2024-03-04&to=2024-03-10&interval=week", token: tokens["testuser"], wantStatus: http.StatusOK, wantClicks: []int64{1}},
+		{name: "Other User's Link", url: linkURL, token: tokens["other"], wantStatus: http.StatusNotFound},
+		{name: "Invalid Link ID", url: "/analytics/links/abc", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Unknown Interval", url: linkURL + "?interval=month", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Unknown Timezone", url: linkURL + "?tz=Mars/Olympus", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Invalid Date", url: linkURL + "?from=03/04/2024", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Unauthenticated", url: "/analytics/profile", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			headers := map[string]string{}
+			if tc.token != "" {
+				headers["Authorization"] = "Bearer " + tc.token
+			}
+
+			w := s.makeRequest(http.MethodGet, tc.url, nil, headers)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+
+			if tc.wantClicks != nil {
+				var series services.ClickSeries
+				assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &series))
+				clicks := make([]int64, 0, len(series.Points))
+				for _, point := range series.Points {
+					clicks = append(clicks, point.Clicks)
+				}
+				assert.Equal(s.T(), tc.wantClicks, clicks)
+			}
+		})
+	}
+}
+
+func (s *HandlerTestSuite) TestProfileViewHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, auth)
+
+	s.analytics.AnalyticsService.SetBotClassifier(services.NewBotClassifier(services.DefaultBotClassifierConfig()))
+	browser := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	for _, headers := range []map[string]string{
+		{"User-Agent": browser, "Accept-Language": "en-GB"},
+		{"User-Agent": browser, "Accept-Language": "en-GB", "Referer": "https://www.instagram.com/"},
+		{"User-Agent": browser, "Accept-Language": "en-GB", "Authorization": auth["Authorization"]},
+		{"User-Agent": "Slackbot-LinkExpanding 1.0"},
+	} {
+		w = s.makeRequest(http.MethodGet, "/users/testuser", nil, headers)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+	}
+	s.analytics.AnalyticsService.SetBotClassifier(nil)
+
+	var views []models.ProfileView
+	s.db.Order("id").Find(&views)
+	assert.Len(s.T(), views, 2)
+	assert.Equal(s.T(), "https://www.instagram.com/", views[1].Referrer)
+
+	var link models.Link
+	s.db.First(&link)
+	s.makeRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click", link.ID), nil, nil)
+
+	w = s.makeRequest(http.MethodGet, "/analytics/ctr", nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var report services.CTRReport
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &report))
+	assert.Equal(s.T(), int64(2), report.ProfileViews)
+	assert.Len(s.T(), report.Links, 1)
+	assert.Equal(s.T(), int64(1), report.Links[0].Clicks)
+	assert.InDelta(s.T(), 0.5, report.Links[0].CTR, 0.001)
+
+	w = s.makeRequest(http.MethodGet, "/analytics/ctr?tz=Nowhere", nil, auth)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *HandlerTestSuite) TestAnalyticsPrivacyHandlers() {
+	tokens := map[string]string{}
+	for _, username := range []string{"testuser", "alice"} {
+		s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+			FullName: "Test User",
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		var loginResponse map[string]string
+		json.Unmarshal(w.Body.Bytes(), &loginResponse)
+		tokens[username] = loginResponse["token"]
+	}
+	owner := map[string]string{"Authorization": "Bearer " + tokens["testuser"]}
+	alice := map[string]string{"Authorization": "Bearer " + tokens["alice"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, owner)
+
+	var link models.Link
+	s.db.First(&link)
+	s.makeRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click", link.ID), nil, alice)
+
+	w := s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.NotContains(s.T(), w.Body.String(), "alice")
+	assert.NotContains(s.T(), w.Body.String(), `"analytics"`)
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser/blocks", nil, nil)
+	assert.NotContains(s.T(), w.Body.String(), "alice")
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, owner)
+	assert.Contains(s.T(), w.Body.String(), `"click_count":1`)
+	assert.NotContains(s.T(), w.Body.String(), "alice")
+
+	w = s.makeRequest(http.MethodPut, "/users/privacy", handlers.AnalyticsPrivacyRequest{PublicClickCounts: true}, owner)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, alice)
+	assert.Contains(s.T(), w.Body.String(), `"click_count":1`)
+
+	w = s.makeRequest(http.MethodPut, "/users/privacy", nil, nil)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (s *HandlerTestSuite) TestClickBreakdownHandlers() {
+	tokens := map[string]string{}
+	for _, username := range []string{"testuser", "other"} {
+		s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+			FullName: "Test User",
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+			Username: username,
+			Password: "password123",
+		}, nil)
+
+		var loginResponse map[string]string
+		json.Unmarshal(w.Body.Bytes(), &loginResponse)
+		tokens[username] = loginResponse["token"]
+	}
+	auth := map[string]string{"Authorization": "Bearer " + tokens["testuser"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, auth)
+
+	var link models.Link
+	s.db.First(&link)
+	w := s.makeRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click", link.ID), nil, map[string]string{
+		"Referer":    "https://www.tiktok.com/@testuser",
+		"User-Agent": "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36",
+	})
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	linkURL := fmt.Sprintf("/analytics/links/%d/breakdown", link.ID)
+	testCases := []struct {
+		name       string
+		url        string
+		token      string
+		wantStatus int
+	}{
+		{name: "Link", url: linkURL, token: tokens["testuser"], wantStatus: http.StatusOK},
+		{name: "Profile", url: "/analytics/profile/breakdown?limit=5&tz=Europe/Berlin", token: tokens["testuser"], wantStatus: http.StatusOK},
+		{name: "Other User's Link", url: linkURL, token: tokens["other"], wantStatus: http.StatusNotFound},
+		{name: "Invalid Link ID", url: "/analytics/links/abc/breakdown", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Invalid Limit", url: linkURL + "?limit=0", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Limit Too High", url: linkURL + "?limit=101", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Reversed Dates", url: linkURL + "?from=2024-03-05&to=2024-03-04", token: tokens["testuser"], wantStatus: http.StatusBadRequest},
+		{name: "Unauthenticated", url: "/analytics/profile/breakdown", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			headers := map[string]string{}
+			if tc.token != "" {
+				headers["Authorization"] = "Bearer " + tc.token
+			}
+
+			w := s.makeRequest(http.MethodGet, tc.url, nil, headers)
+			assert.Equal(s.T(), tc.wantStatus, w.Code)
+
+			if tc.wantStatus == http.StatusOK {
+				var breakdown services.ClickBreakdown
+				json.Unmarshal(w.Body.Bytes(), &breakdown)
+				assert.Equal(s.T(), int64(1), breakdown.Total)
+				assert.Equal(s.T(), []services.BreakdownEntry{{Value: "tiktok.com", Clicks: 1, Share: 1}}, breakdown.Sources)
+				assert.Equal(s.T(), "mobile", breakdown.Devices[0].Value)
+				assert.Equal(s.T(), "Chrome", breakdown.Browsers[0].Value)
+				assert.Equal(s.T(), "Android", breakdown.OperatingSystems[0].Value)
+			}
+		})
+	}
+}
+
+func (s *HandlerTestSuite) TestClickGeolocationHandler() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, auth)
+
+	var link models.Link
+	s.db.First(&link)
+
+	s.analytics.AnalyticsService.SetGeoLocator(fakeGeoLocator{
+		"203.0.113.7": {Country: "JP", Region: "JP-13", City: "Tokyo"},
+	})
+	defer s.analytics.AnalyticsService.SetGeoLocator(nil)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click", link.ID), nil)
+	req.RemoteAddr = "203.0.113.7:52814"
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var event models.ClickEvent
+	s.db.First(&event)
+	assert.Equal(s.T(), "JP", event.Country)
+	assert.Equal(s.T(), "JP-13", event.Region)
+	assert.Equal(s.T(), "Tokyo", event.City)
+	assert.NotEmpty(s.T(), event.VisitorHash)
+
+	w = s.makeRequest(http.MethodGet, fmt.Sprintf("/analytics/links/%d/breakdown", link.ID), nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var breakdown services.ClickBreakdown
+	json.Unmarshal(w.Body.Bytes(), &breakdown)
+	assert.Equal(s.T(), []services.BreakdownEntry{{Value: "JP", Clicks: 1, Share: 1}}, breakdown.Countries)
+	assert.Equal(s.T(), []services.BreakdownEntry{{Value: "JP-13", Clicks: 1, Share: 1}}, breakdown.Regions)
+	assert.Equal(s.T(), []services.BreakdownEntry{{Value: "Tokyo, JP", Clicks: 1, Share: 1}}, breakdown.Cities)
+	assert.NotContains(s.T(), w.Body.String(), "203.0.113.7")
+}
+
+func (s *HandlerTestSuite) TestBotTrafficHandlers() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, auth)
+
+	var link models.Link
+	s.db.First(&link)
+
+	s.analytics.AnalyticsService.SetBotClassifier(services.NewBotClassifier(services.DefaultBotClassifierConfig()))
+	defer s.analytics.AnalyticsService.SetBotClassifier(nil)
+
+	clickURL := fmt.Sprintf("/analytics/%d/click", link.ID)
+	s.makeRequest(http.MethodPost, clickURL, nil, map[string]string{
+		"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+		"Accept-Language": "en-GB",
+	})
+	s.makeRequest(http.MethodPost, clickURL, nil, map[string]string{"User-Agent": "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"})
+	s.makeRequest(http.MethodPost, clickURL, nil, map[string]string{"User-Agent": "Go-http-client/1.1"})
+
+	var analytics models.Analytics
+	s.db.Where("link_id = ?", link.ID).First(&analytics)
+	assert.Equal(s.T(), uint(1), analytics.ClickCount)
+	assert.Equal(s.T(), uint(2), analytics.BotClickCount)
+
+	seriesURL := fmt.Sprintf("/analytics/links/%d", link.ID)
+	var series services.ClickSeries
+	w = s.makeRequest(http.MethodGet, seriesURL, nil, auth)
+	json.Unmarshal(w.Body.Bytes(), &series)
+	assert.Equal(s.T(), int64(1), series.Total)
+
+	w = s.makeRequest(http.MethodPut, "/users/bot-traffic", handlers.BotTrafficRequest{ShowBotTraffic: true}, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	w = s.makeRequest(http.MethodGet, seriesURL, nil, auth)
+	json.Unmarshal(w.Body.Bytes(), &series)
+	assert.Equal(s.T(), int64(3), series.Total)
+	assert.True(s.T(), series.IncludesBots)
+
+	w = s.makeRequest(http.MethodGet, "/users/testuser", nil, nil)
+	assert.NotContains(s.T(), w.Body.String(), "bot_click_count")
+
+	w = s.makeRequest(http.MethodPut, "/users/bot-traffic", "yes", auth)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+
+	w = s.makeRequest(http.MethodPut, "/users/bot-traffic", handlers.BotTrafficRequest{}, nil)
+	assert.Equal(s.T(), http.StatusUnauthorized, w.Code)
+
+	s.Run("Bots Do Not Use Up Click Caps", func() {
+		one := uint(1)
+		w := s.makeRequest(http.MethodPut, fmt.Sprintf("/links/%d/limits", link.ID), handlers.LinkLimitsRequest{MaxClicks: &one}, auth)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+
+		preview := map[string]string{"User-Agent": "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"}
+		person := map[string]string{
+			"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			"Accept-Language": "en-GB",
+		}
+		redirectURL := "/r/" + link.Slug
+
+		w = s.makeRequest(http.MethodGet, redirectURL, nil, preview)
+		assert.Equal(s.T(), http.StatusFound, w.Code)
+		w = s.makeRequest(http.MethodGet, redirectURL, nil, person)
+		assert.Equal(s.T(), http.StatusFound, w.Code)
+
+		w = s.makeRequest(http.MethodGet, redirectURL, nil, preview)
+		assert.Equal(s.T(), http.StatusGone, w.Code)
+
+		var capped models.Link
+		s.db.First(&capped, link.ID)
+		assert.Equal(s.T(), uint(1), capped.ClicksUsed)
+	})
+
+	s.Run("Forwarded For Does Not Reset The Rate Check", func() {
+		config := services.DefaultBotClassifierConfig()
+		config.RateLimit = 3
+		s.analytics.AnalyticsService.SetBotClassifier(services.NewBotClassifier(config))
+
+		for i := 0; i < 4; i++ {
+			req := httptest.NewRequest(http.MethodPost, clickURL, nil)
+			req.RemoteAddr = "192.0.2.77:4321"
+			req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+			req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15")
+			req.Header.Set("Accept-Language", "en-GB")
+			s.router.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		var last models.ClickEvent
+		s.db.Where("link_id = ?", link.ID).Order("id DESC").First(&last)
+		assert.Equal(s.T(), services.BotReasonRate, last.BotReason)
+	})
+}
+
+func (s *HandlerTestSuite) TestUniqueVisitorsHandler() {
+	s.makeRequest(http.MethodPost, "/users/signup", handlers.SignUpRequest{
+		FullName: "Test User",
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	w := s.makeRequest(http.MethodPost, "/users/login", handlers.LoginRequest{
+		Username: "testuser",
+		Password: "password123",
+	}, nil)
+
+	var loginResponse map[string]string
+	json.Unmarshal(w.Body.Bytes(), &loginResponse)
+	auth := map[string]string{"Authorization": "Bearer " + loginResponse["token"]}
+
+	s.makeRequest(http.MethodPost, "/links", handlers.CreateLinkRequest{Title: "Shop", URL: "https://shop.example.com"}, auth)
+
+	var link models.Link
+	s.db.First(&link)
+
+	for _, addr := range []string{"203.0.113.7:52814", "203.0.113.7:52815", "198.51.100.1:40000"} {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/analytics/%d/click", link.ID), nil)
+		req.RemoteAddr = addr
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0")
+		w = httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		assert.Equal(s.T(), http.StatusOK, w.Code)
+	}
+
+	w = s.makeRequest(http.MethodGet, fmt.Sprintf("/analytics/links/%d", link.ID), nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var series services.ClickSeries
+	json.Unmarshal(w.Body.Bytes(), &series)
+	assert.Equal(s.T(), int64(3), series.Total)
+	assert.Equal(s.T(), int64(2), series.UniqueVisitors)
+
+	w = s.makeRequest(http.MethodGet, "/analytics/ctr", nil, auth)
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+
+	var report services.CTRReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	assert.Equal(s.T(), int64(2), report.UniqueVisitors)
+	assert.Equal(s.T(), int64(2), report.Links[0].UniqueVisitors)
+
+	var events []models.ClickEvent
+	s.db.Find(&events)
+	for _, event := range events {
+		assert.NotContains(s.T(), event.VisitorHash, "203.0.113.7")
+	}
+	assert.NotContains(s.T(), w.Body.String(), "visitor_hash")
 }
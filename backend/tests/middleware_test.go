@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"errors"
 	"linktree-mohamedfadel-backend/internal/api/middleware"
 	"net/http"
 	"net/http/httptest"
@@ -115,3 +116,66 @@ func TestOptionalJWTFromContext(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireAdminFromContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	isAdmin := func(username string) (bool, error) {
+		if username == "broken" {
+			return false, errors.New("database is down")
+		}
+		return username == "admin" || username == "moderator", nil
+	}
+
+	tests := []struct {
+		name           string
+		username       string
+		expectedStatus int
+		expectedError  string
+		expectedNext   bool
+	}{
+		{
+			name:           "no user",
+			expectedStatus: http.StatusUnauthorized,
+			expectedError:  "Unauthorized",
+		},
+		{
+			name:           "regular user",
+			username:       "johndoe",
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "Admin access required",
+		},
+		{
+			name:           "admin",
+			username:       "moderator",
+			expectedStatus: http.StatusOK,
+			expectedNext:   true,
+		},
+		{
+			name:           "lookup failure",
+			username:       "broken",
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "database is down",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", "/", nil)
+
+			if tt.username != "" {
+				c.Set("username", tt.username)
+			}
+
+			middleware := middleware.RequireAdminFromContext(isAdmin)
+			middleware(c)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			assert.Equal(t, !tt.expectedNext, c.IsAborted())
+			if tt.expectedError != "" {
+				assert.Contains(t, w.Body.String(), tt.expectedError)
+			}
+		})
+	}
+}
@@ -1,13 +1,27 @@
 package tests
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"linktree-mohamedfadel-backend/internal/database"
 	"linktree-mohamedfadel-backend/internal/models"
 	"linktree-mohamedfadel-backend/internal/services"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/datatypes"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -18,6 +32,8 @@ type ServiceTestSuite struct {
 	userService      *services.UserService
 	linkService      *services.LinkService
 	analyticsService *services.AnalyticsService
+	sectionService   *services.SectionService
+	blockService     *services.BlockService
 }
 
 func (s *ServiceTestSuite) SetupSuite() {
@@ -28,11 +44,15 @@ func (s *ServiceTestSuite) SetupSuite() {
 	}
 	os.Setenv("JWT_SECRET", "test-secret-key")
 
-	s.db.AutoMigrate(&models.User{}, &models.Link{}, &models.Analytics{})
+	s.db.AutoMigrate(&models.User{}, &models.Section{}, &models.Link{}, &models.Analytics{}, &models.Block{}, &models.SlugAlias{}, &models.LinkHealth{}, &models.LinkVariant{}, &models.LinkTag{}, &models.LinkRevision{}, &models.ClickEvent{}, &models.ClickRollup{}, &models.RollupCursor{}, &models.ProfileView{}, &models.VisitorSalt{})
 
 	s.userService = services.NewUserService(s.db)
 	s.linkService = services.NewLinkService(s.db)
+	s.linkService.SetURLPolicy(localURLPolicy())
 	s.analyticsService = services.NewAnalyticsService(s.db)
+	s.sectionService = services.NewSectionService(s.db)
+	s.blockService = services.NewBlockService(s.db)
+	s.blockService.SetURLPolicy(localURLPolicy())
 }
 
 func (s *ServiceTestSuite) TearDownSuite() {
@@ -40,8 +60,20 @@ func (s *ServiceTestSuite) TearDownSuite() {
 }
 
 func (s *ServiceTestSuite) SetupTest() {
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.SlugAlias{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkHealth{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkVariant{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkTag{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.LinkRevision{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ClickEvent{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ClickRollup{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.RollupCursor{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ProfileView{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.VisitorSalt{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Block{})
 	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Analytics{})
 	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Link{})
+	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Section{})
 	s.db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.User{})
 }
 
@@ -49,6 +81,13 @@ func TestServiceSuite(t *testing.T) {
 	suite.Run(t, new(ServiceTestSuite))
 }
 
+// localURLPolicy accepts the loopback URLs of httptest servers.
+func localURLPolicy() *services.URLPolicy {
+	policy := services.NewURLPolicy()
+	policy.SetAllowPrivateNetworks(true)
+	return policy
+}
+
 func (s *ServiceTestSuite) TestUserSignUp() {
 	testCases := []struct {
 		name     string
@@ -167,7 +206,7 @@ func (s *ServiceTestSuite) TestGetUserProfileInfo() {
 		Title: "Test Link",
 		URL:   "https://example.com",
 	}
-	s.linkService.CreateLink("testuser", link)
+	s.linkService.CreateLink(context.Background(), "testuser", link)
 
 	testCases := []struct {
 		name     string
@@ -314,6 +353,92 @@ func (s *ServiceTestSuite) TestDeleteUser() {
 			}
 		})
 	}
+
+	s.Run("Deletes Owned Data", func() {
+		for _, username := range []string{"owner", "other"} {
+			s.userService.SignUp(models.User{FullName: "Test User", Username: username}, "password123")
+
+			section, err := s.sectionService.CreateSection(username, models.Section{Title: "Music"})
+			assert.NoError(s.T(), err)
+			assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), username, models.Link{Title: "Song", URL: "https://example.com/" + username + "/song", SectionID: &section.ID}))
+			assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), username, models.Link{Title: "Home", URL: "https://example.com/" + username}))
+			_, err = s.blockService.CreateBlock(username, models.Block{Type: models.BlockTypeText, Payload: datatypes.JSON(`{"text":"Hi"}`)})
+			assert.NoError(s.T(), err)
+
+			var linkIds []uint
+			s.db.Model(&models.Link{}).Joins("JOIN users ON users.id = links.user_id").Where("users.username = ?", username).Pluck("links.id", &linkIds)
+			for _, linkId := range linkIds {
+				_, err := s.linkService.SetLinkTags(username, uint64(linkId), []string{"music"})
+				assert.NoError(s.T(), err)
+				assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(linkId), services.ClickDetails{}))
+				s.db.Create(&models.ClickRollup{LinkID: linkId, Hour: time.Now().UTC().Truncate(time.Hour), Clicks: 1})
+			}
+			assert.NoError(s.T(), s.analyticsService.TrackProfileView(username, "", services.ClickDetails{}))
+		}
+
+		var owner models.User
+		s.db.Where("username = ?", "owner").First(&owner)
+		assert.NoError(s.T(), s.userService.DeleteUser("owner"))
+
+		var other models.User
+		s.db.Where("username = ?", "other").First(&other)
+		for _, table := range []interface{}{&models.Link{}, &models.Section{}, &models.Block{}, &models.ProfileView{}} {
+			var owned, kept int64
+			s.db.Model(table).Where("user_id = ?", owner.ID).Count(&owned)
+			s.db.Model(table).Where("user_id = ?", other.ID).Count(&kept)
+			assert.Equal(s.T(), int64(0), owned)
+			assert.NotZero(s.T(), kept)
+		}
+
+		var otherLinkIds []uint
+		s.db.Model(&models.Link{}).Where("user_id = ?", other.ID).Pluck("id", &otherLinkIds)
+		for _, table := range []interface{}{&models.Analytics{}, &models.LinkTag{}, &models.ClickEvent{}, &models.ClickRollup{}, &models.SlugAlias{}} {
+			var orphaned int64
+			s.db.Model(table).Where("link_id NOT IN ?", otherLinkIds).Count(&orphaned)
+			assert.Equal(s.T(), int64(0), orphaned)
+		}
+	})
+}
+
+func (s *ServiceTestSuite) TestSetAdmins() {
+	for _, username := range []string{"alice", "bob"} {
+		s.userService.SignUp(models.User{FullName: "Test User", Username: username}, "password123")
+	}
+
+	isAdmin := func(username string) bool {
+		admin, err := s.userService.IsAdmin(username)
+		assert.NoError(s.T(), err)
+		return admin
+	}
+
+	missing, err := s.userService.SetAdmins([]string{"alice"})
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), missing)
+	assert.True(s.T(), isAdmin("alice"))
+	assert.False(s.T(), isAdmin("bob"))
+
+	s.Run("Replaces Admins", func() {
+		missing, err := s.userService.SetAdmins([]string{"bob", "carol"})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), []string{"carol"}, missing)
+		assert.False(s.T(), isAdmin("alice"))
+		assert.True(s.T(), isAdmin("bob"))
+	})
+
+	s.Run("Listed Name Signing Up Later", func() {
+		s.userService.SignUp(models.User{FullName: "Test User", Username: "carol"}, "password123")
+		assert.False(s.T(), isAdmin("carol"))
+	})
+
+	s.Run("Unknown User", func() {
+		assert.False(s.T(), isAdmin("nonexistent"))
+	})
+
+	s.Run("None", func() {
+		_, err := s.userService.SetAdmins(nil)
+		assert.NoError(s.T(), err)
+		assert.False(s.T(), isAdmin("bob"))
+	})
 }
 
 func (s *ServiceTestSuite) TestCreateLink() {
@@ -337,9 +462,9 @@ func (s *ServiceTestSuite) TestCreateLink() {
 			wantErr: false,
 		},
 		{
-			name: "Missing Title",
+			name: "Missing URL",
 			link: models.Link{
-				URL: "https://example.com",
+				Title: "Test Link",
 			},
 			wantErr: true,
 		},
@@ -355,7 +480,7 @@ func (s *ServiceTestSuite) TestCreateLink() {
 
 	for _, tc := range testCases {
 		s.Run(tc.name, func() {
-			err := s.linkService.CreateLink("testuser", tc.link)
+			err := s.linkService.CreateLink(context.Background(), "testuser", tc.link)
 			if tc.wantErr {
 				assert.Error(s.T(), err)
 			} else {
@@ -380,7 +505,7 @@ func (s *ServiceTestSuite) TestUpdateLink() {
 		Title: "Test Link",
 		URL:   "https://example.com",
 	}
-	s.linkService.CreateLink("testuser", link)
+	s.linkService.CreateLink(context.Background(), "testuser", link)
 
 	var createdLink models.Link
 	s.db.Where("url = ?", link.URL).First(&createdLink)
@@ -470,7 +595,7 @@ func (s *ServiceTestSuite) TestDeleteLink() {
 		Title: "Test Link",
 		URL:   "https://example.com",
 	}
-	s.linkService.CreateLink("testuser", link)
+	s.linkService.CreateLink(context.Background(), "testuser", link)
 
 	var createdLink models.Link
 	s.db.Where("url = ?", link.URL).First(&createdLink)
@@ -522,3 +647,2724 @@ func (s *ServiceTestSuite) TestDeleteLink() {
 		})
 	}
 }
+
+func (s *ServiceTestSuite) TestSections() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+
+	podcasts, err := s.sectionService.CreateSection("testuser", models.Section{Title: "Podcasts"})
+	assert.NoError(s.T(), err)
+	videos, err := s.sectionService.CreateSection("testuser", models.Section{Title: "Videos", Collapsed: true})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, podcasts.Position)
+	assert.Equal(s.T(), 1, videos.Position)
+
+	_, err = s.sectionService.CreateSection("testuser", models.Section{})
+	assert.Error(s.T(), err)
+
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Ungrouped", URL: "https://example.com"}))
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Episode 1", URL: "https://example.com/1", SectionID: &podcasts.ID}))
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Episode 2", URL: "https://example.com/2", SectionID: &podcasts.ID}))
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Clip", URL: "https://example.com/clip", SectionID: &videos.ID}))
+
+	missing := uint(9999)
+	assert.Error(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Bad", URL: "https://example.com/bad", SectionID: &missing}))
+
+	s.Run("Profile Groups Links By Section", func() {
+		profile, err := s.userService.GetUserProfileInfo("testuser")
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), profile.Links, 1)
+		assert.Len(s.T(), profile.Sections, 2)
+		assert.Equal(s.T(), "Podcasts", profile.Sections[0].Title)
+		assert.Equal(s.T(), "Episode 1", profile.Sections[0].Links[0].Title)
+		assert.Equal(s.T(), "Episode 2", profile.Sections[0].Links[1].Title)
+		assert.True(s.T(), profile.Sections[1].Collapsed)
+	})
+
+	s.Run("Reorder Sections", func() {
+		assert.Error(s.T(), s.sectionService.ReorderSections("testuser", []uint{videos.ID}))
+		assert.Error(s.T(), s.sectionService.ReorderSections("testuser", []uint{videos.ID, videos.ID}))
+		assert.NoError(s.T(), s.sectionService.ReorderSections("testuser", []uint{videos.ID, podcasts.ID}))
+
+		sections, err := s.sectionService.GetSections("testuser")
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "Videos", sections[0].Title)
+		assert.Equal(s.T(), "Podcasts", sections[1].Title)
+
+		linkIds := []uint{sections[1].Links[1].ID, sections[1].Links[0].ID}
+		assert.NoError(s.T(), s.sectionService.ReorderSectionLinks("testuser", uint64(podcasts.ID), linkIds))
+
+		sections, _ = s.sectionService.GetSections("testuser")
+		assert.Equal(s.T(), "Episode 2", sections[1].Links[0].Title)
+	})
+
+	s.Run("Update Section", func() {
+		collapsed := true
+		assert.NoError(s.T(), s.sectionService.UpdateSection("testuser", uint64(podcasts.ID), "Shows", &collapsed))
+		assert.Error(s.T(), s.sectionService.UpdateSection("wronguser", uint64(podcasts.ID), "Shows", nil))
+
+		var section models.Section
+		s.db.First(&section, podcasts.ID)
+		assert.Equal(s.T(), "Shows", section.Title)
+		assert.True(s.T(), section.Collapsed)
+	})
+
+	s.Run("Delete Section Moving Links", func() {
+		assert.Error(s.T(), s.sectionService.DeleteSection("testuser", uint64(podcasts.ID), ""))
+		assert.NoError(s.T(), s.sectionService.DeleteSection("testuser", uint64(podcasts.ID), services.SectionDeleteMoveLinks))
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Len(s.T(), profile.Sections, 1)
+		assert.Len(s.T(), profile.Links, 3)
+		assert.Equal(s.T(), "Ungrouped", profile.Links[0].Title)
+		assert.Equal(s.T(), "Episode 2", profile.Links[1].Title)
+	})
+
+	s.Run("Reorder Ungrouped Links", func() {
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		linkIds := []uint{profile.Links[2].ID, profile.Links[0].ID, profile.Links[1].ID}
+
+		assert.Error(s.T(), s.sectionService.ReorderUngroupedLinks("testuser", linkIds[:2]))
+		sections, _ := s.sectionService.GetSections("testuser")
+		assert.Error(s.T(), s.sectionService.ReorderUngroupedLinks("testuser", append(linkIds, sections[0].Links[0].ID)))
+		assert.NoError(s.T(), s.sectionService.ReorderUngroupedLinks("testuser", linkIds))
+
+		profile, _ = s.userService.GetUserProfileInfo("testuser")
+		assert.Equal(s.T(), "Episode 1", profile.Links[0].Title)
+		assert.Equal(s.T(), "Ungrouped", profile.Links[1].Title)
+	})
+
+	s.Run("Delete Section With Links", func() {
+		assert.NoError(s.T(), s.sectionService.DeleteSection("testuser", uint64(videos.ID), services.SectionDeleteLinks))
+
+		var count int64
+		s.db.Model(&models.Link{}).Where("url = ?", "https://example.com/clip").Count(&count)
+		assert.Equal(s.T(), int64(0), count)
+	})
+}
+
+func (s *ServiceTestSuite) TestValidateBlockPayload() {
+	testCases := []struct {
+		name      string
+		blockType string
+		payload   string
+		wantErr   bool
+	}{
+		{name: "Text", blockType: models.BlockTypeText, payload: `{"text":"Hello"}`},
+		{name: "Empty Text", blockType: models.BlockTypeText, payload: `{"text":"  "}`, wantErr: true},
+		{name: "Header", blockType: models.BlockTypeHeader, payload: `{"text":"Projects"}`},
+		{name: "Divider", blockType: models.BlockTypeDivider, payload: ``},
+		{name: "Divider With Fields", blockType: models.BlockTypeDivider, payload: `{"style":"dashed"}`, wantErr: true},
+		{name: "Image", blockType: models.BlockTypeImage, payload: `{"url":"https://example.com/a.png","alt":"A"}`},
+		{name: "Image Bad URL", blockType: models.BlockTypeImage, payload: `{"url":"javascript:alert(1)"}`, wantErr: true},
+		{name: "Video", blockType: models.BlockTypeVideo, payload: `{"url":"https://youtube.com/watch?v=1"}`},
+		{name: "Video Missing URL", blockType: models.BlockTypeVideo, payload: `{}`, wantErr: true},
+		{name: "Email Signup", blockType: models.BlockTypeEmailSignup, payload: `{"title":"Newsletter"}`},
+		{name: "Contact Form", blockType: models.BlockTypeContactForm, payload: `{"title":"Contact","fields":["name","email"]}`},
+		{name: "Contact Form Unknown Field", blockType: models.BlockTypeContactForm, payload: `{"title":"Contact","fields":["ssn"]}`, wantErr: true},
+		{name: "Unknown Field", blockType: models.BlockTypeText, payload: `{"text":"Hi","color":"red"}`, wantErr: true},
+		{name: "Unknown Type", blockType: "carousel", payload: `{}`, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			_, err := s.blockService.ValidateBlockPayload(tc.blockType, datatypes.JSON(tc.payload))
+			if tc.wantErr {
+				assert.Error(s.T(), err)
+			} else {
+				assert.NoError(s.T(), err)
+			}
+		})
+	}
+
+	s.Run("URL Policy", func() {
+		policy := services.NewURLPolicy()
+		assert.NoError(s.T(), policy.BlockDomains([]string{"blocked.example"}))
+		blocks := services.NewBlockService(s.db)
+		blocks.SetURLPolicy(policy)
+
+		for _, tc := range []struct {
+			blockType string
+			payload   string
+		}{
+			{models.BlockTypeImage, `{"url":"https://blocked.example/a.png"}`},
+			{models.BlockTypeImage, `{"url":"https://example.com/a.png","link_url":"https://cdn.blocked.example/"}`},
+			{models.BlockTypeImage, `{"url":"http://169.254.169.254/latest/meta-data"}`},
+			{models.BlockTypeVideo, `{"url":"http://127.0.0.1:8080/admin"}`},
+		} {
+			_, err := blocks.ValidateBlockPayload(tc.blockType, datatypes.JSON(tc.payload))
+			assert.ErrorIs(s.T(), err, services.ErrURLRejected, tc.payload)
+		}
+
+		_, err := blocks.ValidateBlockPayload(models.BlockTypeVideo, datatypes.JSON(`{"url":"https://youtube.com/watch?v=1"}`))
+		assert.NoError(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestBlocks() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+
+	header, err := s.blockService.CreateBlock("testuser", models.Block{
+		Type:    models.BlockTypeHeader,
+		Payload: datatypes.JSON(`{"text":"Hello"}`),
+	})
+	assert.NoError(s.T(), err)
+
+	_, err = s.blockService.CreateBlock("testuser", models.Block{Type: models.BlockTypeLink})
+	assert.Error(s.T(), err)
+
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Test Link", URL: "https://example.com"}))
+
+	blocks, err := s.blockService.GetBlocks("testuser")
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), blocks, 2)
+	assert.Equal(s.T(), models.BlockTypeHeader, blocks[0].Type)
+	assert.Equal(s.T(), models.BlockTypeLink, blocks[1].Type)
+	assert.Equal(s.T(), "https://example.com", blocks[1].Link.URL)
+
+	s.Run("Reorder Blocks", func() {
+		assert.NoError(s.T(), s.blockService.ReorderBlocks("testuser", []uint{blocks[1].ID, blocks[0].ID}))
+		reordered, _ := s.blockService.GetBlocks("testuser")
+		assert.Equal(s.T(), models.BlockTypeLink, reordered[0].Type)
+	})
+
+	s.Run("Update Block", func() {
+		assert.NoError(s.T(), s.blockService.UpdateBlock("testuser", uint64(header.ID), datatypes.JSON(`{"text":"Welcome"}`)))
+		assert.Error(s.T(), s.blockService.UpdateBlock("testuser", uint64(header.ID), datatypes.JSON(`{"text":""}`)))
+		assert.Error(s.T(), s.blockService.UpdateBlock("testuser", uint64(blocks[1].ID), datatypes.JSON(`{}`)))
+	})
+
+	s.Run("Migrate Existing Links", func() {
+		var owner models.User
+		s.db.Where("username = ?", "testuser").First(&owner)
+		legacy := models.Link{Title: "Legacy", URL: "https://legacy.example.com", UserID: owner.ID}
+		s.db.Create(&legacy)
+
+		assert.NoError(s.T(), database.MigrateLinkBlocks(s.db))
+		assert.NoError(s.T(), database.MigrateLinkBlocks(s.db))
+
+		var count int64
+		s.db.Model(&models.Block{}).Where("link_id = ?", legacy.ID).Count(&count)
+		assert.Equal(s.T(), int64(1), count)
+	})
+
+	s.Run("Deleting Link Removes Block", func() {
+		assert.Error(s.T(), s.blockService.DeleteBlock("testuser", uint64(blocks[1].ID)))
+		assert.NoError(s.T(), s.linkService.DeleteLink("testuser", uint64(*blocks[1].LinkID)))
+
+		var count int64
+		s.db.Model(&models.Block{}).Where("id = ?", blocks[1].ID).Count(&count)
+		assert.Equal(s.T(), int64(0), count)
+	})
+
+	s.Run("Delete Block", func() {
+		assert.NoError(s.T(), s.blockService.DeleteBlock("testuser", uint64(header.ID)))
+		assert.Error(s.T(), s.blockService.DeleteBlock("testuser", uint64(header.ID)))
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkSlugs() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Generated", URL: "https://example.com"}))
+	assert.NoError(s.T(), s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Podcast", URL: "https://podcast.example.com", Slug: "johndoe-podcast"}))
+
+	var generated, podcast models.Link
+	s.db.Where("url = ?", "https://example.com").First(&generated)
+	s.db.Where("url = ?", "https://podcast.example.com").First(&podcast)
+	assert.Len(s.T(), generated.Slug, 7)
+	assert.Equal(s.T(), "/r/"+generated.Slug, generated.TrackedURL)
+	assert.Equal(s.T(), "johndoe-podcast", podcast.Slug)
+
+	testCases := []struct {
+		name    string
+		slug    string
+		wantErr bool
+	}{
+		{name: "Taken", slug: "johndoe-podcast", wantErr: true},
+		{name: "Reserved", slug: "admin", wantErr: true},
+		{name: "Too Short", slug: "ab", wantErr: true},
+		{name: "Invalid Characters", slug: "hello world", wantErr: true},
+		{name: "Leading Hyphen", slug: "-hello", wantErr: true},
+		{name: "Valid", slug: "my-site", wantErr: false},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := s.linkService.UpdateLink("testuser", uint64(generated.ID), models.Link{Slug: tc.slug})
+			if tc.wantErr {
+				assert.Error(s.T(), err)
+			} else {
+				assert.NoError(s.T(), err)
+			}
+		})
+	}
+
+	s.Run("Old Slug Keeps Redirecting", func() {
+		assert.NoError(s.T(), s.linkService.UpdateLink("testuser", uint64(podcast.ID), models.Link{Slug: "johndoe-show"}))
+
+		link, err := s.linkService.GetLinkBySlug("johndoe-podcast")
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), podcast.ID, link.ID)
+
+		link, err = s.linkService.GetLinkBySlug("johndoe-show")
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), podcast.ID, link.ID)
+
+		err = s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Other", URL: "https://other.example.com", Slug: "johndoe-podcast"})
+		assert.ErrorIs(s.T(), err, services.ErrSlugTaken)
+	})
+
+	s.Run("Owner Can Reclaim Old Slug", func() {
+		assert.NoError(s.T(), s.linkService.UpdateLink("testuser", uint64(podcast.ID), models.Link{Slug: "johndoe-podcast"}))
+
+		var aliases []models.SlugAlias
+		s.db.Where("link_id = ?", podcast.ID).Find(&aliases)
+		assert.Len(s.T(), aliases, 1)
+		assert.Equal(s.T(), "johndoe-show", aliases[0].Slug)
+	})
+
+	s.Run("Expired Slug Stops Redirecting", func() {
+		s.db.Model(&models.SlugAlias{}).Where("slug = ?", "johndoe-show").Update("expires_at", time.Now().Add(-time.Hour))
+
+		_, err := s.linkService.GetLinkBySlug("johndoe-show")
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Migrate Missing Slugs", func() {
+		var owner models.User
+		s.db.Where("username = ?", "testuser").First(&owner)
+		s.db.Exec("INSERT INTO links (title, url, user_id) VALUES (?, ?, ?)", "Legacy", "https://legacy.example.com", owner.ID)
+
+		assert.NoError(s.T(), database.MigrateLinkSlugs(s.db))
+
+		var legacy models.Link
+		s.db.Where("url = ?", "https://legacy.example.com").First(&legacy)
+		assert.NotEmpty(s.T(), legacy.Slug)
+	})
+}
+
+func (s *ServiceTestSuite) TestParseLinkRecords() {
+	s.Run("CSV", func() {
+		records, err := services.ParseLinkRecordsCSV(strings.NewReader(
+			"url,title,section,unknown\nhttps://a.example.com,A,Music,x\nhttps://b.example.com,B,,y\n"))
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), records, 2)
+		assert.Equal(s.T(), "A", records[0].Title)
+		assert.Equal(s.T(), "Music", records[0].Section)
+		assert.Equal(s.T(), "https://b.example.com", records[1].URL)
+	})
+
+	s.Run("CSV Without URL Column", func() {
+		_, err := services.ParseLinkRecordsCSV(strings.NewReader("title\nA\n"))
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("JSON", func() {
+		records, err := services.ParseLinkRecordsJSON(strings.NewReader(`[{"title":"A","url":"https://a.example.com","slug":"a-link"}]`))
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), records, 1)
+		assert.Equal(s.T(), "a-link", records[0].Slug)
+	})
+
+	s.Run("Bookmarks HTML", func() {
+		bookmarks := `<!DOCTYPE NETSCAPE-Bookmark-file-1>
+<META HTTP-EQUIV="Content-Type" CONTENT="text/html; charset=UTF-8">
+<TITLE>Bookmarks</TITLE>
+<H1>Bookmarks</H1>
+<DL><p>
+    <DT><A HREF="https://top.example.com" ADD_DATE="1700000000">Top Level</A>
+    <DT><H3>Music</H3>
+    <DL><p>
+        <DT><A HREF="https://music.example.com">Music Site</A>
+        <DT><H3>Podcasts</H3>
+        <DL><p>
+            <DT><A HREF="https://pod.example.com">Pod</A>
+        </DL><p>
+        <DT><A HREF="https://after.example.com"></A>
+    </DL><p>
+</DL><p>`
+		records, err := services.ParseBookmarksHTML(strings.NewReader(bookmarks))
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), records, 4)
+		assert.Equal(s.T(), services.LinkRecord{Title: "Top Level", URL: "https://top.example.com"}, records[0])
+		assert.Equal(s.T(), "Music", records[1].Section)
+		assert.Equal(s.T(), "Podcasts", records[2].Section)
+		assert.Equal(s.T(), "Music", records[3].Section)
+		assert.Equal(s.T(), "https://after.example.com", records[3].Title)
+	})
+}
+
+func (s *ServiceTestSuite) TestImportExportLinks() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Existing", URL: "https://existing.example.com"})
+
+	records := []services.LinkRecord{
+		{Title: "First", URL: "https://first.example.com", Section: "Music"},
+		{Title: "Existing", URL: "https://existing.example.com"},
+		{Title: "Second", URL: "https://second.example.com", Section: "Music", Slug: "second-link"},
+		{Title: "First Again", URL: "https://first.example.com"},
+		{Title: "Third", URL: "https://third.example.com"},
+	}
+
+	s.Run("Dry Run", func() {
+		report, err := s.linkService.ImportLinks("testuser", records, true)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 3, report.Valid)
+		assert.Equal(s.T(), 2, report.Duplicates)
+		assert.Equal(s.T(), 0, report.Imported)
+		assert.Equal(s.T(), services.ImportStatusDuplicate, report.Rows[1].Status)
+		assert.Equal(s.T(), services.ImportStatusDuplicate, report.Rows[3].Status)
+
+		var count int64
+		s.db.Model(&models.Link{}).Count(&count)
+		assert.Equal(s.T(), int64(1), count)
+	})
+
+	s.Run("Invalid Rows Reject Import", func() {
+		invalid := append([]services.LinkRecord{{Title: "", URL: "not-a-url"}}, records...)
+		report, err := s.linkService.ImportLinks("testuser", invalid, false)
+		assert.ErrorIs(s.T(), err, services.ErrImportHasInvalidRows)
+		assert.Equal(s.T(), services.ImportStatusInvalid, report.Rows[0].Status)
+		assert.NotEmpty(s.T(), report.Rows[0].Error)
+
+		var count int64
+		s.db.Model(&models.Link{}).Count(&count)
+		assert.Equal(s.T(), int64(1), count)
+	})
+
+	s.Run("Import", func() {
+		report, err := s.linkService.ImportLinks("testuser", records, false)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 3, report.Imported)
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Len(s.T(), profile.Links, 2)
+		assert.Len(s.T(), profile.Sections, 1)
+		assert.Equal(s.T(), "Music", profile.Sections[0].Title)
+		assert.Len(s.T(), profile.Sections[0].Links, 2)
+		assert.Equal(s.T(), "second-link", profile.Sections[0].Links[1].Slug)
+	})
+
+	s.Run("Export", func() {
+		exported, err := s.linkService.ExportLinks("testuser")
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), exported, 4)
+		assert.Equal(s.T(), "Existing", exported[0].Title)
+		assert.Equal(s.T(), "Third", exported[1].Title)
+		assert.Equal(s.T(), "Music", exported[2].Section)
+		assert.Equal(s.T(), 1, exported[3].Position)
+		assert.NotNil(s.T(), exported[0].CreatedAt)
+
+		var csv strings.Builder
+		assert.NoError(s.T(), services.WriteLinkRecordsCSV(&csv, exported))
+		roundTrip, err := services.ParseLinkRecordsCSV(strings.NewReader(csv.String()))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), exported[2].URL, roundTrip[2].URL)
+		assert.Equal(s.T(), exported[2].Slug, roundTrip[2].Slug)
+	})
+
+	s.Run("Import Keeps Positions", func() {
+		report, err := s.linkService.ImportLinks("testuser", []services.LinkRecord{
+			{Title: "Last", URL: "https://last.example.com", Section: "Video", Position: 2},
+			{Title: "Top", URL: "https://top.example.com", Position: 1},
+			{Title: "Middle", URL: "https://middle.example.com", Section: "Video", Position: 1},
+			{Title: "Opening", URL: "https://opening.example.com", Section: "Video", Position: 0},
+			{Title: "Podcast", URL: "https://podcast.example.com", Section: "Audio"},
+		}, false)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 5, report.Imported)
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Equal(s.T(), "Top", profile.Links[2].Title)
+		assert.Equal(s.T(), "Video", profile.Sections[1].Title)
+		assert.Equal(s.T(), "Audio", profile.Sections[2].Title)
+		var titles []string
+		for _, link := range profile.Sections[1].Links {
+			titles = append(titles, link.Title)
+			assert.Equal(s.T(), len(titles)-1, link.Position)
+		}
+		assert.Equal(s.T(), []string{"Opening", "Middle", "Last"}, titles)
+	})
+
+	s.Run("CSV Formula Injection", func() {
+		records := []services.LinkRecord{
+			{Title: "=HYPERLINK(\"https://evil.example.com\")", URL: "https://a.example.com", Section: "@SUM(A1)"},
+			{Title: "+1", URL: "https://b.example.com", Slug: "b-link"},
+			{Title: "-1", URL: "https://c.example.com"},
+			{Title: "Plain - title", URL: "https://d.example.com"},
+		}
+
+		var csv strings.Builder
+		assert.NoError(s.T(), services.WriteLinkRecordsCSV(&csv, records))
+		rows := strings.Split(strings.TrimSpace(csv.String()), "\n")
+		assert.True(s.T(), strings.HasPrefix(rows[1], `"'=HYPERLINK(`))
+		assert.Contains(s.T(), rows[1], ",'@SUM(A1),")
+		assert.True(s.T(), strings.HasPrefix(rows[2], "'+1,"))
+		assert.True(s.T(), strings.HasPrefix(rows[3], "'-1,"))
+		assert.True(s.T(), strings.HasPrefix(rows[4], "Plain - title,"))
+
+		roundTrip, err := services.ParseLinkRecordsCSV(strings.NewReader(csv.String()))
+		assert.NoError(s.T(), err)
+		for i, record := range records {
+			assert.Equal(s.T(), record.Title, roundTrip[i].Title)
+			assert.Equal(s.T(), record.Section, roundTrip[i].Section)
+		}
+	})
+
+	s.Run("Preview And Tags", func() {
+		report, err := s.linkService.ImportLinks("testuser", []services.LinkRecord{{
+			Title:       "Tagged",
+			URL:         "https://tagged.example.com",
+			Description: "A page with a preview",
+			ImageURL:    "https://tagged.example.com/og.png",
+			Tags:        []string{"Music", "live", "music"},
+		}}, false)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 1, report.Imported)
+
+		exported, err := s.linkService.ExportLinks("testuser")
+		assert.NoError(s.T(), err)
+		var tagged services.LinkRecord
+		for _, record := range exported {
+			if record.URL == "https://tagged.example.com" {
+				tagged = record
+			}
+		}
+		assert.Equal(s.T(), "A page with a preview", tagged.Description)
+		assert.Equal(s.T(), "https://tagged.example.com/og.png", tagged.ImageURL)
+		assert.Equal(s.T(), []string{"live", "music"}, tagged.Tags)
+
+		var csv strings.Builder
+		assert.NoError(s.T(), services.WriteLinkRecordsCSV(&csv, []services.LinkRecord{tagged}))
+		roundTrip, err := services.ParseLinkRecordsCSV(strings.NewReader(csv.String()))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), tagged.Description, roundTrip[0].Description)
+		assert.Equal(s.T(), tagged.ImageURL, roundTrip[0].ImageURL)
+		assert.Equal(s.T(), tagged.Tags, roundTrip[0].Tags)
+	})
+
+	s.Run("Invalid Preview Image Or Tags", func() {
+		report, err := s.linkService.ImportLinks("testuser", []services.LinkRecord{
+			{Title: "Script", URL: "https://script.example.com", ImageURL: "javascript:alert(1)"},
+			{Title: "Mail", URL: "https://mail.example.com", ImageURL: "mailto:me@example.com"},
+			{Title: "Long Tag", URL: "https://long.example.com", Tags: []string{strings.Repeat("x", 100)}},
+		}, true)
+		assert.ErrorIs(s.T(), err, services.ErrImportHasInvalidRows)
+		assert.Equal(s.T(), 3, report.Invalid)
+	})
+}
+
+func (s *ServiceTestSuite) TestHealthChecker() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+
+	var headRequests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				atomic.AddInt32(&headRequests, 1)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+
+	config := services.DefaultHealthCheckerConfig()
+	config.Timeout = 2 * time.Second
+	config.MaxRedirects = 3
+	config.PerHostInterval = 0
+	config.AllowPrivateNetworks = true
+	checker := services.NewHealthChecker(s.db, config)
+
+	testCases := []struct {
+		name        string
+		path        string
+		wantHealthy bool
+		wantStatus  int
+		wantFinal   string
+		wantErr     string
+	}{
+		{name: "Healthy", path: "/ok", wantHealthy: true, wantStatus: http.StatusOK, wantFinal: "/ok"},
+		{name: "Not Found", path: "/missing", wantHealthy: false, wantStatus: http.StatusNotFound, wantErr: "Not Found"},
+		{name: "Followed Redirect", path: "/moved", wantHealthy: true, wantStatus: http.StatusOK, wantFinal: "/ok"},
+		{name: "Too Many Redirects", path: "/loop", wantHealthy: false, wantErr: "too many redirects"},
+		{name: "HEAD Not Allowed", path: "/no-head", wantHealthy: true, wantStatus: http.StatusOK, wantFinal: "/no-head"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: tc.name, URL: server.URL + tc.path})
+			assert.NoError(s.T(), err)
+
+			var link models.Link
+			s.db.Where("url = ?", server.URL+tc.path).First(&link)
+
+			health, err := checker.CheckLink(context.Background(), link)
+			assert.NoError(s.T(), err)
+			assert.Equal(s.T(), tc.wantHealthy, health.Healthy)
+			assert.Equal(s.T(), tc.wantStatus, health.LastStatus)
+			if tc.wantFinal != "" {
+				assert.Equal(s.T(), server.URL+tc.wantFinal, health.FinalURL)
+			}
+			if tc.wantErr != "" {
+				assert.Contains(s.T(), health.LastError, tc.wantErr)
+				assert.Equal(s.T(), 1, health.FailureStreak)
+			}
+		})
+	}
+	assert.Equal(s.T(), int32(1), atomic.LoadInt32(&headRequests))
+
+	s.Run("Failure Streak", func() {
+		err := checker.CheckAll(context.Background())
+		assert.NoError(s.T(), err)
+
+		var health models.LinkHealth
+		s.db.Joins("JOIN links ON links.id = link_healths.link_id").
+			Where("links.url = ?", server.URL+"/missing").First(&health)
+		assert.Equal(s.T(), 2, health.FailureStreak)
+
+		var recovered models.LinkHealth
+		s.db.Joins("JOIN links ON links.id = link_healths.link_id").
+			Where("links.url = ?", server.URL+"/ok").First(&recovered)
+		assert.True(s.T(), recovered.Healthy)
+		assert.Equal(s.T(), 0, recovered.FailureStreak)
+	})
+
+	s.Run("Broken Links", func() {
+		links, err := s.linkService.GetLinksHealth("testuser", true)
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), links, 2)
+		for _, link := range links {
+			assert.NotNil(s.T(), link.Health)
+			assert.False(s.T(), link.Health.Healthy)
+		}
+
+		links, err = s.linkService.GetLinksHealth("testuser", false)
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), links, 5)
+	})
+
+	s.Run("Per Host Rate Limit", func() {
+		config.PerHostInterval = 100 * time.Millisecond
+		limited := services.NewHealthChecker(s.db, config)
+
+		var link models.Link
+		s.db.Where("url = ?", server.URL+"/ok").First(&link)
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_, err := limited.CheckLink(context.Background(), link)
+			assert.NoError(s.T(), err)
+		}
+		assert.GreaterOrEqual(s.T(), time.Since(start), 200*time.Millisecond)
+	})
+
+	s.Run("Private Addresses", func() {
+		publicOnly := services.DefaultHealthCheckerConfig()
+		publicOnly.Timeout = 2 * time.Second
+		publicOnly.PerHostInterval = 0
+		guarded := services.NewHealthChecker(s.db, publicOnly)
+
+		var link models.Link
+		s.db.Where("url = ?", server.URL+"/ok").First(&link)
+		health, err := guarded.CheckLink(context.Background(), link)
+		assert.NoError(s.T(), err)
+		assert.False(s.T(), health.Healthy)
+		assert.Zero(s.T(), health.LastStatus)
+		assert.Contains(s.T(), health.LastError, services.ErrBlockedAddress.Error())
+	})
+
+	s.Run("Interval Must Be Positive", func() {
+		os.Setenv("LINK_HEALTH_INTERVAL", "0s")
+		defer os.Unsetenv("LINK_HEALTH_INTERVAL")
+		_, err := services.LoadHealthCheckerConfig()
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestParseLinkMetadata() {
+	base, _ := url.Parse("https://example.com/blog/post")
+
+	testCases := []struct {
+		name string
+		page string
+		want services.LinkMetadata
+	}{
+		{
+			name: "OpenGraph Wins",
+			page: `<html><head><title>Plain</title>
+				<meta name="description" content="Plain description">
+				<meta name="twitter:title" content="Twitter">
+				<meta property="og:title" content="OpenGraph">
+				<meta property="og:description" content="OG description">
+				<meta property="og:image" content="/images/cover.png">
+				<link rel="shortcut icon" href="icon.png"></head><body></body></html>`,
+			want: services.LinkMetadata{
+				Title:       "OpenGraph",
+				Description: "OG description",
+				ImageURL:    "https://example.com/images/cover.png",
+				FaviconURL:  "https://example.com/blog/icon.png",
+			},
+		},
+		{
+			name: "Twitter Card Fallback",
+			page: `<head><title>Plain</title>
+				<meta name="twitter:title" content="Twitter">
+				<meta name="twitter:image" content="https://cdn.example.com/card.jpg"></head>`,
+			want: services.LinkMetadata{
+				Title:      "Twitter",
+				ImageURL:   "https://cdn.example.com/card.jpg",
+				FaviconURL: "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "Title And Description Only",
+			page: `<head><title>
+				Plain Title </title><meta name="description" content="Plain description"></head>
+				<body><meta property="og:title" content="Ignored"></body>`,
+			want: services.LinkMetadata{
+				Title:       "Plain Title",
+				Description: "Plain description",
+				FaviconURL:  "https://example.com/favicon.ico",
+			},
+		},
+		{
+			name: "Unsafe Image Scheme",
+			page: `<head><meta property="og:image" content="javascript:alert(1)"></head>`,
+			want: services.LinkMetadata{
+				FaviconURL: "https://example.com/favicon.ico",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			got := services.ParseLinkMetadata(strings.NewReader(tc.page), base)
+			assert.Equal(s.T(), tc.want, got)
+		})
+	}
+}
+
+func (s *ServiceTestSuite) TestLinkMetadata() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/large":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<head><!--" + strings.Repeat("x", 4096) + "--><title>Too Far</title></head>"))
+		case "/file":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF"))
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<head><title>Profile Page</title>
+				<meta property="og:description" content="All my links">
+				<meta property="og:image" content="/cover.png"></head>`))
+		}
+	}))
+	defer server.Close()
+
+	config := services.DefaultMetadataFetcherConfig()
+	config.AllowPrivateNetworks = true
+	config.MaxBytes = 1024
+	fetcher := services.NewMetadataFetcher(config)
+	linkService := services.NewLinkService(s.db)
+	linkService.SetURLPolicy(localURLPolicy())
+	linkService.SetMetadataFetcher(fetcher)
+
+	s.Run("Title Filled From Page", func() {
+		err := linkService.CreateLink(context.Background(), "testuser", models.Link{URL: server.URL + "/profile"})
+		assert.NoError(s.T(), err)
+
+		var link models.Link
+		s.db.Where("url = ?", server.URL+"/profile").First(&link)
+		assert.Equal(s.T(), "Profile Page", link.Title)
+		assert.Equal(s.T(), "All my links", link.Description)
+		assert.Equal(s.T(), server.URL+"/cover.png", link.ImageURL)
+		assert.Equal(s.T(), server.URL+"/favicon.ico", link.FaviconURL)
+	})
+
+	s.Run("Cached", func() {
+		before := atomic.LoadInt32(&hits)
+		metadata, err := fetcher.Fetch(context.Background(), server.URL+"/profile")
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "Profile Page", metadata.Title)
+		assert.Equal(s.T(), before, atomic.LoadInt32(&hits))
+	})
+
+	s.Run("Refresh Bypasses Cache", func() {
+		var link models.Link
+		s.db.Where("url = ?", server.URL+"/profile").First(&link)
+		s.db.Model(&link).Update("description", "")
+
+		before := atomic.LoadInt32(&hits)
+		refreshed, err := linkService.RefreshLinkMetadata(context.Background(), "testuser", uint64(link.ID))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "All my links", refreshed.Description)
+		assert.Equal(s.T(), before+1, atomic.LoadInt32(&hits))
+	})
+
+	s.Run("Size Limit", func() {
+		metadata, err := fetcher.Fetch(context.Background(), server.URL+"/large")
+		assert.NoError(s.T(), err)
+		assert.Empty(s.T(), metadata.Title)
+
+		err = linkService.CreateLink(context.Background(), "testuser", models.Link{URL: server.URL + "/large"})
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Not HTML", func() {
+		_, err := fetcher.Fetch(context.Background(), server.URL+"/file")
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Private Address Blocked", func() {
+		blocked := services.NewMetadataFetcher(services.DefaultMetadataFetcherConfig())
+		_, err := blocked.Fetch(context.Background(), server.URL+"/profile")
+		assert.ErrorIs(s.T(), err, services.ErrBlockedAddress)
+
+		err = services.NewLinkService(s.db).CreateLink(context.Background(), "testuser", models.Link{URL: server.URL + "/private"})
+		assert.ErrorIs(s.T(), err, services.ErrURLRejected)
+	})
+}
+
+func (s *ServiceTestSuite) TestURLPolicy() {
+	policy := services.NewURLPolicy()
+
+	testCases := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{name: "HTTPS", url: "https://github.com/johndoe"},
+		{name: "Mailto", url: "mailto:john@example.com"},
+		{name: "Tel", url: "tel:+1-555-0100"},
+		{name: "Internationalised Domain", url: "https://münchen.de/"},
+		{name: "Cyrillic Domain Under Cyrillic TLD", url: "https://сеть.рф"},
+		{name: "Javascript", url: "javascript:alert(1)", wantErr: `scheme "javascript" is not allowed`},
+		{name: "Data", url: "data:text/html;base64,PHNjcmlwdD4=", wantErr: `scheme "data" is not allowed`},
+		{name: "File", url: "file:///etc/passwd", wantErr: `scheme "file" is not allowed`},
+		{name: "Private Address", url: "http://10.0.0.5/admin", wantErr: "private and loopback"},
+		{name: "Loopback Address", url: "http://[::1]:8080", wantErr: "private and loopback"},
+		{name: "Localhost", url: "http://localhost:8188", wantErr: "not a public domain"},
+		{name: "Internal TLD", url: "http://db.internal", wantErr: "not a public domain"},
+		{name: "Decimal IP", url: "http://2130706433/", wantErr: "numeric hosts"},
+		{name: "Hex IP", url: "http://0x7f.0.0.1/", wantErr: "numeric hosts"},
+		{name: "Credentials", url: "https://github.com@evil.example/", wantErr: "credentials"},
+		{name: "Bad Email", url: "mailto:nobody", wantErr: "invalid email"},
+		{name: "Bad Phone", url: "tel:call-me", wantErr: "invalid phone"},
+		{name: "No Scheme", url: "github.com/johndoe", wantErr: "invalid url"},
+		{name: "Too Long", url: "https://example.com/" + strings.Repeat("a", 2048), wantErr: "longer than 2048"},
+		{name: "Whole Script Homograph", url: "https://аррӏе.com", wantErr: "look like Latin"},
+		{name: "Punycode Homograph", url: "https://xn--80ak6aa92e.com/login", wantErr: "look like Latin"},
+		{name: "Mixed Scripts", url: "https://xn--pypal-4ve.com", wantErr: "mixes Cyrillic and Latin"},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := policy.Check(tc.url)
+			if tc.wantErr == "" {
+				assert.NoError(s.T(), err)
+			} else {
+				assert.ErrorIs(s.T(), err, services.ErrURLRejected)
+				assert.ErrorContains(s.T(), err, tc.wantErr)
+			}
+		})
+	}
+
+	s.Run("Schemes And Length Are Configurable", func() {
+		custom := services.NewURLPolicy()
+		custom.SetAllowedSchemes([]string{"https"})
+		custom.SetMaxLength(30)
+		assert.ErrorContains(s.T(), custom.Check("http://example.com"), `scheme "http"`)
+		assert.ErrorContains(s.T(), custom.Check("https://example.com/a/long/path"), "longer than 30")
+		assert.NoError(s.T(), custom.Check("https://example.com"))
+	})
+
+	s.Run("Allowlist Skips Domain Checks", func() {
+		custom := services.NewURLPolicy()
+		custom.SetAllowedDomains([]string{"xn--80ak6aa92e.com"})
+		assert.NoError(s.T(), custom.Check("https://аррӏе.com"))
+		assert.Error(s.T(), custom.Check("http://10.0.0.5"))
+	})
+
+	s.Run("Runtime Blocklist", func() {
+		path := s.T().TempDir() + "/blocklist.txt"
+		os.WriteFile(path, []byte("# known phishing\nphishing.example\n\n"), 0o644)
+
+		custom := services.NewURLPolicy()
+		assert.NoError(s.T(), custom.LoadBlocklistFile(path))
+		assert.ErrorContains(s.T(), custom.Check("https://login.phishing.example/"), `"phishing.example" is blocked`)
+
+		assert.NoError(s.T(), custom.BlockDomains([]string{"Malware.Example."}))
+		assert.Equal(s.T(), []string{"malware.example", "phishing.example"}, custom.BlockedDomains())
+		assert.Error(s.T(), custom.Check("https://malware.example"))
+
+		assert.NoError(s.T(), custom.UnblockDomain("phishing.example"))
+		assert.NoError(s.T(), custom.Check("https://login.phishing.example/"))
+		assert.Error(s.T(), custom.UnblockDomain("phishing.example"))
+
+		saved, _ := os.ReadFile(path)
+		assert.Equal(s.T(), "malware.example\n", string(saved))
+	})
+
+	s.Run("Links Are Checked", func() {
+		linkService := services.NewLinkService(s.db)
+		linkService.SetURLPolicy(policy)
+		s.userService.SignUp(models.User{FullName: "Test User", Username: "testuser"}, "password123")
+
+		err := linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "XSS", URL: "javascript:alert(1)"})
+		assert.ErrorIs(s.T(), err, services.ErrURLRejected)
+
+		err = linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Mail", URL: "mailto:john@example.com"})
+		assert.NoError(s.T(), err)
+
+		var link models.Link
+		s.db.Where("title = ?", "Mail").First(&link)
+		err = linkService.UpdateLink("testuser", uint64(link.ID), models.Link{URL: "http://192.168.1.1"})
+		assert.ErrorIs(s.T(), err, services.ErrURLRejected)
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkProtection() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Secret", URL: "https://secret.example.com", Description: "Hidden"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Public", URL: "https://public.example.com"})
+
+	var link models.Link
+	s.db.Where("title = ?", "Secret").First(&link)
+	linkId := uint64(link.ID)
+
+	s.Run("Password Too Short", func() {
+		err := s.linkService.SetLinkPassword("testuser", linkId, "abc")
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Other User", func() {
+		s.userService.SignUp(models.User{FullName: "Other", Username: "other"}, "password123")
+		err := s.linkService.SetLinkPassword("other", linkId, "open-sesame")
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Protect And Hide URL", func() {
+		err := s.linkService.SetLinkPassword("testuser", linkId, "open-sesame")
+		assert.NoError(s.T(), err)
+
+		link, _ = s.linkService.GetLink(linkId)
+		assert.True(s.T(), link.PasswordProtected)
+		assert.NotEqual(s.T(), "open-sesame", link.PasswordHash)
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Len(s.T(), profile.Links, 2)
+		assert.Empty(s.T(), profile.Links[0].URL)
+		assert.Empty(s.T(), profile.Links[0].Description)
+		assert.Equal(s.T(), link.TrackedURL, profile.Links[0].TrackedURL)
+		assert.Equal(s.T(), "https://public.example.com", profile.Links[1].URL)
+
+		blocks, _ := s.blockService.GetBlocks("testuser")
+		assert.Empty(s.T(), blocks[0].Link.URL)
+	})
+
+	s.Run("Unlock", func() {
+		_, err := s.linkService.UnlockLink(link, "wrong", "10.0.0.1")
+		assert.ErrorIs(s.T(), err, services.ErrWrongLinkPassword)
+
+		_, err = s.linkService.UnlockLink(link, "open-sesame", "10.0.0.1")
+		assert.NoError(s.T(), err)
+	})
+
+	s.Run("Rate Limited", func() {
+		for i := 0; i < 5; i++ {
+			_, err := s.linkService.UnlockLink(link, "wrong", "10.0.0.2")
+			assert.ErrorIs(s.T(), err, services.ErrWrongLinkPassword)
+		}
+
+		wait, err := s.linkService.UnlockLink(link, "open-sesame", "10.0.0.2")
+		assert.ErrorIs(s.T(), err, services.ErrTooManyUnlocks)
+		assert.Greater(s.T(), wait, time.Duration(0))
+
+		_, err = s.linkService.UnlockLink(link, "open-sesame", "10.0.0.3")
+		assert.NoError(s.T(), err)
+	})
+
+	s.Run("Track Unlocks", func() {
+		assert.NoError(s.T(), s.analyticsService.TrackLinkUnlock(linkId))
+		assert.NoError(s.T(), s.analyticsService.TrackLinkUnlock(linkId))
+		assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+
+		var analytics models.Analytics
+		s.db.Where("link_id = ?", linkId).First(&analytics)
+		assert.Equal(s.T(), uint(2), analytics.UnlockCount)
+		assert.Equal(s.T(), uint(1), analytics.ClickCount)
+	})
+
+	s.Run("Remove Password", func() {
+		err := s.linkService.SetLinkPassword("testuser", linkId, "")
+		assert.NoError(s.T(), err)
+
+		link, _ = s.linkService.GetLink(linkId)
+		assert.False(s.T(), link.PasswordProtected)
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Equal(s.T(), "https://secret.example.com", profile.Links[0].URL)
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkRules() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "App", URL: "https://app.example.com"})
+
+	var link models.Link
+	s.db.Where("title = ?", "App").First(&link)
+	linkId := uint64(link.ID)
+
+	iPhone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	android := "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Mobile Safari/537.36"
+
+	s.Run("Detect Platform", func() {
+		parser := services.DefaultClickParser()
+		assert.Equal(s.T(), models.PlatformIOS, parser.Platform(iPhone))
+		assert.Equal(s.T(), models.PlatformAndroid, parser.Platform(android))
+		assert.Equal(s.T(), models.PlatformWindows, parser.Platform("Mozilla/5.0 (Windows NT 10.0; Win64; x64)"))
+		assert.Equal(s.T(), models.PlatformMacOS, parser.Platform("Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7)"))
+		assert.Equal(s.T(), models.PlatformOther, parser.Platform(""))
+
+		custom, err := services.NewClickParser([]byte(`{"version": 1, "operating_systems": [
+			{"name": "Android", "patterns": ["HarmonyOS"]},
+			{"name": "Tizen", "patterns": ["tizen"]}
+		]}`))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), models.PlatformAndroid, custom.Platform("Mozilla/5.0 (Linux; HarmonyOS 4.0)"))
+		assert.Equal(s.T(), models.PlatformOther, custom.Platform("Mozilla/5.0 (SMART-TV; Linux; Tizen 7.0)"))
+		assert.Equal(s.T(), models.PlatformOther, custom.Platform(android))
+	})
+
+	invalid := []struct {
+		name string
+		rule models.LinkRule
+	}{
+		{name: "Missing Target", rule: models.LinkRule{Platforms: []string{"ios"}}},
+		{name: "Unknown Platform", rule: models.LinkRule{Platforms: []string{"beos"}, Target: "https://a.example.com"}},
+		{name: "Invalid Language", rule: models.LinkRule{Languages: []string{"not a language"}, Target: "https://a.example.com"}},
+		{name: "Half Time Range", rule: models.LinkRule{TimeFrom: "09:00", Target: "https://a.example.com"}},
+		{name: "Invalid Time", rule: models.LinkRule{TimeFrom: "9am", TimeTo: "17:00", Target: "https://a.example.com"}},
+		{name: "Unknown Timezone", rule: models.LinkRule{TimeFrom: "09:00", TimeTo: "17:00", Timezone: "Mars/Olympus", Target: "https://a.example.com"}},
+	}
+
+	for _, tc := range invalid {
+		s.Run(tc.name, func() {
+			err := s.linkService.SetLinkRules("testuser", linkId, []models.LinkRule{tc.rule})
+			assert.Error(s.T(), err)
+		})
+	}
+
+	rules := []models.LinkRule{
+		{Platforms: []string{models.PlatformIOS}, Target: "https://apps.apple.com/app/id1"},
+		{Platforms: []string{models.PlatformAndroid}, Target: "https://play.google.com/store/apps/details?id=app"},
+		{Languages: []string{"de"}, Target: "https://app.example.com/de"},
+		{Referrers: []string{"instagram.com"}, Target: "https://app.example.com/instagram"},
+		{TimeFrom: "22:00", TimeTo: "06:00", Timezone: "Europe/Berlin", Target: "https://app.example.com/night"},
+	}
+	assert.NoError(s.T(), s.linkService.SetLinkRules("testuser", linkId, rules))
+
+	noon := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name      string
+		req       services.RuleRequest
+		wantIndex int
+	}{
+		{name: "iOS", req: services.RuleRequest{UserAgent: iPhone, AcceptLanguage: "de-DE", Time: noon}, wantIndex: 0},
+		{name: "Android", req: services.RuleRequest{UserAgent: android, Time: noon}, wantIndex: 1},
+		{name: "Regional Language", req: services.RuleRequest{AcceptLanguage: "de-AT,de;q=0.9,en;q=0.8", Time: noon}, wantIndex: 2},
+		{name: "Referrer Subdomain", req: services.RuleRequest{Referrer: "https://l.instagram.com/?u=x", Time: noon}, wantIndex: 3},
+		{name: "Night In Timezone", req: services.RuleRequest{Time: time.Date(2024, 1, 1, 22, 30, 0, 0, time.UTC)}, wantIndex: 4},
+		{name: "Fallback", req: services.RuleRequest{AcceptLanguage: "en-US", Time: noon}, wantIndex: -1},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			result, err := s.linkService.TestLinkRules("testuser", linkId, nil, tc.req)
+			assert.NoError(s.T(), err)
+			assert.Equal(s.T(), tc.wantIndex, result.RuleIndex)
+			if tc.wantIndex >= 0 {
+				assert.Equal(s.T(), rules[tc.wantIndex].Target, result.Target)
+			} else {
+				assert.Equal(s.T(), "https://app.example.com", result.Target)
+			}
+		})
+	}
+
+	s.Run("Platforms From Click Rules", func() {
+		custom, _ := services.NewClickParser([]byte(`{"version": 1, "operating_systems": [{"name": "Android", "patterns": ["HarmonyOS"]}]}`))
+		s.linkService.SetClickParser(custom)
+		defer s.linkService.SetClickParser(services.DefaultClickParser())
+
+		result, err := s.linkService.TestLinkRules("testuser", linkId, nil, services.RuleRequest{UserAgent: "Mozilla/5.0 (Linux; HarmonyOS 4.0)", Time: noon})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), models.PlatformAndroid, result.Platform)
+		assert.Equal(s.T(), 1, result.RuleIndex)
+	})
+
+	s.Run("Unsaved Rules", func() {
+		draft := []models.LinkRule{{Languages: []string{"en"}, Target: "https://app.example.com/en"}}
+		result, err := s.linkService.TestLinkRules("testuser", linkId, draft, services.RuleRequest{AcceptLanguage: "en-GB"})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "https://app.example.com/en", result.Target)
+
+		link, _ = s.linkService.GetLink(linkId)
+		assert.Len(s.T(), link.Rules, len(rules))
+	})
+
+	s.Run("Other User", func() {
+		s.userService.SignUp(models.User{FullName: "Other", Username: "other"}, "password123")
+		err := s.linkService.SetLinkRules("other", linkId, nil)
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Clear Rules", func() {
+		assert.NoError(s.T(), s.linkService.SetLinkRules("testuser", linkId, nil))
+		link, _ = s.linkService.GetLink(linkId)
+		assert.Empty(s.T(), link.Rules)
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkVariants() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Podcast", URL: "https://podcast.example.com"})
+
+	var link models.Link
+	s.db.Where("title = ?", "Podcast").First(&link)
+	linkId := uint64(link.ID)
+
+	s.Run("Empty Variant", func() {
+		_, err := s.linkService.AddLinkVariant("testuser", linkId, models.LinkVariant{Weight: 1})
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Other User", func() {
+		s.userService.SignUp(models.User{FullName: "Other", Username: "other"}, "password123")
+		_, err := s.linkService.AddLinkVariant("other", linkId, models.LinkVariant{Title: "Mine"})
+		assert.Error(s.T(), err)
+	})
+
+	control, err := s.linkService.AddLinkVariant("testuser", linkId, models.LinkVariant{Title: "Podcast"})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), uint(1), control.Weight)
+	challenger, err := s.linkService.AddLinkVariant("testuser", linkId, models.LinkVariant{Title: "Listen now", URL: "https://podcast.example.com/latest", Weight: 3})
+	assert.NoError(s.T(), err)
+
+	s.Run("Weighted Sticky Assignment", func() {
+		variants := []models.LinkVariant{control, challenger}
+		picked := map[uint]int{}
+		for i := 0; i < 1000; i++ {
+			visitor := fmt.Sprintf("visitor-%d", i)
+			first := services.PickLinkVariant(variants, link.ID, visitor)
+			again := services.PickLinkVariant(variants, link.ID, visitor)
+			assert.Equal(s.T(), first.ID, again.ID)
+			picked[first.ID]++
+		}
+		assert.InDelta(s.T(), 750, picked[challenger.ID], 60)
+	})
+
+	s.Run("Profile Shows Variant", func() {
+		profile, err := s.userService.GetUserProfileForVisitor("testuser", "visitor-1", "")
+		assert.NoError(s.T(), err)
+		shown := profile.Links[0]
+		assert.NotNil(s.T(), shown.VariantID)
+		assert.Contains(s.T(), shown.TrackedURL, fmt.Sprintf("?v=%d", *shown.VariantID))
+
+		again, _ := s.userService.GetUserProfileForVisitor("testuser", "visitor-1", "")
+		assert.Equal(s.T(), *shown.VariantID, *again.Links[0].VariantID)
+
+		var variant models.LinkVariant
+		s.db.First(&variant, *shown.VariantID)
+		assert.Equal(s.T(), uint(2), variant.Impressions)
+
+		plain, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Nil(s.T(), plain.Links[0].VariantID)
+		assert.Equal(s.T(), "Podcast", plain.Links[0].Title)
+	})
+
+	s.Run("Report", func() {
+		s.db.Model(&control).Updates(map[string]interface{}{"impressions": 1000, "clicks": 50})
+		s.db.Model(&challenger).Updates(map[string]interface{}{"impressions": 1000, "clicks": 90})
+		assert.NoError(s.T(), s.analyticsService.TrackVariantClick(linkId, challenger.ID))
+		assert.Error(s.T(), s.analyticsService.TrackVariantClick(linkId+1, challenger.ID))
+
+		report, err := s.analyticsService.GetVariantReport("testuser", linkId)
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), report, 2)
+		assert.InDelta(s.T(), 0.05, report[0].CTR, 0.0001)
+		assert.Equal(s.T(), uint(91), report[1].Clicks)
+		assert.Greater(s.T(), report[1].ZScore, 1.96)
+		assert.True(s.T(), report[1].Significant)
+		assert.False(s.T(), report[0].Significant)
+	})
+
+	s.Run("Not Significant With Few Impressions", func() {
+		s.db.Model(&control).Updates(map[string]interface{}{"impressions": 10, "clicks": 1})
+		s.db.Model(&challenger).Updates(map[string]interface{}{"impressions": 10, "clicks": 6})
+
+		report, _ := s.analyticsService.GetVariantReport("testuser", linkId)
+		assert.False(s.T(), report[1].Significant)
+	})
+
+	s.Run("Promote Winner", func() {
+		promoted, err := s.linkService.PromoteLinkVariant("testuser", linkId, uint64(challenger.ID))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "Listen now", promoted.Title)
+
+		link, _ = s.linkService.GetLink(linkId)
+		assert.Equal(s.T(), "https://podcast.example.com/latest", link.URL)
+
+		var count int64
+		s.db.Model(&models.LinkVariant{}).Where("link_id = ?", linkId).Count(&count)
+		assert.Equal(s.T(), int64(0), count)
+	})
+}
+
+func (s *ServiceTestSuite) TestUTMTemplates() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com/?ref=bio#top", Slug: "shop"})
+
+	var link models.Link
+	s.db.Where("slug = ?", "shop").First(&link)
+	linkId := uint64(link.ID)
+
+	s.Run("Append", func() {
+		testCases := []struct {
+			name   string
+			target string
+			want   string
+		}{
+			{name: "No Query", target: "https://a.example.com/page", want: "https://a.example.com/page?utm_campaign=jd-7&utm_source=bio"},
+			{name: "Keeps Query And Fragment", target: "https://a.example.com/?b=2&a=1#x", want: "https://a.example.com/?b=2&a=1&utm_campaign=jd-7&utm_source=bio#x"},
+			{name: "Existing Param Wins", target: "https://a.example.com/?utm_source=newsletter", want: "https://a.example.com/?utm_source=newsletter&utm_campaign=jd-7"},
+			{name: "Not HTTP", target: "mailto:jd@example.com", want: "mailto:jd@example.com"},
+		}
+
+		template := models.UTMTemplate{Source: "bio", Campaign: "{username}-{link_id}"}
+		values := map[string]string{"{username}": "jd", "{link_id}": "7"}
+		for _, tc := range testCases {
+			s.Run(tc.name, func() {
+				assert.Equal(s.T(), tc.want, services.AppendUTM(tc.target, template, values))
+			})
+		}
+	})
+
+	s.Run("Invalid Templates", func() {
+		assert.Error(s.T(), s.userService.SetProfileUTM("testuser", models.UTMTemplate{Campaign: "{email}"}))
+		assert.Error(s.T(), s.userService.SetProfileUTM("testuser", models.UTMTemplate{Campaign: "{username"}))
+		assert.Error(s.T(), s.linkService.SetLinkUTM("testuser", linkId, models.UTMTemplate{Source: strings.Repeat("a", 201)}))
+	})
+
+	s.Run("No Template", func() {
+		target, err := s.linkService.TagOutgoingURL(link, link.URL)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "https://shop.example.com/?ref=bio#top", target)
+	})
+
+	s.Run("Profile And Link Templates", func() {
+		assert.NoError(s.T(), s.userService.SetProfileUTM("testuser", models.UTMTemplate{Source: "linktree", Medium: "social", Campaign: "{username}"}))
+		assert.NoError(s.T(), s.linkService.SetLinkUTM("testuser", linkId, models.UTMTemplate{Campaign: "spring-sale", Content: "{slug}"}))
+
+		link, _ = s.linkService.GetLink(linkId)
+		target, err := s.linkService.TagOutgoingURL(link, link.URL)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "https://shop.example.com/?ref=bio&utm_campaign=spring-sale&utm_content=shop&utm_medium=social&utm_source=linktree#top", target)
+		assert.Equal(s.T(), "https://shop.example.com/?ref=bio#top", link.URL)
+	})
+
+	s.Run("Clear Link Template", func() {
+		assert.NoError(s.T(), s.linkService.SetLinkUTM("testuser", linkId, models.UTMTemplate{}))
+
+		link, _ = s.linkService.GetLink(linkId)
+		assert.Nil(s.T(), link.UTM)
+		target, _ := s.linkService.TagOutgoingURL(link, link.URL)
+		assert.Contains(s.T(), target, "utm_campaign=testuser")
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkLimits() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Giveaway", URL: "https://giveaway.example.com", Slug: "giveaway"})
+
+	var link models.Link
+	s.db.Where("slug = ?", "giveaway").First(&link)
+	linkId := uint64(link.ID)
+	two := uint(2)
+
+	invalid := []struct {
+		name   string
+		limits services.LinkLimits
+	}{
+		{name: "Unknown Action", limits: services.LinkLimits{MaxClicks: &two, ExpiryAction: "explode"}},
+		{name: "Fallback Without URL", limits: services.LinkLimits{MaxClicks: &two, ExpiryAction: models.ExpiryActionFallback}},
+		{name: "Blocked Fallback", limits: services.LinkLimits{MaxClicks: &two, ExpiryAction: models.ExpiryActionFallback, FallbackURL: "javascript:alert(1)"}},
+	}
+
+	for _, tc := range invalid {
+		s.Run(tc.name, func() {
+			assert.Error(s.T(), s.linkService.SetLinkLimits("testuser", linkId, tc.limits))
+		})
+	}
+
+	s.Run("Unlimited", func() {
+		allowed, err := s.linkService.ClaimClick(link)
+		assert.NoError(s.T(), err)
+		assert.True(s.T(), allowed)
+	})
+
+	s.Run("Click Cap", func() {
+		assert.NoError(s.T(), s.linkService.SetLinkLimits("testuser", linkId, services.LinkLimits{MaxClicks: &two, ExpiryAction: models.ExpiryActionHide}))
+		link, _ = s.linkService.GetLink(linkId)
+
+		for i := 0; i < 2; i++ {
+			allowed, err := s.linkService.ClaimClick(link)
+			assert.NoError(s.T(), err)
+			assert.True(s.T(), allowed)
+		}
+		allowed, _ := s.linkService.ClaimClick(link)
+		assert.False(s.T(), allowed)
+
+		link, _ = s.linkService.GetLink(linkId)
+		assert.True(s.T(), link.Expired)
+		assert.Equal(s.T(), uint(2), link.ClicksUsed)
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Empty(s.T(), profile.Links)
+	})
+
+	s.Run("Reset Clicks", func() {
+		assert.NoError(s.T(), s.linkService.SetLinkLimits("testuser", linkId, services.LinkLimits{MaxClicks: &two, ResetClicks: true}))
+		link, _ = s.linkService.GetLink(linkId)
+		assert.False(s.T(), link.Expired)
+		assert.Equal(s.T(), models.ExpiryActionSoldOut, link.ExpiryAction)
+	})
+
+	s.Run("Expiry Date With Fallback", func() {
+		past := time.Now().Add(-time.Minute)
+		assert.NoError(s.T(), s.linkService.SetLinkLimits("testuser", linkId, services.LinkLimits{
+			ExpiresAt:    &past,
+			ExpiryAction: models.ExpiryActionFallback,
+			FallbackURL:  "https://giveaway.example.com/ended",
+		}))
+		link, _ = s.linkService.GetLink(linkId)
+		assert.True(s.T(), link.Expired)
+
+		allowed, _ := s.linkService.ClaimClick(link)
+		assert.False(s.T(), allowed)
+
+		profile, _ := s.userService.GetUserProfileInfo("testuser")
+		assert.Equal(s.T(), "https://giveaway.example.com/ended", profile.Links[0].URL)
+	})
+
+	s.Run("Remove Limits", func() {
+		assert.NoError(s.T(), s.linkService.SetLinkLimits("testuser", linkId, services.LinkLimits{}))
+		link, _ = s.linkService.GetLink(linkId)
+		assert.Nil(s.T(), link.MaxClicks)
+		assert.Nil(s.T(), link.ExpiresAt)
+		assert.Empty(s.T(), link.ExpiryAction)
+		assert.False(s.T(), link.Expired)
+	})
+}
+
+func (s *ServiceTestSuite) TestConcurrentClickCap() {
+	// An in-memory database is private to one connection, so concurrent
+	// clicks need a database file that all pooled connections share.
+	db, err := gorm.Open(sqlite.Open(s.T().TempDir()+"/clicks.db?_busy_timeout=5000"), &gorm.Config{})
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	db.AutoMigrate(&models.Link{})
+
+	limit := uint(10)
+	link := models.Link{Title: "Giveaway", URL: "https://giveaway.example.com", Slug: "giveaway", MaxClicks: &limit}
+	db.Create(&link)
+
+	linkService := services.NewLinkService(db)
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := linkService.ClaimClick(link)
+			assert.NoError(s.T(), err)
+			if ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(s.T(), int32(limit), allowed.Load())
+
+	db.First(&link, link.ID)
+	assert.Equal(s.T(), limit, link.ClicksUsed)
+}
+
+func (s *ServiceTestSuite) TestConcurrentClickCounting() {
+	db, err := gorm.Open(sqlite.Open(s.T().TempDir()+"/clicks.db?_busy_timeout=5000"), &gorm.Config{})
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	db.AutoMigrate(&models.User{}, &models.Link{}, &models.Analytics{}, &models.ClickEvent{})
+
+	link := models.Link{Title: "Launch", URL: "https://launch.example.com", Slug: "launch"}
+	db.Create(&link)
+
+	analyticsService := services.NewAnalyticsService(db)
+	clicks := 300
+	var wg sync.WaitGroup
+	for i := 0; i < clicks; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			assert.NoError(s.T(), analyticsService.TrackLinkClicks(uint64(link.ID), services.ClickDetails{}))
+			if i%3 == 0 {
+				assert.NoError(s.T(), analyticsService.TrackLinkUnlock(uint64(link.ID)))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	var rows []models.Analytics
+	db.Where("link_id = ?", link.ID).Find(&rows)
+	assert.Len(s.T(), rows, 1)
+	assert.Equal(s.T(), uint(clicks), rows[0].ClickCount)
+	assert.Equal(s.T(), uint(clicks/3), rows[0].UnlockCount)
+
+	var events int64
+	db.Model(&models.ClickEvent{}).Where("link_id = ?", link.ID).Count(&events)
+	assert.Equal(s.T(), int64(clicks), events)
+}
+
+// legacyAnalytics is the analytics table as it was before link_id was unique
+// and before any of the later counters were added.
+type legacyAnalytics struct {
+	ID                uint
+	ClickCount        uint
+	VisitorsUsernames string
+	LinkID            uint
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+func (legacyAnalytics) TableName() string { return "analytics" }
+
+func (s *ServiceTestSuite) TestMergeDuplicateAnalytics() {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	db.AutoMigrate(&legacyAnalytics{})
+	db.Create(&[]legacyAnalytics{
+		{LinkID: 1, ClickCount: 5, VisitorsUsernames: `["alice"]`},
+		{LinkID: 1, ClickCount: 3, VisitorsUsernames: `["bob", "alice"]`},
+		{LinkID: 2, ClickCount: 7, VisitorsUsernames: `[]`},
+	})
+
+	assert.NoError(s.T(), database.MergeDuplicateAnalytics(db))
+	assert.NoError(s.T(), db.AutoMigrate(&models.Analytics{}))
+
+	var rows []models.Analytics
+	db.Order("link_id").Find(&rows)
+	assert.Len(s.T(), rows, 2)
+	assert.Equal(s.T(), uint(8), rows[0].ClickCount)
+	assert.Equal(s.T(), uint(7), rows[1].ClickCount)
+
+	err = db.Create(&models.Analytics{LinkID: 2}).Error
+	assert.Error(s.T(), err)
+
+	assert.NoError(s.T(), database.DropVisitorIdentifiers(db))
+	assert.False(s.T(), db.Migrator().HasColumn(&models.Analytics{}, "visitors_usernames"))
+
+	s.Run("Later Counters", func() {
+		db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+		if err != nil {
+			s.T().Fatal(err)
+		}
+		db.AutoMigrate(&legacyAnalytics{})
+		db.Migrator().AddColumn(&models.Analytics{}, "UnlockCount")
+		db.Create(&[]legacyAnalytics{{LinkID: 1, ClickCount: 5}, {LinkID: 1, ClickCount: 3}})
+		db.Model(&legacyAnalytics{}).Where("link_id = ?", 1).UpdateColumn("unlock_count", 2)
+
+		assert.NoError(s.T(), database.MergeDuplicateAnalytics(db))
+		assert.NoError(s.T(), db.AutoMigrate(&models.Analytics{}))
+
+		var merged models.Analytics
+		db.Where("link_id = ?", 1).First(&merged)
+		assert.Equal(s.T(), uint(8), merged.ClickCount)
+		assert.Equal(s.T(), uint(4), merged.UnlockCount)
+	})
+}
+
+func (s *ServiceTestSuite) TestClickIngester() {
+	// Workers write from their own connections, which an in-memory database
+	// would not share.
+	db, err := gorm.Open(sqlite.Open(s.T().TempDir()+"/clicks.db?_busy_timeout=5000"), &gorm.Config{})
+	if err != nil {
+		s.T().Fatal(err)
+	}
+	db.AutoMigrate(&models.User{}, &models.Link{}, &models.Analytics{}, &models.ClickEvent{})
+
+	link := models.Link{Title: "Launch", URL: "https://launch.example.com", Slug: "launch"}
+	db.Create(&link)
+	linkId := uint64(link.ID)
+
+	clickCount := func() int64 {
+		var count int64
+		db.Model(&models.ClickEvent{}).Where("link_id = ?", linkId).Count(&count)
+		return count
+	}
+	newIngester := func(config services.ClickIngesterConfig) (*services.AnalyticsService, *services.ClickIngester) {
+		db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.ClickEvent{})
+		db.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.Analytics{})
+		ingester := services.NewClickIngester(db, config)
+		analyticsService := services.NewAnalyticsService(db)
+		analyticsService.SetClickIngester(ingester)
+		return analyticsService, ingester
+	}
+
+	s.Run("Flush On Batch Size", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 100, Workers: 1, BatchSize: 5, FlushInterval: time.Hour})
+		ingester.Start()
+		defer ingester.Close(context.Background())
+
+		for i := 0; i < 5; i++ {
+			assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		}
+		assert.Eventually(s.T(), func() bool { return clickCount() == 5 }, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(s.T(), int64(1), ingester.Stats().Flushes)
+	})
+
+	s.Run("Flush On Interval", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 100, Workers: 2, BatchSize: 100, FlushInterval: 20 * time.Millisecond})
+		ingester.Start()
+		defer ingester.Close(context.Background())
+
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.Eventually(s.T(), func() bool { return clickCount() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+		var analytics models.Analytics
+		db.Where("link_id = ?", linkId).First(&analytics)
+		assert.Equal(s.T(), uint(2), analytics.ClickCount)
+	})
+
+	s.Run("Drop When Full", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 1, BatchSize: 10, Overflow: services.OverflowDrop})
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		}
+		stats := ingester.Stats()
+		assert.Equal(s.T(), 1, stats.QueueDepth)
+		assert.Equal(s.T(), int64(1), stats.Enqueued)
+		assert.Equal(s.T(), int64(2), stats.Dropped)
+		assert.Zero(s.T(), clickCount())
+
+		ingester.Start()
+		assert.NoError(s.T(), ingester.Close(context.Background()))
+		assert.Equal(s.T(), int64(1), clickCount())
+	})
+
+	s.Run("Write Synchronously When Full", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 1, BatchSize: 10, Overflow: services.OverflowSync})
+
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.Equal(s.T(), int64(1), ingester.Stats().Overflowed)
+		assert.Equal(s.T(), int64(1), clickCount())
+
+		ingester.Start()
+		ingester.Close(context.Background())
+	})
+
+	s.Run("Block Times Out", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 1, BatchSize: 10, Overflow: services.OverflowBlock, BlockTimeout: 10 * time.Millisecond})
+
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.Equal(s.T(), int64(1), ingester.Stats().Dropped)
+
+		ingester.Start()
+		ingester.Close(context.Background())
+	})
+
+	s.Run("Failed Write", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 10, BatchSize: 2, FlushInterval: time.Hour})
+		assert.NoError(s.T(), db.Migrator().RenameTable(&models.ClickEvent{}, "click_events_offline"))
+		defer db.Migrator().RenameTable("click_events_offline", &models.ClickEvent{})
+
+		ingester.Start()
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.NoError(s.T(), ingester.Close(context.Background()))
+
+		stats := ingester.Stats()
+		assert.Equal(s.T(), int64(1), stats.FlushErrors)
+		assert.Equal(s.T(), int64(2), stats.FailedClicks)
+		assert.Equal(s.T(), int64(2), stats.Dropped)
+	})
+
+	s.Run("Drain On Close", func() {
+		analyticsService, ingester := newIngester(services.ClickIngesterConfig{QueueSize: 1000, Workers: 4, BatchSize: 1000, FlushInterval: time.Hour})
+		ingester.Start()
+
+		var wg sync.WaitGroup
+		for i := 0; i < 200; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+			}()
+		}
+		wg.Wait()
+
+		assert.NoError(s.T(), ingester.Close(context.Background()))
+		assert.Equal(s.T(), int64(200), clickCount())
+		assert.Zero(s.T(), ingester.Stats().QueueDepth)
+
+		var analytics models.Analytics
+		db.Where("link_id = ?", linkId).First(&analytics)
+		assert.Equal(s.T(), uint(200), analytics.ClickCount)
+
+		assert.NoError(s.T(), analyticsService.TrackLinkClicks(linkId, services.ClickDetails{}))
+		assert.Equal(s.T(), int64(201), clickCount())
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkSearch() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.userService.SignUp(models.User{FullName: "Other", Username: "other"}, "password123")
+
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "My Podcast", URL: "https://podcast.example.com", Slug: "podcast"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "GitHub", URL: "https://github.com/johndoe", Slug: "github"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Blog", URL: "https://blog.example.com", Slug: "blog"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "100% Sale", URL: "https://shop.example.com", Slug: "sale"})
+	s.linkService.CreateLink(context.Background(), "other", models.Link{Title: "Other Podcast", URL: "https://other.example.com", Slug: "other-podcast"})
+
+	linkIds := map[string]uint64{}
+	var links []models.Link
+	s.db.Find(&links)
+	for _, link := range links {
+		linkIds[link.Slug] = uint64(link.ID)
+	}
+	s.db.Model(&models.Link{}).Where("slug = ?", "blog").Update("description", "Notes about audio engineering")
+
+	s.Run("Invalid Tags", func() {
+		_, err := s.linkService.SetLinkTags("testuser", linkIds["podcast"], []string{strings.Repeat("a", 33)})
+		assert.Error(s.T(), err)
+		_, err = s.linkService.SetLinkTags("other", linkIds["podcast"], []string{"audio"})
+		assert.Error(s.T(), err)
+	})
+
+	tags, err := s.linkService.SetLinkTags("testuser", linkIds["podcast"], []string{" Audio ", "weekly", "audio"})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"audio", "weekly"}, tags)
+	s.linkService.SetLinkTags("testuser", linkIds["github"], []string{"code"})
+	s.linkService.SetLinkTags("testuser", linkIds["blog"], []string{"audio", "writing"})
+
+	testCases := []struct {
+		name      string
+		query     services.LinkQuery
+		wantSlugs []string
+		wantTotal int64
+	}{
+		{name: "All Links", query: services.LinkQuery{}, wantSlugs: []string{"podcast", "github", "blog", "sale"}, wantTotal: 4},
+		{name: "One Tag", query: services.LinkQuery{Tags: []string{"audio"}}, wantSlugs: []string{"podcast", "blog"}, wantTotal: 2},
+		{name: "All Tags Required", query: services.LinkQuery{Tags: []string{"AUDIO", "weekly"}}, wantSlugs: []string{"podcast"}, wantTotal: 1},
+		{name: "Title", query: services.LinkQuery{Text: "podcast"}, wantSlugs: []string{"podcast"}, wantTotal: 1},
+		{name: "URL", query: services.LinkQuery{Text: "github.com"}, wantSlugs: []string{"github"}, wantTotal: 1},
+		{name: "Description Or Tag", query: services.LinkQuery{Text: "audio"}, wantSlugs: []string{"podcast", "blog"}, wantTotal: 2},
+		{name: "Every Word", query: services.LinkQuery{Text: "audio writing"}, wantSlugs: []string{"blog"}, wantTotal: 1},
+		{name: "Text And Tag", query: services.LinkQuery{Text: "example", Tags: []string{"writing"}}, wantSlugs: []string{"blog"}, wantTotal: 1},
+		{name: "Wildcards Are Literal", query: services.LinkQuery{Text: "100%"}, wantSlugs: []string{"sale"}, wantTotal: 1},
+		{name: "Percent Alone", query: services.LinkQuery{Text: "%"}, wantSlugs: []string{"sale"}, wantTotal: 1},
+		{name: "Paginated", query: services.LinkQuery{Page: 2, PerPage: 3}, wantSlugs: []string{"sale"}, wantTotal: 4},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			page, err := s.linkService.SearchLinks("testuser", tc.query)
+			assert.NoError(s.T(), err)
+			assert.Equal(s.T(), tc.wantTotal, page.Total)
+
+			slugs := []string{}
+			for _, link := range page.Links {
+				slugs = append(slugs, link.Slug)
+			}
+			assert.Equal(s.T(), tc.wantSlugs, slugs)
+		})
+	}
+
+	s.Run("Tags Loaded", func() {
+		page, _ := s.linkService.SearchLinks("testuser", services.LinkQuery{Text: "podcast"})
+		assert.Equal(s.T(), []string{"audio", "weekly"}, page.Links[0].Tags)
+	})
+
+	s.Run("Tags Removed With Link", func() {
+		s.linkService.DeleteLink("testuser", linkIds["podcast"])
+
+		var count int64
+		s.db.Model(&models.LinkTag{}).Where("link_id = ?", linkIds["podcast"]).Count(&count)
+		assert.Equal(s.T(), int64(0), count)
+	})
+}
+
+func (s *ServiceTestSuite) TestLinkRevisions() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com/v1", Slug: "shop"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Blog", URL: "https://blog.example.com"})
+
+	var link models.Link
+	s.db.Where("slug = ?", "shop").First(&link)
+	linkId := uint64(link.ID)
+	created := link.CreatedAt
+
+	s.Run("No Revision Without Change", func() {
+		assert.NoError(s.T(), s.linkService.UpdateLink("testuser", linkId, models.Link{Slug: "store"}))
+
+		revisions, err := s.linkService.GetLinkRevisions("testuser", linkId)
+		assert.NoError(s.T(), err)
+		assert.Empty(s.T(), revisions)
+	})
+
+	assert.NoError(s.T(), s.linkService.UpdateLink("testuser", linkId, models.Link{URL: "https://shop.example.com/v2"}))
+	betweenChanges := time.Now()
+	assert.NoError(s.T(), s.linkService.UpdateLink("testuser", linkId, models.Link{Title: "Store", URL: "https://shop.example.com/v3"}))
+
+	revisions, err := s.linkService.GetLinkRevisions("testuser", linkId)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), revisions, 2)
+
+	s.Run("Revision History", func() {
+		assert.Equal(s.T(), "https://shop.example.com/v2", revisions[0].URL)
+		assert.Equal(s.T(), "Shop", revisions[0].Title)
+		assert.Equal(s.T(), "https://shop.example.com/v1", revisions[1].URL)
+		assert.Equal(s.T(), "testuser", revisions[1].Actor)
+		assert.WithinDuration(s.T(), created, revisions[1].ValidFrom, time.Millisecond)
+		assert.Equal(s.T(), revisions[1].ValidTo, revisions[0].ValidFrom)
+	})
+
+	s.Run("URL Live At", func() {
+		url, err := s.analyticsService.LinkURLAt(linkId, created.Add(-time.Second))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "https://shop.example.com/v1", url)
+
+		url, _ = s.analyticsService.LinkURLAt(linkId, betweenChanges)
+		assert.Equal(s.T(), "https://shop.example.com/v2", url)
+
+		url, _ = s.analyticsService.LinkURLAt(linkId, time.Now().Add(time.Hour))
+		assert.Equal(s.T(), "https://shop.example.com/v3", url)
+	})
+
+	s.Run("Rollback", func() {
+		restored, err := s.linkService.RollbackLink("testuser", linkId, uint64(revisions[1].ID))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), "https://shop.example.com/v1", restored.URL)
+		assert.Equal(s.T(), "Shop", restored.Title)
+
+		history, _ := s.linkService.GetLinkRevisions("testuser", linkId)
+		assert.Len(s.T(), history, 3)
+		assert.Equal(s.T(), "https://shop.example.com/v3", history[0].URL)
+	})
+
+	s.Run("Rollback To Taken URL", func() {
+		s.db.Model(&models.LinkRevision{}).Where("id = ?", revisions[0].ID).Update("url", "https://blog.example.com")
+		_, err := s.linkService.RollbackLink("testuser", linkId, uint64(revisions[0].ID))
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Other User", func() {
+		s.userService.SignUp(models.User{FullName: "Other", Username: "other"}, "password123")
+		_, err := s.linkService.GetLinkRevisions("other", linkId)
+		assert.Error(s.T(), err)
+		_, err = s.linkService.RollbackLink("other", linkId, uint64(revisions[1].ID))
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestClickEvents() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+
+	var link models.Link
+	s.db.First(&link)
+	linkId := uint64(link.ID)
+
+	s.Run("Click Stored As Event", func() {
+		err := s.analyticsService.TrackLinkClicks(linkId, services.ClickDetails{
+			Referrer:  "https://www.instagram.com/",
+			UserAgent: "Mozilla/5.0 (iPhone)",
+			Country:   "DE",
+			VisitorID: "cookie-123",
+		})
+		assert.NoError(s.T(), err)
+
+		var event models.ClickEvent
+		assert.NoError(s.T(), s.db.Where("link_id = ?", linkId).First(&event).Error)
+		assert.Equal(s.T(), "https://www.instagram.com/", event.Referrer)
+		assert.Equal(s.T(), "Mozilla/5.0 (iPhone)", event.UserAgent)
+		assert.Equal(s.T(), "DE", event.Country)
+		assert.Empty(s.T(), event.VisitorHash)
+		assert.WithinDuration(s.T(), time.Now(), event.OccurredAt, time.Minute)
+	})
+
+	s.Run("Anonymous Click", func() {
+		assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(linkId, services.ClickDetails{Country: "Germany"}))
+
+		var event models.ClickEvent
+		s.db.Where("link_id = ?", linkId).Order("id DESC").First(&event)
+		assert.Empty(s.T(), event.Country)
+	})
+
+	s.Run("Counts From Events", func() {
+		s.db.Create(&models.ClickEvent{LinkID: link.ID, OccurredAt: time.Now().Add(-48 * time.Hour)})
+
+		count, err := s.analyticsService.CountClicks(linkId, time.Now().Add(-24*time.Hour), time.Time{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(2), count)
+
+		count, _ = s.analyticsService.CountClicks(linkId, time.Time{}, time.Now().Add(-24*time.Hour))
+		assert.Equal(s.T(), int64(1), count)
+
+		var analytics models.Analytics
+		s.db.Where("link_id = ?", linkId).First(&analytics)
+		assert.Equal(s.T(), uint(2), analytics.ClickCount)
+	})
+
+	s.Run("Migration Keeps Historical Totals", func() {
+		s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Old", URL: "https://old.example.com"})
+		var old models.Link
+		s.db.Where("url = ?", "https://old.example.com").First(&old)
+		s.db.Create(&models.Analytics{LinkID: old.ID, ClickCount: 40})
+
+		assert.NoError(s.T(), database.MigrateClickEvents(s.db))
+		assert.NoError(s.T(), database.MigrateClickEvents(s.db))
+		assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(old.ID), services.ClickDetails{}))
+
+		count, err := s.analyticsService.CountClicks(uint64(old.ID), time.Time{}, time.Time{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(41), count)
+
+		var analytics models.Analytics
+		s.db.Where("link_id = ?", old.ID).First(&analytics)
+		assert.Equal(s.T(), uint(41), analytics.ClickCount)
+
+		recent, _ := s.analyticsService.CountClicks(uint64(old.ID), time.Now().Add(-time.Hour), time.Time{})
+		assert.Equal(s.T(), int64(1), recent)
+	})
+
+	s.Run("Deleted With Link", func() {
+		assert.NoError(s.T(), s.linkService.DeleteLink("testuser", linkId))
+
+		var count int64
+		s.db.Model(&models.ClickEvent{}).Where("link_id = ?", linkId).Count(&count)
+		assert.Zero(s.T(), count)
+	})
+}
+
+func (s *ServiceTestSuite) TestClickSeries() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.userService.SignUp(models.User{FullName: "Other", Username: "other"}, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Blog", URL: "https://blog.example.com"})
+	s.linkService.CreateLink(context.Background(), "other", models.Link{Title: "Other", URL: "https://other.example.com"})
+
+	var shop, blog, other models.Link
+	s.db.Where("title = ?", "Shop").First(&shop)
+	s.db.Where("title = ?", "Blog").First(&blog)
+	s.db.Where("title = ?", "Other").First(&other)
+
+	at := func(value string) time.Time {
+		parsed, _ := time.Parse(time.RFC3339, value)
+		return parsed
+	}
+	for _, event := range []models.ClickEvent{
+		{LinkID: shop.ID, OccurredAt: at("2024-03-04T10:15:00Z")},
+		{LinkID: shop.ID, OccurredAt: at("2024-03-04T10:45:00Z")},
+		{LinkID: blog.ID, OccurredAt: at("2024-03-04T23:30:00Z")},
+		{LinkID: shop.ID, OccurredAt: at("2024-03-12T09:00:00Z")},
+		{LinkID: other.ID, OccurredAt: at("2024-03-04T10:00:00Z")},
+	} {
+		s.db.Create(&event)
+	}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	day := func(value string, loc *time.Location) time.Time {
+		parsed, _ := time.ParseInLocation("2006-01-02", value, loc)
+		return parsed
+	}
+	clicks := func(series services.ClickSeries) []int64 {
+		values := make([]int64, 0, len(series.Points))
+		for _, point := range series.Points {
+			values = append(values, point.Clicks)
+		}
+		return values
+	}
+
+	check := func() {
+		series, err := s.analyticsService.GetLinkClickSeries("testuser", uint64(shop.ID), services.SeriesQuery{
+			From: day("2024-03-04", time.UTC), To: day("2024-03-12", time.UTC), Interval: services.IntervalDay,
+		})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), []int64{2, 0, 0, 0, 0, 0, 0, 0, 1}, clicks(series))
+		assert.Equal(s.T(), int64(3), series.Total)
+
+		series, _ = s.analyticsService.GetProfileClickSeries("testuser", services.SeriesQuery{
+			From: day("2024-03-04", berlin), To: day("2024-03-05", berlin), Interval: services.IntervalDay, Location: berlin,
+		})
+		assert.Equal(s.T(), []int64{2, 1}, clicks(series))
+		assert.Equal(s.T(), "Europe/Berlin", series.Timezone)
+
+		series, _ = s.analyticsService.GetProfileClickSeries("testuser", services.SeriesQuery{
+			From: day("2024-03-06", time.UTC), To: day("2024-03-12", time.UTC), Interval: services.IntervalWeek,
+		})
+		assert.Equal(s.T(), []int64{3, 1}, clicks(series))
+		assert.Equal(s.T(), day("2024-03-04", time.UTC), series.From)
+
+		series, _ = s.analyticsService.GetLinkClickSeries("testuser", uint64(shop.ID), services.SeriesQuery{
+			From: day("2024-03-04", time.UTC), To: day("2024-03-04", time.UTC), Interval: services.IntervalHour,
+		})
+		assert.Len(s.T(), series.Points, 24)
+		assert.Equal(s.T(), int64(2), series.Points[10].Clicks)
+	}
+
+	s.Run("From Events", check)
+
+	s.Run("From Rollups", func() {
+		assert.NoError(s.T(), services.RollupClicks(s.db, at("2024-03-06T00:00:00Z")))
+		assert.NoError(s.T(), services.RollupClicks(s.db, at("2024-03-06T00:00:00Z")))
+
+		var rollup models.ClickRollup
+		s.db.Where("link_id = ?", shop.ID).First(&rollup)
+		assert.Equal(s.T(), uint(2), rollup.Clicks)
+		assert.True(s.T(), rollup.Hour.Equal(at("2024-03-04T10:00:00Z")))
+
+		// Rolled up hours are no longer read from the events.
+		s.db.Where("occurred_at < ?", at("2024-03-05T00:00:00Z")).Delete(&models.ClickEvent{})
+		check()
+	})
+
+	s.Run("Invalid Query", func() {
+		_, err := s.analyticsService.GetLinkClickSeries("testuser", uint64(shop.ID), services.SeriesQuery{Interval: "month"})
+		assert.ErrorIs(s.T(), err, services.ErrInvalidSeriesQuery)
+
+		_, err = s.analyticsService.GetLinkClickSeries("testuser", uint64(shop.ID), services.SeriesQuery{
+			From: day("2024-01-01", time.UTC), To: day("2024-03-01", time.UTC), Interval: services.IntervalHour,
+		})
+		assert.ErrorIs(s.T(), err, services.ErrInvalidSeriesQuery)
+
+		_, err = s.analyticsService.GetLinkClickSeries("testuser", uint64(shop.ID), services.SeriesQuery{
+			From: day("2024-03-05", time.UTC), To: day("2024-03-01", time.UTC),
+		})
+		assert.ErrorIs(s.T(), err, services.ErrInvalidSeriesQuery)
+	})
+
+	s.Run("Other User's Link", func() {
+		_, err := s.analyticsService.GetLinkClickSeries("testuser", uint64(other.ID), services.SeriesQuery{})
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestProfileViews() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Blog", URL: "https://blog.example.com"})
+
+	var shop, blog models.Link
+	s.db.Where("title = ?", "Shop").First(&shop)
+	s.db.Where("title = ?", "Blog").First(&blog)
+
+	browser := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"
+
+	s.analyticsService.SetBotClassifier(services.NewBotClassifier(services.DefaultBotClassifierConfig()))
+	testCases := []struct {
+		name      string
+		viewer    string
+		userAgent string
+		wantViews int64
+	}{
+		{name: "Visitor", userAgent: browser, wantViews: 1},
+		{name: "Signed In Visitor", viewer: "someone", userAgent: browser, wantViews: 2},
+		{name: "Owner", viewer: "testuser", userAgent: browser, wantViews: 2},
+		{name: "Crawler", userAgent: "Googlebot/2.1 (+http://www.google.com/bot.html)", wantViews: 2},
+		{name: "Link Preview", userAgent: "facebookexternalhit/1.1", wantViews: 2},
+		{name: "No User Agent", wantViews: 2},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := s.analyticsService.TrackProfileView("testuser", tc.viewer, services.ClickDetails{UserAgent: tc.userAgent, AcceptLanguage: "en-US"})
+			assert.NoError(s.T(), err)
+
+			var views int64
+			s.db.Model(&models.ProfileView{}).Count(&views)
+			assert.Equal(s.T(), tc.wantViews, views)
+		})
+	}
+
+	s.Run("Unknown Profile", func() {
+		assert.Error(s.T(), s.analyticsService.TrackProfileView("nobody", "", services.ClickDetails{UserAgent: browser, AcceptLanguage: "en-US"}))
+	})
+	s.analyticsService.SetBotClassifier(nil)
+
+	s.Run("CTR Report", func() {
+		s.db.Create(&models.ProfileView{UserID: shop.UserID, ViewedAt: time.Now().AddDate(0, 0, -60)})
+		for i := 0; i < 3; i++ {
+			s.analyticsService.TrackLinkClicks(uint64(shop.ID), services.ClickDetails{})
+		}
+		s.db.Create(&models.ClickEvent{LinkID: blog.ID, OccurredAt: time.Now().AddDate(0, 0, -60)})
+
+		report, err := s.analyticsService.GetCTRReport("testuser", services.SeriesQuery{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(2), report.ProfileViews)
+		assert.Len(s.T(), report.Links, 2)
+		assert.Equal(s.T(), int64(3), report.Links[0].Clicks)
+		assert.InDelta(s.T(), 1.5, report.Links[0].CTR, 0.001)
+		assert.Zero(s.T(), report.Links[1].Clicks)
+
+		report, _ = s.analyticsService.GetCTRReport("testuser", services.SeriesQuery{
+			From: time.Now().AddDate(0, 0, -90), To: time.Now().AddDate(0, 0, -30),
+		})
+		assert.Equal(s.T(), int64(1), report.ProfileViews)
+		assert.Equal(s.T(), int64(1), report.Links[1].Clicks)
+		assert.InDelta(s.T(), 1.0, report.Links[1].CTR, 0.001)
+	})
+}
+
+func (s *ServiceTestSuite) TestAnalyticsPrivacy() {
+	for _, username := range []string{"testuser", "alice"} {
+		s.userService.SignUp(models.User{FullName: "Test User", Username: username}, "password123")
+	}
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+
+	var link models.Link
+	s.db.First(&link)
+	linkId := uint64(link.ID)
+	s.analyticsService.TrackLinkClicks(linkId, services.ClickDetails{})
+
+	s.Run("Hidden From Public", func() {
+		profile, err := s.userService.GetUserProfileForVisitor("testuser", "", "")
+		assert.NoError(s.T(), err)
+		assert.Nil(s.T(), profile.Links[0].Analytics)
+
+		blocks, _ := s.blockService.GetBlocks("testuser")
+		assert.Nil(s.T(), blocks[0].Link.Analytics)
+	})
+
+	s.Run("Shown To Owner", func() {
+		profile, _ := s.userService.GetUserProfileForVisitor("testuser", "", "testuser")
+		assert.NotNil(s.T(), profile.Links[0].Analytics)
+		assert.Equal(s.T(), uint(1), profile.Links[0].Analytics.ClickCount)
+	})
+
+	s.Run("Public Counts", func() {
+		assert.NoError(s.T(), s.userService.SetAnalyticsPrivacy("testuser", true))
+
+		profile, _ := s.userService.GetUserProfileForVisitor("testuser", "", "alice")
+		assert.Equal(s.T(), uint(1), profile.Links[0].Analytics.ClickCount)
+
+		blocks, _ := s.blockService.GetBlocks("testuser")
+		assert.Equal(s.T(), uint(1), blocks[0].Link.Analytics.ClickCount)
+	})
+
+	s.Run("Unknown User", func() {
+		assert.Error(s.T(), s.userService.SetAnalyticsPrivacy("nobody", true))
+	})
+}
+
+func (s *ServiceTestSuite) TestClickParser() {
+	parser := services.DefaultClickParser()
+
+	testCases := []struct {
+		name      string
+		referrer  string
+		userAgent string
+		want      services.ClickClass
+	}{
+		{
+			name:      "Instagram In-App On iPhone",
+			referrer:  "https://l.instagram.com/?u=https%3A%2F%2Fshop.example.com",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 309.0.0.40.113",
+			want:      services.ClickClass{Source: "instagram.com", Device: "mobile", Browser: "Instagram", OS: "iOS"},
+		},
+		{
+			name:      "Edge On Windows",
+			referrer:  "https://www.google.de/",
+			userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+			want:      services.ClickClass{Source: "google.com", Device: "desktop", Browser: "Edge", OS: "Windows"},
+		},
+		{
+			name:      "Chrome On Android Tablet",
+			referrer:  "https://t.co/abc",
+			userAgent: "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Tablet",
+			want:      services.ClickClass{Source: "twitter.com", Device: "tablet", Browser: "Chrome", OS: "Android"},
+		},
+		{
+			name:      "Safari On Mac From Unknown Site",
+			referrer:  "https://www.Blog.Example.com/post",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Safari/605.1.15",
+			want:      services.ClickClass{Source: "blog.example.com", Device: "desktop", Browser: "Safari", OS: "macOS"},
+		},
+		{
+			name:      "Crawler",
+			userAgent: "Googlebot/2.1 (+http://www.google.com/bot.html)",
+			want:      services.ClickClass{Source: services.SourceDirect, Device: "bot", Browser: services.ClassOther, OS: services.ClassOther},
+		},
+		{
+			name: "No Headers",
+			want: services.ClickClass{Source: services.SourceDirect, Device: services.ClassUnknown, Browser: services.ClassUnknown, OS: services.ClassUnknown},
+		},
+		{
+			name:     "Malformed Referrer",
+			referrer: "not a url",
+			want:     services.ClickClass{Source: services.ClassUnknown, Device: services.ClassUnknown, Browser: services.ClassUnknown, OS: services.ClassUnknown},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			assert.Equal(s.T(), tc.want, parser.Parse(tc.referrer, tc.userAgent))
+		})
+	}
+
+	s.Run("Custom Rules", func() {
+		custom, err := services.NewClickParser([]byte(`{
+			"version": 7,
+			"sources": [{"name": "newsletter", "domains": ["mail.example.com"]}],
+			"devices": [{"name": "watch", "patterns": ["Watch OS"]}],
+			"default_device": "desktop",
+			"browsers": [{"name": "Arc", "patterns": ["arc/"]}]
+		}`))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 7, custom.Version())
+		assert.Equal(s.T(), services.ClickClass{Source: "newsletter", Device: "watch", Browser: "Arc", OS: services.ClassOther},
+			custom.Parse("https://eu.mail.example.com/inbox", "Mozilla/5.0 (Watch OS 10) Arc/1.0"))
+	})
+
+	s.Run("Invalid Rules", func() {
+		_, err := services.NewClickParser([]byte(`{"version": 1, "browsers": [{"patterns": ["x"]}]}`))
+		assert.Error(s.T(), err)
+		_, err = services.NewClickParser([]byte(`{"sources": []}`))
+		assert.Error(s.T(), err)
+		_, err = services.NewClickParser([]byte(`not json`))
+		assert.Error(s.T(), err)
+	})
+
+	s.Run("Rules File", func() {
+		path := s.T().TempDir() + "/rules.json"
+		os.WriteFile(path, []byte(`{"version": 2}`), 0o644)
+		os.Setenv("CLICK_RULES_FILE", path)
+		defer os.Unsetenv("CLICK_RULES_FILE")
+
+		loaded, err := services.LoadClickParser()
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 2, loaded.Version())
+
+		os.Setenv("CLICK_RULES_FILE", path+".missing")
+		_, err = services.LoadClickParser()
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestClickBreakdown() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Blog", URL: "https://blog.example.com"})
+
+	var shop, blog models.Link
+	s.db.Where("title = ?", "Shop").First(&shop)
+	s.db.Where("title = ?", "Blog").First(&blog)
+
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	windows := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	clicks := []struct {
+		link      models.Link
+		referrer  string
+		userAgent string
+	}{
+		{shop, "https://www.instagram.com/", iphone},
+		{shop, "https://l.instagram.com/", iphone},
+		{shop, "", windows},
+		{blog, "https://www.youtube.com/watch", windows},
+	}
+	for _, click := range clicks {
+		err := s.analyticsService.TrackLinkClicks(uint64(click.link.ID), services.ClickDetails{Referrer: click.referrer, UserAgent: click.userAgent})
+		assert.NoError(s.T(), err)
+	}
+	s.db.Create(&models.ClickEvent{LinkID: shop.ID, OccurredAt: time.Now().AddDate(0, 0, -60), Referrer: "https://t.co/x", UserAgent: iphone})
+
+	s.Run("Link", func() {
+		breakdown, err := s.analyticsService.GetLinkClickBreakdown("testuser", uint64(shop.ID), services.SeriesQuery{}, 0)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(3), breakdown.Total)
+		assert.Equal(s.T(), []services.BreakdownEntry{
+			{Value: "instagram.com", Clicks: 2, Share: 2.0 / 3},
+			{Value: services.SourceDirect, Clicks: 1, Share: 1.0 / 3},
+		}, breakdown.Sources)
+		assert.Equal(s.T(), "mobile", breakdown.Devices[0].Value)
+		assert.Equal(s.T(), "Safari", breakdown.Browsers[0].Value)
+		assert.Equal(s.T(), "Chrome", breakdown.Browsers[1].Value)
+		assert.Equal(s.T(), "iOS", breakdown.OperatingSystems[0].Value)
+	})
+
+	s.Run("Profile With Limit", func() {
+		breakdown, err := s.analyticsService.GetProfileClickBreakdown("testuser", services.SeriesQuery{}, 1)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(4), breakdown.Total)
+		assert.Len(s.T(), breakdown.Sources, 1)
+		assert.Equal(s.T(), "instagram.com", breakdown.Sources[0].Value)
+		assert.Len(s.T(), breakdown.Devices, 1)
+	})
+
+	s.Run("Unclassified Clicks", func() {
+		breakdown, _ := s.analyticsService.GetLinkClickBreakdown("testuser", uint64(shop.ID), services.SeriesQuery{
+			From: time.Now().AddDate(0, 0, -61), To: time.Now().AddDate(0, 0, -59),
+		}, 0)
+		assert.Equal(s.T(), []services.BreakdownEntry{{Value: services.ClassUnknown, Clicks: 1, Share: 1}}, breakdown.Sources)
+
+		updated, err := services.ReclassifyClicks(s.db, services.DefaultClickParser())
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 1, updated)
+
+		breakdown, _ = s.analyticsService.GetLinkClickBreakdown("testuser", uint64(shop.ID), services.SeriesQuery{
+			From: time.Now().AddDate(0, 0, -61), To: time.Now().AddDate(0, 0, -59),
+		}, 0)
+		assert.Equal(s.T(), "twitter.com", breakdown.Sources[0].Value)
+		assert.Equal(s.T(), "mobile", breakdown.Devices[0].Value)
+	})
+
+	s.Run("New Rules Version", func() {
+		parser, _ := services.NewClickParser([]byte(`{"version": 3, "sources": [{"name": "social", "domains": ["instagram.com", "youtube.com", "t.co"]}]}`))
+		updated, err := services.ReclassifyClicks(s.db, parser)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), 5, updated)
+
+		breakdown, _ := s.analyticsService.GetProfileClickBreakdown("testuser", services.SeriesQuery{}, 0)
+		assert.Equal(s.T(), "social", breakdown.Sources[0].Value)
+		assert.Equal(s.T(), int64(3), breakdown.Sources[0].Clicks)
+
+		updated, _ = services.ReclassifyClicks(s.db, parser)
+		assert.Zero(s.T(), updated)
+	})
+
+	s.Run("Other User's Link", func() {
+		_, err := s.analyticsService.GetLinkClickBreakdown("someone", uint64(shop.ID), services.SeriesQuery{}, 0)
+		assert.Error(s.T(), err)
+	})
+}
+
+// fakeGeoLocator locates the IP addresses it has a location for.
+type fakeGeoLocator map[string]services.GeoLocation
+
+func (f fakeGeoLocator) Locate(ip net.IP) (services.GeoLocation, error) {
+	if ip.String() == "203.0.113.99" {
+		return services.GeoLocation{}, errors.New("corrupt database")
+	}
+	return f[ip.String()], nil
+}
+
+func (s *ServiceTestSuite) TestClickGeolocation() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+
+	var link models.Link
+	s.db.First(&link)
+
+	s.analyticsService.SetGeoLocator(fakeGeoLocator{
+		"203.0.113.7":  {Country: "DE", Region: "DE-BE", City: "Berlin"},
+		"203.0.113.8":  {Country: "DE", Region: "DE-BY", City: "Munich"},
+		"2001:db8::1":  {Country: "US", Region: "US-CA", City: "San Francisco"},
+		"198.51.100.1": {Country: "FR", Region: "FR-IDF", City: "Paris"},
+	})
+	defer s.analyticsService.SetGeoLocator(nil)
+
+	testCases := []struct {
+		name    string
+		details services.ClickDetails
+		want    services.GeoLocation
+	}{
+		{name: "Database", details: services.ClickDetails{IP: net.ParseIP("203.0.113.7")}, want: services.GeoLocation{Country: "DE", Region: "DE-BE", City: "Berlin"}},
+		{name: "IPv6", details: services.ClickDetails{IP: net.ParseIP("2001:db8::1")}, want: services.GeoLocation{Country: "US", Region: "US-CA", City: "San Francisco"}},
+		{name: "CDN Header Agrees", details: services.ClickDetails{IP: net.ParseIP("203.0.113.8"), Country: "DE"}, want: services.GeoLocation{Country: "DE", Region: "DE-BY", City: "Munich"}},
+		{name: "CDN Header Wins", details: services.ClickDetails{IP: net.ParseIP("198.51.100.1"), Country: "BE"}, want: services.GeoLocation{Country: "BE"}},
+		{name: "Unknown Address", details: services.ClickDetails{IP: net.ParseIP("192.0.2.1")}, want: services.GeoLocation{}},
+		{name: "Failed Lookup", details: services.ClickDetails{IP: net.ParseIP("203.0.113.99")}, want: services.GeoLocation{}},
+		{name: "No Address", details: services.ClickDetails{Country: "NL"}, want: services.GeoLocation{Country: "NL"}},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			err := s.analyticsService.TrackLinkClicks(uint64(link.ID), tc.details)
+			assert.NoError(s.T(), err)
+
+			var event models.ClickEvent
+			s.db.Order("id DESC").First(&event)
+			assert.Equal(s.T(), tc.want, services.GeoLocation{Country: event.Country, Region: event.Region, City: event.City})
+			if tc.details.IP != nil {
+				assert.Len(s.T(), event.VisitorHash, 32)
+			} else {
+				assert.Empty(s.T(), event.VisitorHash)
+			}
+		})
+	}
+
+	s.Run("Addresses Are Not Stored", func() {
+		var events []map[string]interface{}
+		s.db.Model(&models.ClickEvent{}).Find(&events)
+		raw, _ := json.Marshal(events)
+		for _, ip := range []string{"203.0.113.7", "2001:db8::1", "198.51.100.1"} {
+			assert.NotContains(s.T(), string(raw), ip)
+		}
+
+		var hashes []string
+		s.db.Model(&models.ClickEvent{}).Where("visitor_hash <> ''").Order("id").Pluck("visitor_hash", &hashes)
+		s.analyticsService.TrackLinkClicks(uint64(link.ID), services.ClickDetails{IP: net.ParseIP("203.0.113.7")})
+		var repeat models.ClickEvent
+		s.db.Order("id DESC").First(&repeat)
+		assert.Equal(s.T(), hashes[0], repeat.VisitorHash)
+		assert.NotEqual(s.T(), hashes[0], hashes[1])
+
+		s.analyticsService.TrackLinkClicks(uint64(link.ID), services.ClickDetails{IP: net.ParseIP("203.0.113.7"), UserAgent: "Mozilla/5.0"})
+		var otherBrowser models.ClickEvent
+		s.db.Order("id DESC").First(&otherBrowser)
+		assert.NotEqual(s.T(), hashes[0], otherBrowser.VisitorHash)
+	})
+
+	s.Run("Breakdown", func() {
+		breakdown, err := s.analyticsService.GetLinkClickBreakdown("testuser", uint64(link.ID), services.SeriesQuery{}, 0)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(9), breakdown.Total)
+		assert.Equal(s.T(), services.BreakdownEntry{Value: "DE", Clicks: 4, Share: 4.0 / 9}, breakdown.Countries[0])
+		assert.Equal(s.T(), services.BreakdownEntry{Value: services.ClassUnknown, Clicks: 4, Share: 4.0 / 9}, breakdown.Regions[0])
+		assert.Equal(s.T(), services.BreakdownEntry{Value: "DE-BE", Clicks: 3, Share: 3.0 / 9}, breakdown.Regions[1])
+		assert.Equal(s.T(), services.BreakdownEntry{Value: "Berlin, DE", Clicks: 3, Share: 3.0 / 9}, breakdown.Cities[1])
+	})
+
+	s.Run("Database File", func() {
+		_, err := services.OpenMMDBGeoLocator(s.T().TempDir() + "/missing.mmdb")
+		assert.Error(s.T(), err)
+
+		locator, err := services.LoadGeoLocator()
+		assert.NoError(s.T(), err)
+		assert.Nil(s.T(), locator)
+
+		os.Setenv("GEOIP_DATABASE_FILE", s.T().TempDir()+"/missing.mmdb")
+		defer os.Unsetenv("GEOIP_DATABASE_FILE")
+		_, err = services.LoadGeoLocator()
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestBotClassifier() {
+	browser := services.ClickDetails{
+		UserAgent:      "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1",
+		AcceptLanguage: "en-US,en;q=0.9",
+	}
+	now := time.Now()
+
+	testCases := []struct {
+		name       string
+		signatures []string
+		details    services.ClickDetails
+		want       string
+	}{
+		{name: "Browser", details: browser, want: ""},
+		{name: "Chat Preview", details: services.ClickDetails{UserAgent: "WhatsApp/2.23.20.0 A", AcceptLanguage: "en"}, want: services.BotReasonUserAgent},
+		{name: "Crawler", details: services.ClickDetails{UserAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"}, want: services.BotReasonUserAgent},
+		{name: "No User Agent", details: services.ClickDetails{AcceptLanguage: "en"}, want: services.BotReasonHeaders},
+		{name: "No Accept-Language", details: services.ClickDetails{UserAgent: browser.UserAgent}, want: services.BotReasonHeaders},
+		{name: "Custom Signature", signatures: []string{"  Safari/604 "}, details: browser, want: services.BotReasonUserAgent},
+		{name: "Custom Signatures Replace Defaults", signatures: []string{"monitor"}, details: services.ClickDetails{UserAgent: "curl/8.0", AcceptLanguage: "en"}, want: ""},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			config := services.DefaultBotClassifierConfig()
+			if tc.signatures != nil {
+				config.Signatures = tc.signatures
+			}
+			assert.Equal(s.T(), tc.want, services.NewBotClassifier(config).Classify(tc.details, "visitor", now))
+		})
+	}
+
+	s.Run("Request Rate", func() {
+		classifier := services.NewBotClassifier(services.BotClassifierConfig{RateLimit: 3, RateWindow: time.Minute})
+		for i := 0; i < 3; i++ {
+			assert.Empty(s.T(), classifier.Classify(browser, "visitor", now.Add(time.Duration(i)*time.Second)))
+		}
+		assert.Equal(s.T(), services.BotReasonRate, classifier.Classify(browser, "visitor", now.Add(4*time.Second)))
+		assert.Empty(s.T(), classifier.Classify(browser, "someone-else", now.Add(4*time.Second)))
+		assert.Empty(s.T(), classifier.Classify(browser, "", now.Add(5*time.Second)))
+		assert.Empty(s.T(), classifier.Classify(browser, "visitor", now.Add(time.Minute)))
+	})
+
+	s.Run("Rate Check Off", func() {
+		classifier := services.NewBotClassifier(services.BotClassifierConfig{})
+		for i := 0; i < 100; i++ {
+			assert.Empty(s.T(), classifier.Classify(browser, "visitor", now))
+		}
+	})
+
+	s.Run("Config", func() {
+		path := s.T().TempDir() + "/bots.txt"
+		os.WriteFile(path, []byte("# uptime monitors\nPingdom\n\nstatuscake # paid plan\n"), 0o644)
+		os.Setenv("BOT_SIGNATURES_FILE", path)
+		os.Setenv("BOT_RATE_LIMIT", "5")
+		os.Setenv("BOT_RATE_WINDOW", "10s")
+		defer os.Unsetenv("BOT_SIGNATURES_FILE")
+		defer os.Unsetenv("BOT_RATE_LIMIT")
+		defer os.Unsetenv("BOT_RATE_WINDOW")
+
+		custom, _ := services.NewClickParser([]byte(`{"version": 1, "devices": [{"name": "bot", "patterns": ["UptimeRobot"]}, {"name": "tv", "patterns": ["smart-tv"]}]}`))
+		os.Unsetenv("BOT_SIGNATURES_FILE")
+		config, err := services.LoadBotClassifierConfig(custom)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), []string{"uptimerobot"}, config.Signatures)
+
+		os.Setenv("BOT_SIGNATURES_FILE", path)
+		config, err = services.LoadBotClassifierConfig(custom)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), []string{"Pingdom", "statuscake"}, config.Signatures)
+		assert.Equal(s.T(), 5, config.RateLimit)
+		assert.Equal(s.T(), 10*time.Second, config.RateWindow)
+
+		os.Setenv("BOT_RATE_WINDOW", "soon")
+		_, err = services.LoadBotClassifierConfig(services.DefaultClickParser())
+		assert.Error(s.T(), err)
+
+		os.Setenv("BOT_RATE_WINDOW", "10s")
+		os.Setenv("BOT_SIGNATURES_FILE", path+".missing")
+		_, err = services.LoadBotClassifierConfig(services.DefaultClickParser())
+		assert.Error(s.T(), err)
+	})
+}
+
+func (s *ServiceTestSuite) TestBotClicks() {
+	s.userService.SignUp(models.User{FullName: "Test User", Username: "testuser"}, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+
+	var link models.Link
+	s.db.First(&link)
+
+	s.analyticsService.SetBotClassifier(services.NewBotClassifier(services.DefaultBotClassifierConfig()))
+	defer s.analyticsService.SetBotClassifier(nil)
+
+	browser := services.ClickDetails{
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+		AcceptLanguage: "de-DE,de;q=0.9",
+		IP:             net.ParseIP("203.0.113.7"),
+	}
+	preview := services.ClickDetails{UserAgent: "TelegramBot (like TwitterBot)"}
+
+	assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(link.ID), browser))
+	assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(link.ID), browser))
+	assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(link.ID), preview))
+	assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(link.ID), services.ClickDetails{UserAgent: browser.UserAgent}))
+
+	s.Run("Counted Apart", func() {
+		var analytics models.Analytics
+		s.db.Where("link_id = ?", link.ID).First(&analytics)
+		assert.Equal(s.T(), uint(2), analytics.ClickCount)
+		assert.Equal(s.T(), uint(2), analytics.BotClickCount)
+
+		var reasons []string
+		s.db.Model(&models.ClickEvent{}).Where("bot = ?", true).Order("id").Pluck("bot_reason", &reasons)
+		assert.Equal(s.T(), []string{services.BotReasonUserAgent, services.BotReasonHeaders}, reasons)
+
+		clicks, err := s.analyticsService.CountClicks(uint64(link.ID), time.Time{}, time.Time{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(2), clicks)
+	})
+
+	s.Run("Bots Are Not Visitors", func() {
+		series, err := s.analyticsService.GetLinkClickSeries("testuser", uint64(link.ID), services.SeriesQuery{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(1), series.UniqueVisitors)
+	})
+
+	s.Run("Reports Hide Bots", func() {
+		series, err := s.analyticsService.GetLinkClickSeries("testuser", uint64(link.ID), services.SeriesQuery{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(2), series.Total)
+		assert.False(s.T(), series.IncludesBots)
+
+		breakdown, _ := s.analyticsService.GetProfileClickBreakdown("testuser", services.SeriesQuery{}, 0)
+		assert.Equal(s.T(), int64(2), breakdown.Total)
+
+		report, _ := s.analyticsService.GetCTRReport("testuser", services.SeriesQuery{})
+		assert.Equal(s.T(), int64(2), report.Links[0].Clicks)
+	})
+
+	s.Run("Owner Shows Bots", func() {
+		assert.NoError(s.T(), s.userService.SetShowBotTraffic("testuser", true))
+		defer s.userService.SetShowBotTraffic("testuser", false)
+
+		series, _ := s.analyticsService.GetLinkClickSeries("testuser", uint64(link.ID), services.SeriesQuery{})
+		assert.Equal(s.T(), int64(4), series.Total)
+		assert.True(s.T(), series.IncludesBots)
+
+		breakdown, _ := s.analyticsService.GetProfileClickBreakdown("testuser", services.SeriesQuery{}, 0)
+		assert.Equal(s.T(), int64(4), breakdown.Total)
+		assert.True(s.T(), breakdown.IncludesBots)
+
+		report, _ := s.analyticsService.GetCTRReport("testuser", services.SeriesQuery{})
+		assert.Equal(s.T(), int64(4), report.Links[0].Clicks)
+
+		assert.Error(s.T(), s.userService.SetShowBotTraffic("nobody", true))
+	})
+
+	s.Run("Rollups", func() {
+		assert.NoError(s.T(), services.RollupClicks(s.db, time.Now().Add(2*time.Hour)))
+
+		var rollup models.ClickRollup
+		s.db.Where("link_id = ?", link.ID).First(&rollup)
+		assert.Equal(s.T(), uint(2), rollup.Clicks)
+		assert.Equal(s.T(), uint(2), rollup.BotClicks)
+
+		series, _ := s.analyticsService.GetLinkClickSeries("testuser", uint64(link.ID), services.SeriesQuery{})
+		assert.Equal(s.T(), int64(2), series.Total)
+	})
+
+	s.Run("Profile Views", func() {
+		assert.NoError(s.T(), s.analyticsService.TrackProfileView("testuser", "", browser))
+		assert.NoError(s.T(), s.analyticsService.TrackProfileView("testuser", "", services.ClickDetails{UserAgent: browser.UserAgent}))
+
+		var views int64
+		s.db.Model(&models.ProfileView{}).Count(&views)
+		assert.Equal(s.T(), int64(1), views)
+	})
+
+	s.Run("Bot Clicks A Fresh Link First", func() {
+		s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Fresh", URL: "https://fresh.example.com"})
+		var fresh models.Link
+		s.db.Where("url = ?", "https://fresh.example.com").First(&fresh)
+
+		assert.NoError(s.T(), s.analyticsService.TrackLinkClicks(uint64(fresh.ID), preview))
+
+		var analytics models.Analytics
+		s.db.Where("link_id = ?", fresh.ID).First(&analytics)
+		assert.Equal(s.T(), uint(0), analytics.ClickCount)
+		assert.Equal(s.T(), uint(1), analytics.BotClickCount)
+	})
+}
+
+func (s *ServiceTestSuite) TestUniqueVisitors() {
+	user := models.User{
+		FullName: "Test User",
+		Username: "testuser",
+	}
+	s.userService.SignUp(user, "password123")
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Shop", URL: "https://shop.example.com"})
+	s.linkService.CreateLink(context.Background(), "testuser", models.Link{Title: "Blog", URL: "https://blog.example.com"})
+
+	var shop, blog models.Link
+	s.db.Where("title = ?", "Shop").First(&shop)
+	s.db.Where("title = ?", "Blog").First(&blog)
+
+	// The suite's service caches the salt of a day whose row an earlier test
+	// deleted.
+	analytics := services.NewAnalyticsService(s.db)
+
+	iphone := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1"
+	windows := "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	alice := services.ClickDetails{IP: net.ParseIP("203.0.113.7"), UserAgent: iphone}
+	sameAddressOtherDevice := services.ClickDetails{IP: net.ParseIP("203.0.113.7"), UserAgent: windows}
+	otherAddress := services.ClickDetails{IP: net.ParseIP("198.51.100.1"), UserAgent: iphone}
+
+	clicks := []struct {
+		link    models.Link
+		details services.ClickDetails
+	}{
+		{shop, alice}, {shop, alice}, {shop, alice}, {blog, alice},
+		{shop, sameAddressOtherDevice},
+		{shop, otherAddress},
+		{shop, services.ClickDetails{UserAgent: iphone}}, {shop, services.ClickDetails{UserAgent: iphone}},
+	}
+	for _, click := range clicks {
+		assert.NoError(s.T(), analytics.TrackLinkClicks(uint64(click.link.ID), click.details))
+	}
+
+	s.Run("Hashes", func() {
+		var hashes []string
+		s.db.Model(&models.ClickEvent{}).Where("link_id = ?", shop.ID).Order("id").Pluck("visitor_hash", &hashes)
+		assert.Len(s.T(), hashes[0], 32)
+		assert.Equal(s.T(), hashes[0], hashes[1])
+		assert.NotEqual(s.T(), hashes[0], hashes[3])
+		assert.NotEqual(s.T(), hashes[0], hashes[4])
+		assert.Empty(s.T(), hashes[5])
+
+		var salts []models.VisitorSalt
+		s.db.Find(&salts)
+		assert.Len(s.T(), salts, 1)
+		assert.Equal(s.T(), time.Now().UTC().Format("2006-01-02"), salts[0].Day)
+	})
+
+	s.Run("Series", func() {
+		series, err := analytics.GetLinkClickSeries("testuser", uint64(shop.ID), services.SeriesQuery{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(7), series.Total)
+		assert.Equal(s.T(), int64(5), series.UniqueVisitors)
+
+		series, _ = analytics.GetProfileClickSeries("testuser", services.SeriesQuery{})
+		assert.Equal(s.T(), int64(8), series.Total)
+		assert.Equal(s.T(), int64(5), series.UniqueVisitors)
+	})
+
+	s.Run("CTR Report", func() {
+		analytics.TrackProfileView("testuser", "", alice)
+		analytics.TrackProfileView("testuser", "", alice)
+		analytics.TrackProfileView("testuser", "", otherAddress)
+
+		report, err := analytics.GetCTRReport("testuser", services.SeriesQuery{})
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(3), report.ProfileViews)
+		assert.Equal(s.T(), int64(2), report.UniqueViewers)
+		assert.Equal(s.T(), int64(5), report.UniqueVisitors)
+		assert.Equal(s.T(), int64(5), report.Links[0].UniqueVisitors)
+		assert.Equal(s.T(), int64(1), report.Links[1].UniqueVisitors)
+	})
+
+	s.Run("Breakdown", func() {
+		breakdown, err := analytics.GetLinkClickBreakdown("testuser", uint64(shop.ID), services.SeriesQuery{}, 0)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(7), breakdown.Total)
+		assert.Equal(s.T(), int64(5), breakdown.UniqueVisitors)
+	})
+
+	s.Run("Bots Are Not Visitors", func() {
+		analytics.SetBotClassifier(services.NewBotClassifier(services.DefaultBotClassifierConfig()))
+		defer analytics.SetBotClassifier(nil)
+		analytics.TrackLinkClicks(uint64(blog.ID), services.ClickDetails{IP: net.ParseIP("192.0.2.50"), UserAgent: "Twitterbot/1.0"})
+
+		series, _ := analytics.GetLinkClickSeries("testuser", uint64(blog.ID), services.SeriesQuery{})
+		assert.Equal(s.T(), int64(1), series.Total)
+		assert.Equal(s.T(), int64(1), series.UniqueVisitors)
+	})
+
+	s.Run("Servers Share The Day's Salt", func() {
+		other := services.NewAnalyticsService(s.db)
+		assert.NoError(s.T(), other.TrackLinkClicks(uint64(blog.ID), alice))
+
+		var hashes []string
+		s.db.Model(&models.ClickEvent{}).Where("link_id = ? AND bot = ?", blog.ID, false).Order("id").Pluck("visitor_hash", &hashes)
+		assert.Equal(s.T(), hashes[0], hashes[1])
+	})
+
+	s.Run("Salts Are Discarded", func() {
+		s.db.Create(&models.VisitorSalt{Day: "2000-01-01", Salt: "00"})
+		assert.NoError(s.T(), services.DiscardVisitorSalts(s.db, time.Now()))
+
+		var days []string
+		s.db.Model(&models.VisitorSalt{}).Pluck("day", &days)
+		assert.Equal(s.T(), []string{time.Now().UTC().Format("2006-01-02")}, days)
+
+		assert.NoError(s.T(), services.DiscardVisitorSalts(s.db, time.Now().AddDate(0, 0, 1)))
+		var remaining int64
+		s.db.Model(&models.VisitorSalt{}).Count(&remaining)
+		assert.Zero(s.T(), remaining)
+	})
+
+	s.Run("New Day Rotates The Salt", func() {
+		s.db.Create(&models.VisitorSalt{Day: "2000-01-01", Salt: "00"})
+		fresh := services.NewAnalyticsService(s.db)
+		assert.NoError(s.T(), fresh.TrackLinkClicks(uint64(blog.ID), alice))
+
+		var days []string
+		s.db.Model(&models.VisitorSalt{}).Pluck("day", &days)
+		assert.Equal(s.T(), []string{time.Now().UTC().Format("2006-01-02")}, days)
+
+		var hashes []string
+		s.db.Model(&models.ClickEvent{}).Where("link_id = ? AND bot = ?", blog.ID, false).Order("id DESC").Limit(2).Pluck("visitor_hash", &hashes)
+		assert.NotEqual(s.T(), hashes[0], hashes[1])
+	})
+}